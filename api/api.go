@@ -0,0 +1,9 @@
+// api package holds the contracts of the service APIs, so they can be enforced at runtime and in tests.
+package api
+
+import _ "embed"
+
+// OpenAPI is the OpenAPI 3 specification of the HTTP API.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrClosed is returned when events are added to a closed Client.
+var ErrClosed = errors.New("clicks and views: client is closed")
+
+// ErrQueueFull is returned when events are added faster than they can be sent.
+var ErrQueueFull = errors.New("clicks and views: queue is full")
+
+// AddClick queues a Click to be sent with the next batch.
+// A random EventID is assigned if it's empty, so a batch sent again after a failure isn't recorded twice.
+func (c *Client) AddClick(click Click) error {
+	if click.EventID == "" {
+		click.EventID = newID()
+	}
+	return add(c, &c.clicks, click)
+}
+
+// AddView queues a View to be sent with the next batch.
+// A random EventID is assigned if it's empty, so a batch sent again after a failure isn't recorded twice.
+func (c *Client) AddView(view View) error {
+	if view.EventID == "" {
+		view.EventID = newID()
+	}
+	return add(c, &c.views, view)
+}
+
+// add appends an event to a queue and wakes up the sender if a batch is full.
+func add[T any](c *Client, queue *[]T, event T) error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	if len(*queue) >= c.maxQueued {
+		c.mu.Unlock()
+		return ErrQueueFull
+	}
+	*queue = append(*queue, event)
+	queued := len(*queue)
+	c.mu.Unlock()
+
+	if queued >= c.batchSize {
+		select {
+		case c.full <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Flush sends all queued events. Events which couldn't be sent because of a network error, 429 or 5xx status
+// stay queued, events of batches rejected otherwise are dropped and the error is returned.
+func (c *Client) Flush(ctx context.Context) error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	clicks, views := c.clicks, c.views
+	c.clicks, c.views = nil, nil
+	c.mu.Unlock()
+
+	clicksErr := sendBatches(ctx, c, "/clicks", clicks, newClickJSON, &c.clicks)
+	viewsErr := sendBatches(ctx, c, "/views", views, newViewJSON, &c.views)
+
+	return errors.Join(clicksErr, viewsErr)
+}
+
+// sendBatches sends events in batches of batchSize. A batch the API rejected is dropped and sending goes on,
+// otherwise on error the events not sent yet are put back to the queue.
+func sendBatches[T any](ctx context.Context, c *Client, path string, events []T, toJSON func(T) eventJSON, queue *[]T) error {
+	var errs []error
+	for len(events) > 0 {
+		n := len(events)
+		if n > c.batchSize {
+			n = c.batchSize
+		}
+		batch := make([]eventJSON, 0, n)
+		for _, e := range events[:n] {
+			batch = append(batch, toJSON(e))
+		}
+
+		if err := c.do(ctx, http.MethodPost, path, nil, batch, nil); err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && !apiErr.retryable() {
+				// sending the same batch again would be rejected again
+				errs = append(errs, fmt.Errorf("dropping %d events: %w", n, err))
+				events = events[n:]
+				continue
+			}
+
+			c.mu.Lock()
+			*queue = append(events, *queue...)
+			if dropped := len(*queue) - c.maxQueued; dropped > 0 {
+				*queue = (*queue)[dropped:]
+				err = errors.Join(err, fmt.Errorf("%w, dropping %d oldest events", ErrQueueFull, dropped))
+			}
+			c.mu.Unlock()
+			return errors.Join(append(errs, err)...)
+		}
+		events = events[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// run sends queued events every flushInterval, or as soon as a batch is full, until Close is called.
+func (c *Client) run(ctx context.Context) {
+	defer close(c.stopped)
+
+	ticker := time.NewTicker(c.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		case <-c.full:
+		}
+		if err := c.Flush(ctx); err != nil {
+			c.onError(err)
+		}
+	}
+}
+
+// Close stops sending events in the background and sends the ones still queued.
+// If ctx is done before all of them are sent, the rest is dropped and the error is returned.
+// Close must be called once, after which events can't be added.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+
+	close(c.done)
+	select {
+	case <-c.stopped:
+	case <-ctx.Done():
+		// abort the batch being sent in the background
+		c.cancel()
+		<-c.stopped
+	}
+	defer c.cancel()
+
+	return c.Flush(ctx)
+}
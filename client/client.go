@@ -0,0 +1,300 @@
+// Package client is a Go client for the Clicks and Views API.
+//
+// Clicks and views are created one at a time with CreateClick and CreateView, or queued with
+// AddClick and AddView and sent in batches in the background. Requests which fail with a network
+// error, 429 or 5xx status are retried with exponential backoff, each attempt carrying the same
+// Idempotency-Key, so a retried request is never recorded twice.
+//
+//	c := client.New("https://clicks.example.com", writeKey)
+//	defer c.Close(context.Background())
+//
+//	c.AddClick(client.Click{URL: "https://example.com/pricing"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default configuration of a Client.
+const (
+	DefaultTimeout       = 30 * time.Second
+	DefaultMaxAttempts   = 4
+	DefaultMinBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff    = 5 * time.Second
+	DefaultBatchSize     = MaxBatchSize
+	DefaultFlushInterval = 5 * time.Second
+	DefaultMaxQueued     = 10_000
+)
+
+// MaxBatchSize is the maximum number of events the API accepts in a single request.
+const MaxBatchSize = 100
+
+// APIError is returned when the API responds with an error status.
+type APIError struct {
+	StatusCode int
+	Message    string
+
+	// retryAfter is the delay the API asked for in Retry-After header
+	retryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("clicks and views: %d %s", e.StatusCode, e.Message)
+}
+
+// retryable reports whether a request may succeed if it's sent again.
+// 409 is returned while a request with the same Idempotency-Key is still being processed.
+func (e *APIError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusConflict || e.StatusCode >= 500
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to send requests, http.Client with DefaultTimeout by default.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sets the number of attempts made for every request, and the range of delays between them.
+// The delay doubles with every attempt, unless the API asks for a specific one with Retry-After header.
+// A single attempt disables retries.
+func WithRetries(maxAttempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithBatching sets the number of queued events which are sent together,
+// and the interval after which queued events are sent even if there are fewer of them.
+// Size is capped at MaxBatchSize.
+func WithBatching(size int, flushInterval time.Duration) Option {
+	return func(c *Client) {
+		c.batchSize = size
+		c.flushInterval = flushInterval
+	}
+}
+
+// WithMaxQueued sets the number of clicks, and separately of views, which can wait to be sent.
+// Once the queue is full, AddClick and AddView return ErrQueueFull.
+func WithMaxQueued(n int) Option {
+	return func(c *Client) {
+		c.maxQueued = n
+	}
+}
+
+// WithErrorHandler sets a function called with errors of batches sent in the background.
+// Events of a batch which failed with a network error, 429 or 5xx status stay queued and are sent again
+// with the next one, events of a batch rejected otherwise are dropped.
+func WithErrorHandler(onError func(error)) Option {
+	return func(c *Client) {
+		c.onError = onError
+	}
+}
+
+// Client sends requests to the Clicks and Views API on behalf of a single project.
+// A write key is needed to create events and a read key to filter them.
+// It's safe for concurrent use.
+type Client struct {
+	baseURL     string
+	key         string
+	httpClient  *http.Client
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	batchSize     int
+	flushInterval time.Duration
+	maxQueued     int
+	onError       func(error)
+
+	mu     sync.Mutex
+	clicks []Click
+	views  []View
+	closed bool
+
+	// flushMu keeps batches in the order events were added
+	flushMu sync.Mutex
+	full    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	cancel  context.CancelFunc
+}
+
+// New is a Client constructor. It starts a goroutine which sends queued events, stopped by Close.
+func New(baseURL, key string, options ...Option) *Client {
+	c := &Client{
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		key:           key,
+		httpClient:    &http.Client{Timeout: DefaultTimeout},
+		maxAttempts:   DefaultMaxAttempts,
+		minBackoff:    DefaultMinBackoff,
+		maxBackoff:    DefaultMaxBackoff,
+		batchSize:     DefaultBatchSize,
+		flushInterval: DefaultFlushInterval,
+		maxQueued:     DefaultMaxQueued,
+		onError:       func(error) {},
+		full:          make(chan struct{}, 1),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.batchSize < 1 || c.batchSize > MaxBatchSize {
+		c.batchSize = MaxBatchSize
+	}
+	if c.flushInterval <= 0 {
+		c.flushInterval = DefaultFlushInterval
+	}
+	if c.maxAttempts < 1 {
+		c.maxAttempts = 1
+	}
+	if c.maxQueued < c.batchSize {
+		c.maxQueued = c.batchSize
+	}
+
+	var ctx context.Context
+	ctx, c.cancel = context.WithCancel(context.Background())
+	go c.run(ctx)
+
+	return c
+}
+
+// do sends a request, retrying it as configured, and decodes JSON response into out.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	idempotencyKey := ""
+	if method == http.MethodPost {
+		idempotencyKey = newID()
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < c.maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := c.wait(ctx, attempt, lastErr); err != nil {
+				return err
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+c.key)
+		req.Header.Set("Accept", "application/json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if idempotencyKey != "" {
+			req.Header.Set("Idempotency-Key", idempotencyKey)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+
+		lastErr = decode(resp, out)
+		var apiErr *APIError
+		if errors.As(lastErr, &apiErr) && apiErr.retryable() {
+			continue
+		}
+		return lastErr
+	}
+
+	return lastErr
+}
+
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		if out == nil {
+			_, err := io.Copy(io.Discard, resp.Body)
+			return err
+		}
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+
+	var message struct {
+		Message string `json:"message"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if json.Unmarshal(body, &message) != nil || message.Message == "" {
+		message.Message = http.StatusText(resp.StatusCode)
+	}
+
+	err := &APIError{StatusCode: resp.StatusCode, Message: message.Message}
+	if seconds, parseErr := strconv.Atoi(resp.Header.Get("Retry-After")); parseErr == nil && seconds >= 0 {
+		err.retryAfter = time.Duration(seconds) * time.Second
+	}
+	return err
+}
+
+// wait sleeps before a given attempt, returning early with an error when ctx is done.
+func (c *Client) wait(ctx context.Context, attempt int, lastErr error) error {
+	backoff := c.minBackoff << (attempt - 1)
+	if backoff > c.maxBackoff || backoff <= 0 {
+		backoff = c.maxBackoff
+	}
+	// jitter spreads retries of clients which failed at the same time
+	if half := backoff / 2; half > 0 {
+		backoff = half + time.Duration(mathrand.Int63n(int64(half)))
+	}
+	var apiErr *APIError
+	if errors.As(lastErr, &apiErr) && apiErr.retryAfter > backoff {
+		backoff = apiErr.retryAfter
+	}
+
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// newID returns a random identifier for events and idempotency keys.
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
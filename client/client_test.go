@@ -0,0 +1,264 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/idempotency"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// server runs the API handlers with a project, and its read and write keys.
+type server struct {
+	*httptest.Server
+	readKey  string
+	writeKey string
+	// failures is the number of requests which are going to fail with 503 before reaching handlers
+	failures atomic.Int32
+	requests atomic.Int32
+}
+
+func TestCreateAndFilter(t *testing.T) {
+	s := newServer(t)
+	defer func() {
+		s.Close()
+		teardownDatabase(t)
+	}()
+
+	ctx := context.Background()
+	writer := New(s.URL, s.writeKey)
+	defer writer.Close(ctx)
+
+	for _, u := range []string{"test.url1", "test.url2", "test.url1", "test.url1"} {
+		_, err := writer.CreateClick(ctx, Click{URL: u})
+		assert.NoError(t, err)
+	}
+	created, err := writer.CreateView(ctx, View{EventID: "e1", URL: "test.url1", Referrer: "test.referrer", ScreenWidth: 1920, ScreenHeight: 1080})
+	assert.NoError(t, err)
+	assert.Equal(t, View{ID: 1, EventID: "e1", URL: "test.url1", Referrer: "test.referrer", ScreenWidth: 1920, ScreenHeight: 1080, CreatedAt: created.CreatedAt}, created)
+	assert.WithinDuration(t, time.Now(), created.CreatedAt, time.Minute)
+
+	reader := New(s.URL, s.readKey)
+	defer reader.Close(ctx)
+
+	s.requests.Store(0)
+	var ids []uint
+	it := reader.FilterClicks(ctx, Filter{URL: "test.url1", PageSize: 2})
+	for it.Next() {
+		ids = append(ids, it.Value().ID)
+	}
+	assert.NoError(t, it.Err())
+	assert.Equal(t, []uint{1, 3, 4}, ids)
+	assert.Equal(t, int32(2), s.requests.Load(), "pages are fetched as needed")
+
+	views := reader.FilterViews(ctx, Filter{})
+	assert.True(t, views.Next())
+	assert.Equal(t, "test.referrer", views.Value().Referrer)
+	assert.False(t, views.Next())
+
+	_, err = reader.CreateClick(ctx, Click{URL: "test.url1"})
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusForbidden, apiErr.StatusCode)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	s := newServer(t)
+	defer func() {
+		s.Close()
+		teardownDatabase(t)
+	}()
+
+	ctx := context.Background()
+	c := New(s.URL, s.writeKey, WithRetries(3, time.Millisecond, 10*time.Millisecond))
+	defer c.Close(ctx)
+
+	s.failures.Store(2)
+	_, err := c.CreateClick(ctx, Click{URL: "test.url1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), s.requests.Load())
+
+	s.failures.Store(3)
+	_, err = c.CreateClick(ctx, Click{URL: "test.url1"})
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusServiceUnavailable, apiErr.StatusCode)
+	}
+
+	s.failures.Store(100)
+	cancelled, cancel := context.WithTimeout(ctx, 5*time.Millisecond)
+	defer cancel()
+	c = New(s.URL, s.writeKey, WithRetries(100, time.Second, time.Second))
+	defer c.Close(ctx)
+	_, err = c.CreateClick(cancelled, Click{URL: "test.url1"})
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "waiting for a retry stops with the context")
+}
+
+func TestBatching(t *testing.T) {
+	s := newServer(t)
+	defer func() {
+		s.Close()
+		teardownDatabase(t)
+	}()
+
+	ctx := context.Background()
+	var backgroundErrors atomic.Int32
+	c := New(s.URL, s.writeKey,
+		WithBatching(3, time.Hour),
+		WithRetries(1, 0, 0),
+		WithErrorHandler(func(error) { backgroundErrors.Add(1) }),
+	)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.AddClick(Click{URL: "test.url1"}))
+	}
+	assert.Eventually(t, func() bool { return count(t, s, "/clicks") == 3 }, time.Second, 10*time.Millisecond, "a full batch is sent")
+	assert.Equal(t, int32(1), s.requests.Load(), "a batch is sent in one request")
+
+	s.failures.Store(1)
+	assert.NoError(t, c.AddView(View{URL: "test.url1"}))
+	assert.Error(t, c.Flush(ctx))
+	assert.NoError(t, c.AddView(View{URL: "test.url2"}))
+
+	assert.NoError(t, c.Close(ctx))
+	assert.Equal(t, 2, count(t, s, "/views"), "failed events are sent again when closing")
+	assert.Equal(t, int32(0), backgroundErrors.Load())
+	assert.ErrorIs(t, c.AddClick(Click{URL: "test.url1"}), ErrClosed)
+}
+
+func TestBatchingErrors(t *testing.T) {
+	s := newServer(t)
+	defer func() {
+		s.Close()
+		teardownDatabase(t)
+	}()
+
+	ctx := context.Background()
+	var backgroundErrors atomic.Int32
+	c := New(s.URL, s.writeKey,
+		WithBatching(3, time.Hour),
+		WithMaxQueued(3),
+		WithRetries(1, 0, 0),
+		WithErrorHandler(func(error) { backgroundErrors.Add(1) }),
+	)
+
+	s.failures.Store(100)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, c.AddClick(Click{URL: "test.url1"}))
+	}
+	assert.Eventually(t, func() bool { return backgroundErrors.Load() == 1 }, time.Second, 10*time.Millisecond, "a full batch is sent")
+	assert.ErrorIs(t, c.AddClick(Click{URL: "test.url1"}), ErrQueueFull)
+
+	s.failures.Store(0)
+	assert.NoError(t, c.AddView(View{}))
+	var apiErr *APIError
+	if assert.ErrorAs(t, c.Flush(ctx), &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	}
+	assert.NoError(t, c.Flush(ctx), "a rejected batch isn't sent again")
+
+	assert.NoError(t, c.Close(ctx))
+	assert.Equal(t, 3, count(t, s, "/clicks"), "failed events are sent again")
+	assert.Equal(t, 0, count(t, s, "/views"))
+}
+
+func count(t *testing.T, s *server, path string) int {
+	t.Helper()
+
+	reader := New(s.URL, s.readKey)
+	defer reader.Close(context.Background())
+
+	requests := s.requests.Load()
+	defer s.requests.Store(requests)
+
+	var it interface {
+		Next() bool
+		Err() error
+	}
+	if path == "/clicks" {
+		it = reader.FilterClicks(context.Background(), Filter{})
+	} else {
+		it = reader.FilterViews(context.Background(), Filter{})
+	}
+	n := 0
+	for it.Next() {
+		n++
+	}
+	assert.NoError(t, it.Err())
+	return n
+}
+
+func newServer(t *testing.T) *server {
+	t.Helper()
+
+	gormDB := setupDatabase(t)
+	projectRepository := project.NewSQLiteRepository(gormDB)
+	p, err := projectRepository.CreateProject(context.Background(), project.Project{Name: "test"})
+	assert.NoError(t, err)
+
+	s := &server{}
+	for _, key := range []struct {
+		scope project.Scope
+		plain *string
+	}{{project.ScopeRead, &s.readKey}, {project.ScopeWrite, &s.writeKey}} {
+		k, plain, err := project.NewKey(p.ID, key.scope)
+		assert.NoError(t, err)
+		_, err = projectRepository.CreateKey(context.Background(), k)
+		assert.NoError(t, err)
+		*key.plain = plain
+	}
+
+	clickHandler := click.NewHandler(click.NewSQLiteRepository(gormDB), nil, nil, nil, nil)
+	viewHandler := view.NewHandler(view.NewSQLiteRepository(gormDB), nil, nil, nil)
+	guard := idempotency.NewGuard(idempotency.NewSQLiteRepository(gormDB), idempotency.DefaultWindow)
+	readAuth := project.Auth(projectRepository, project.ScopeRead)
+	writeAuth := project.Auth(projectRepository, project.ScopeWrite)
+
+	e := echo.New()
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			s.requests.Add(1)
+			if s.failures.Add(-1) >= 0 {
+				return echo.NewHTTPError(http.StatusServiceUnavailable)
+			}
+			s.failures.Store(0)
+			return next(c)
+		}
+	})
+	e.GET("/clicks", clickHandler.Filter, readAuth)
+	e.POST("/clicks", clickHandler.Create, writeAuth, guard.Middleware())
+	e.GET("/views", viewHandler.Filter, readAuth)
+	e.POST("/views", viewHandler.Create, writeAuth, guard.Middleware())
+
+	s.Server = httptest.NewServer(e)
+	return s
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&click.ClickDAO{}, &view.ViewDAO{}, &project.ProjectDAO{}, &project.KeyDAO{}, &idempotency.RecordDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,219 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultPageSize is the number of events fetched at once by iterators.
+const DefaultPageSize = 100
+
+// Click is a single click on a URL.
+// EventID is an optional client generated identifier, used by the API to recognise resubmissions.
+// VisitorID optionally identifies the visitor, so their events can be analysed together.
+type Click struct {
+	ID        uint
+	EventID   string
+	VisitorID string
+	URL       string
+	CreatedAt time.Time
+}
+
+// View is a single view of a URL.
+// EventID is an optional client generated identifier, used by the API to recognise resubmissions.
+// VisitorID optionally identifies the visitor, so their events can be analysed together.
+type View struct {
+	ID           uint
+	EventID      string
+	VisitorID    string
+	URL          string
+	Referrer     string
+	ScreenWidth  int
+	ScreenHeight int
+	CreatedAt    time.Time
+}
+
+// Filter holds parameters for filtering events. Zero values are ignored.
+// PageSize is the number of events fetched by a single request, DefaultPageSize if zero.
+type Filter struct {
+	URL      string
+	After    time.Time
+	Before   time.Time
+	PageSize int
+}
+
+// eventJSON is the wire format of both clicks and views.
+type eventJSON struct {
+	ID           uint   `json:"id,omitempty"`
+	EventID      string `json:"eventId,omitempty"`
+	VisitorID    string `json:"visitorId,omitempty"`
+	URL          string `json:"url"`
+	Referrer     string `json:"referrer,omitempty"`
+	ScreenWidth  int    `json:"screenWidth,omitempty"`
+	ScreenHeight int    `json:"screenHeight,omitempty"`
+	CreatedAt    string `json:"createdAt,omitempty"`
+}
+
+func newClickJSON(c Click) eventJSON {
+	return eventJSON{EventID: c.EventID, VisitorID: c.VisitorID, URL: c.URL}
+}
+
+func (e eventJSON) click() Click {
+	return Click{ID: e.ID, EventID: e.EventID, VisitorID: e.VisitorID, URL: e.URL, CreatedAt: parseTime(e.CreatedAt)}
+}
+
+func newViewJSON(v View) eventJSON {
+	return eventJSON{EventID: v.EventID, VisitorID: v.VisitorID, URL: v.URL, Referrer: v.Referrer, ScreenWidth: v.ScreenWidth, ScreenHeight: v.ScreenHeight}
+}
+
+func (e eventJSON) view() View {
+	return View{
+		ID:           e.ID,
+		EventID:      e.EventID,
+		VisitorID:    e.VisitorID,
+		URL:          e.URL,
+		Referrer:     e.Referrer,
+		ScreenWidth:  e.ScreenWidth,
+		ScreenHeight: e.ScreenHeight,
+		CreatedAt:    parseTime(e.CreatedAt),
+	}
+}
+
+// parseTime parses RFC 3339 timestamps, falling back to "2006-01-02 15:04:05" in UTC sent by older servers.
+func parseTime(value string) time.Time {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	t, _ := time.Parse(time.DateTime, value)
+	return t
+}
+
+// CreateClick creates a Click and returns it as stored. Resubmitting a known EventID returns the original Click.
+func (c *Client) CreateClick(ctx context.Context, click Click) (Click, error) {
+	var created eventJSON
+	if err := c.do(ctx, http.MethodPost, "/clicks", nil, newClickJSON(click), &created); err != nil {
+		return Click{}, err
+	}
+	return created.click(), nil
+}
+
+// CreateView creates a View and returns it as stored. Resubmitting a known EventID returns the original View.
+func (c *Client) CreateView(ctx context.Context, view View) (View, error) {
+	var created eventJSON
+	if err := c.do(ctx, http.MethodPost, "/views", nil, newViewJSON(view), &created); err != nil {
+		return View{}, err
+	}
+	return created.view(), nil
+}
+
+// FilterClicks returns an iterator over Clicks matching filter, ordered by ID.
+func (c *Client) FilterClicks(ctx context.Context, filter Filter) *Iterator[Click] {
+	return newIterator(ctx, c, "/clicks", filter, eventJSON.click, func(click Click) uint { return click.ID })
+}
+
+// FilterViews returns an iterator over Views matching filter, ordered by ID.
+func (c *Client) FilterViews(ctx context.Context, filter Filter) *Iterator[View] {
+	return newIterator(ctx, c, "/views", filter, eventJSON.view, func(view View) uint { return view.ID })
+}
+
+// Iterator pages through events, fetching the next page when the current one is used up.
+//
+//	it := c.FilterClicks(ctx, client.Filter{URL: "https://example.com"})
+//	for it.Next() {
+//		fmt.Println(it.Value().CreatedAt)
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type Iterator[T any] struct {
+	ctx     context.Context
+	client  *Client
+	path    string
+	query   url.Values
+	limit   int
+	convert func(eventJSON) T
+	id      func(T) uint
+
+	page    []T
+	current T
+	afterID uint
+	last    bool
+	err     error
+}
+
+func newIterator[T any](ctx context.Context, client *Client, path string, filter Filter, convert func(eventJSON) T, id func(T) uint) *Iterator[T] {
+	query := url.Values{}
+	if filter.URL != "" {
+		query.Set("url", filter.URL)
+	}
+	if !filter.After.IsZero() {
+		query.Set("after", filter.After.Format(time.RFC3339))
+	}
+	if !filter.Before.IsZero() {
+		query.Set("before", filter.Before.Format(time.RFC3339))
+	}
+	limit := filter.PageSize
+	if limit <= 0 {
+		limit = DefaultPageSize
+	}
+
+	return &Iterator[T]{ctx: ctx, client: client, path: path, query: query, limit: limit, convert: convert, id: id}
+}
+
+// Next advances to the next event, which is then available through Value.
+// It returns false when there are no more events or an error occurred.
+func (it *Iterator[T]) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.page) == 0 {
+		if it.last {
+			return false
+		}
+		if it.err = it.fetch(); it.err != nil || len(it.page) == 0 {
+			return false
+		}
+	}
+
+	it.current, it.page = it.page[0], it.page[1:]
+	return true
+}
+
+func (it *Iterator[T]) fetch() error {
+	query := url.Values{}
+	for k, v := range it.query {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(it.limit))
+	if it.afterID > 0 {
+		query.Set("afterId", strconv.FormatUint(uint64(it.afterID), 10))
+	}
+
+	var events []eventJSON
+	if err := it.client.do(it.ctx, http.MethodGet, it.path, query, nil, &events); err != nil {
+		return err
+	}
+
+	it.page = make([]T, 0, len(events))
+	for _, e := range events {
+		it.page = append(it.page, it.convert(e))
+	}
+	it.last = len(events) < it.limit
+	if len(it.page) > 0 {
+		it.afterID = it.id(it.page[len(it.page)-1])
+	}
+	return nil
+}
+
+// Value returns the current event.
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// Err returns the error which stopped the iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
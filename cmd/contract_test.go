@@ -0,0 +1,248 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/metrics"
+	"google.com/ivan-sabo/clicks-and-views/internal/spec"
+)
+
+const adminKey = "test-admin-key"
+
+// echoParam matches path parameters in echo routes, e.g. ":id".
+var echoParam = regexp.MustCompile(`:([A-Za-z]+)`)
+
+func TestRoutesDocumented(t *testing.T) {
+	e, _ := setupService(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	doc, err := spec.Load()
+	assert.NoError(t, err)
+
+	routes := make(map[string]bool)
+	for _, route := range e.Routes() {
+		// groups register catch-all routes answering 404
+		if route.Method == echo.RouteNotFound {
+			continue
+		}
+		path := echoParam.ReplaceAllString(route.Path, "{$1}")
+		routes[route.Method+" "+path] = true
+
+		pathItem := doc.Paths.Value(path)
+		assert.True(t, pathItem != nil && pathItem.GetOperation(route.Method) != nil, "%s %s is documented", route.Method, path)
+	}
+
+	for path, pathItem := range doc.Paths.Map() {
+		for method := range pathItem.Operations() {
+			assert.True(t, routes[method+" "+path], "documented %s %s is served", method, path)
+		}
+	}
+}
+
+// TestContract calls every route and checks that responses match the specification.
+// Streaming endpoints are left out, since their responses never end.
+func TestContract(t *testing.T) {
+	e, validator := setupService(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	c := contract{t: t, e: e, validator: validator}
+
+	c.call(http.MethodPost, "/admin/projects", adminKey, echo.MIMEApplicationJSON, `{"name":"test"}`, http.StatusCreated)
+	c.call(http.MethodPost, "/admin/projects", adminKey, echo.MIMEApplicationJSON, `{}`, http.StatusBadRequest)
+	c.call(http.MethodGet, "/admin/projects", adminKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/admin/projects", "", "", "", http.StatusUnauthorized)
+	c.call(http.MethodPost, "/admin/projects", "", echo.MIMEApplicationJSON, `{}`, http.StatusUnauthorized)
+	writeKey := c.key(c.call(http.MethodPost, "/admin/projects/1/keys", adminKey, echo.MIMEApplicationJSON, `{"scope":"write"}`, http.StatusCreated))
+	readKey := c.key(c.call(http.MethodPost, "/admin/projects/1/keys", adminKey, echo.MIMEApplicationJSON, `{"scope":"read"}`, http.StatusCreated))
+	manageKey := c.key(c.call(http.MethodPost, "/admin/projects/1/keys", adminKey, echo.MIMEApplicationJSON, `{"scope":"manage"}`, http.StatusCreated))
+	c.call(http.MethodPost, "/admin/projects/2/keys", adminKey, echo.MIMEApplicationJSON, `{"scope":"write"}`, http.StatusNotFound)
+	c.call(http.MethodPost, "/admin/projects/1/keys", adminKey, echo.MIMEApplicationJSON, `{"scope":"admin"}`, http.StatusBadRequest)
+	c.call(http.MethodGet, "/admin/projects/1/keys", adminKey, "", "", http.StatusOK)
+
+	for _, kind := range []string{"clicks", "views"} {
+		c.call(http.MethodPost, "/"+kind, writeKey, echo.MIMEApplicationJSON, `{"url":"https://example.com/a","visitorId":"v1"}`, http.StatusCreated)
+		c.call(http.MethodPost, "/"+kind, writeKey, "text/plain;charset=UTF-8", `[{"eventId":"e1","url":"https://example.com/b"}]`, http.StatusCreated)
+		c.call(http.MethodPost, "/"+kind, writeKey, echo.MIMEApplicationJSON, `{"url":1}`, http.StatusBadRequest)
+		c.call(http.MethodPost, "/"+kind, "", echo.MIMEApplicationJSON, `{"url":"https://example.com/a"}`, http.StatusUnauthorized)
+		c.call(http.MethodPost, "/"+kind, "", echo.MIMEApplicationJSON, `{"url":1}`, http.StatusUnauthorized)
+		c.call(http.MethodGet, "/"+kind+"?url=https%3A%2F%2Fexample.com%2Fa&after=2024-01-02T03:04:05Z&limit=10", readKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"?after=now-7d&before=4102444800", readKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"?url=%2Fa&url=%2Fb&url!=%2Fc&urlPrefix=https%3A%2F%2F&urlGlob=*%2Fa&host=example.com&domain=example.com&path=%2F*", readKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"?q="+url.QueryEscape(`url ~ "/a" and (id > 1 or createdAt > now-1d)`), readKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"?q="+url.QueryEscape(`url ~`), readKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodGet, "/"+kind+"?url="+strings.Repeat("%2Fa&url=", 100)+"%2Fa", readKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodGet, "/"+kind+"?after=yesterday", readKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodGet, "/"+kind+"?limit=-1", readKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodGet, "/"+kind, writeKey, "", "", http.StatusForbidden)
+		c.call(http.MethodGet, "/"+kind+"/1", readKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"/first", readKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodPatch, "/"+kind+"/1", manageKey, echo.MIMEApplicationJSON, `{"url":"https://example.com/c","visitorId":"v2"}`, http.StatusOK)
+		c.call(http.MethodPatch, "/"+kind+"/1", manageKey, echo.MIMEApplicationJSON, `{"url":""}`, http.StatusBadRequest)
+		c.call(http.MethodPatch, "/"+kind+"/9", manageKey, echo.MIMEApplicationJSON, `{"visitorId":"v2"}`, http.StatusNotFound)
+		c.call(http.MethodPatch, "/"+kind+"/1", writeKey, echo.MIMEApplicationJSON, `{"visitorId":"v2"}`, http.StatusForbidden)
+		c.call(http.MethodDelete, "/"+kind+"/1", manageKey, "", "", http.StatusNoContent)
+		c.call(http.MethodGet, "/"+kind+"/1", readKey, "", "", http.StatusNotFound)
+		c.call(http.MethodDelete, "/"+kind+"/1", manageKey, "", "", http.StatusNotFound)
+		c.call(http.MethodDelete, "/"+kind+"/1", writeKey, "", "", http.StatusForbidden)
+		c.call(http.MethodPost, "/"+kind+"/1/restore", manageKey, "", "", http.StatusOK)
+		c.call(http.MethodGet, "/"+kind+"/1", readKey, "", "", http.StatusOK)
+		c.call(http.MethodPost, "/"+kind+"/9/restore", manageKey, "", "", http.StatusNotFound)
+		c.call(http.MethodPost, "/"+kind+"/1/restore", writeKey, "", "", http.StatusForbidden)
+		c.call(http.MethodDelete, "/"+kind+"?url=https%3A%2F%2Fexample.com%2Fb&before=now", manageKey, "", "", http.StatusOK)
+		c.call(http.MethodDelete, "/"+kind+"?url=https%3A%2F%2Fexample.com%2Fb", writeKey, "", "", http.StatusForbidden)
+		c.call(http.MethodDelete, "/"+kind, manageKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodDelete, "/"+kind+"?host=example.com&limit=1", manageKey, "", "", http.StatusBadRequest)
+		c.call(http.MethodDelete, "/admin/projects/1/"+kind+"?deletedBefore=now", adminKey, "", "", http.StatusOK)
+		c.call(http.MethodDelete, "/admin/projects/1/"+kind, writeKey, "", "", http.StatusUnauthorized)
+	}
+
+	c.call(http.MethodGet, "/tracker.js", "", "", "", http.StatusOK)
+	c.call(http.MethodGet, "/v.gif?url=https%3A%2F%2Fexample.com&key="+writeKey, "", "", "", http.StatusOK)
+	c.call(http.MethodGet, "/v.gif?key="+writeKey, "", "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/r?to=https%3A%2F%2Fexample.com%2Fsale&key="+writeKey, "", "", "", http.StatusFound)
+	c.call(http.MethodGet, "/r?to=https%3A%2F%2Fevil.example&key="+writeKey, "", "", "", http.StatusBadRequest)
+
+	c.call(http.MethodPost, "/links", manageKey, echo.MIMEApplicationJSON, `{"code":"sale","destination":"https://example.com/sale"}`, http.StatusCreated)
+	c.call(http.MethodPost, "/links", manageKey, echo.MIMEApplicationJSON, `{"code":"sale","destination":"https://example.com/sale"}`, http.StatusConflict)
+	c.call(http.MethodPost, "/links", manageKey, echo.MIMEApplicationJSON, `{"destination":"/sale"}`, http.StatusUnprocessableEntity)
+	c.call(http.MethodPost, "/links", manageKey, echo.MIMEApplicationJSON, `{"destination":"https://evil.example/sale"}`, http.StatusUnprocessableEntity)
+	c.call(http.MethodPost, "/links", writeKey, echo.MIMEApplicationJSON, `{"destination":"https://example.com/sale"}`, http.StatusForbidden)
+	c.call(http.MethodGet, "/links", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/links/1", readKey, "", "", http.StatusOK)
+	c.call(http.MethodPut, "/links/1", manageKey, echo.MIMEApplicationJSON, `{"destination":"https://example.com/sale2","expiresAt":"2030-01-01T00:00:00Z"}`, http.StatusOK)
+	c.call(http.MethodGet, "/s/sale", "", "", "", http.StatusFound)
+	c.call(http.MethodGet, "/s/unknown", "", "", "", http.StatusNotFound)
+	c.call(http.MethodGet, "/links/1/stats?period=hour&tz=Europe/Zagreb", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/links/1/stats?tz=Nowhere", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/links/1/stats?period=week", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodDelete, "/links/1", writeKey, "", "", http.StatusForbidden)
+	c.call(http.MethodDelete, "/links/1", manageKey, "", "", http.StatusNoContent)
+	c.call(http.MethodGet, "/links/1", readKey, "", "", http.StatusNotFound)
+
+	c.call(http.MethodPost, "/import?format=csv", writeKey, "text/csv", "type,url,created_at\nclick,https://example.com,2024-01-02T03:04:05Z\n", http.StatusOK)
+	c.call(http.MethodPost, "/import?format=xml", writeKey, "text/xml", "<clicks/>", http.StatusBadRequest)
+
+	c.call(http.MethodGet, "/funnel?step=view%3Ahttps%3A%2F%2Fexample.com%2Fa&step=click%3Ahttps%3A%2F%2Fexample.com%2Fa&window=1h", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/funnel?step=view%3Ahttps%3A%2F%2Fexample.com%2Fa", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/sessions?timeout=1h&limit=5", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/sessions?timeout=soon", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/paths?url=https%3A%2F%2Fexample.com%2Fa", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/paths", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/stats/top?type=click&limit=3", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/stats/top?type=view&mode=trending&approximate=true", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/stats/top?type=scroll", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/stats/count?type=click&period=day&tz=Europe%2FZagreb", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/stats/count?type=view&period=day&after=now-7d&compare=previous_period", readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/stats/count?type=view&compare=previous_year", readKey, "", "", http.StatusBadRequest)
+
+	c.call(http.MethodPost, "/graphql", readKey, echo.MIMEApplicationJSON, `{"query":"{ clicks { totalCount } }"}`, http.StatusOK)
+	c.call(http.MethodGet, "/graphql?query="+url.QueryEscape("{ views { totalCount } }"), readKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/graphql", readKey, "", "", http.StatusBadRequest)
+
+	c.call(http.MethodPost, "/webhooks", manageKey, echo.MIMEApplicationJSON, `{"url":"https://192.0.2.10/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`, http.StatusCreated)
+	c.call(http.MethodPost, "/webhooks", manageKey, echo.MIMEApplicationJSON, `{"url":"https://example.com/hook","eventType":"click","condition":{"threshold":1000,"window":"soon"}}`, http.StatusUnprocessableEntity)
+	c.call(http.MethodPost, "/webhooks", manageKey, echo.MIMEApplicationJSON, `{"url":"http://169.254.169.254/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`, http.StatusUnprocessableEntity)
+	c.call(http.MethodGet, "/webhooks", manageKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/webhooks", writeKey, "", "", http.StatusForbidden)
+	c.call(http.MethodGet, "/webhooks/1", manageKey, "", "", http.StatusOK)
+	c.call(http.MethodGet, "/webhooks/1/deliveries?status=dead", manageKey, "", "", http.StatusOK)
+	c.call(http.MethodPost, "/webhooks/1/deliveries/1/redeliver", manageKey, "", "", http.StatusNotFound)
+	c.call(http.MethodDelete, "/webhooks/1", manageKey, "", "", http.StatusNoContent)
+	c.call(http.MethodGet, "/webhooks/1", manageKey, "", "", http.StatusNotFound)
+
+	c.call(http.MethodGet, "/live", readKey, "", "", http.StatusBadRequest)
+	c.call(http.MethodGet, "/metrics", "", "", "", http.StatusOK)
+	c.call(http.MethodGet, "/healthz", "", "", "", http.StatusOK)
+	c.call(http.MethodGet, "/readyz", "", "", "", http.StatusOK)
+	c.call(http.MethodGet, "/version", "", "", "", http.StatusOK)
+
+	c.call(http.MethodDelete, "/admin/keys/1", adminKey, "", "", http.StatusNoContent)
+	c.call(http.MethodDelete, "/admin/keys/1", adminKey, "", "", http.StatusNotFound)
+}
+
+type contract struct {
+	t         *testing.T
+	e         *echo.Echo
+	validator *spec.Validator
+}
+
+// call sends a request and checks its response status, and that the response is documented.
+func (c contract) call(method, target, key, contentType, body string, expectedStatus int) *httptest.ResponseRecorder {
+	c.t.Helper()
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if contentType != "" {
+		req.Header.Set(echo.HeaderContentType, contentType)
+	}
+	if key != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+	}
+	rec := httptest.NewRecorder()
+	c.e.ServeHTTP(rec, req)
+
+	assert.Equal(c.t, expectedStatus, rec.Code, "%s %s: %s", method, target, rec.Body.String())
+	assert.NoError(c.t, c.validator.ValidateResponse(req, rec.Code, rec.Header(), rec.Body.Bytes()), "%s %s", method, target)
+	return rec
+}
+
+// key returns the plain key from the response to key creation.
+func (c contract) key(rec *httptest.ResponseRecorder) string {
+	c.t.Helper()
+
+	var key struct {
+		Key string `json:"key"`
+	}
+	assert.NoError(c.t, json.Unmarshal(rec.Body.Bytes(), &key))
+	return key.Key
+}
+
+func setupService(t *testing.T) (*echo.Echo, *spec.Validator) {
+	t.Helper()
+
+	t.Setenv("ADMIN_API_KEY", adminKey)
+	t.Setenv("REDIRECT_ALLOWLIST", "example.com")
+
+	gormDB, err := openDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := spec.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	validator, err := spec.NewValidator(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := echo.New()
+	if _, err := newService(e, gormDB, metrics.New(sqlDB), validator.Middleware()); err != nil {
+		t.Fatal(err)
+	}
+
+	return e, validator
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove(databaseFile)
+}
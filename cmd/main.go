@@ -1,37 +1,585 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.com/ivan-sabo/clicks-and-views/internal/analytics"
 	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/gql"
+	"google.com/ivan-sabo/clicks-and-views/internal/health"
+	"google.com/ivan-sabo/clicks-and-views/internal/idempotency"
+	"google.com/ivan-sabo/clicks-and-views/internal/importer"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/metrics"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/ratelimit"
+	"google.com/ivan-sabo/clicks-and-views/internal/rpc"
+	"google.com/ivan-sabo/clicks-and-views/internal/rpc/pb"
+	"google.com/ivan-sabo/clicks-and-views/internal/shortlink"
+	"google.com/ivan-sabo/clicks-and-views/internal/spec"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+	"google.com/ivan-sabo/clicks-and-views/internal/topk"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracing"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracker"
 	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"google.com/ivan-sabo/clicks-and-views/internal/webhook"
+	"google.golang.org/grpc"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+const databaseFile = "gorm.db"
+
+// defaultGRPCAddress is used when GRPC_ADDR environment variable is not set.
+const defaultGRPCAddress = ":9090"
+
+// defaultPublicURL is used when PUBLIC_URL environment variable is not set.
+const defaultPublicURL = "http://localhost:8080"
+
+// schemaVersion is the version of the database schema the service expects.
+// It must be incremented whenever a model or a migration in openDatabase changes.
+const schemaVersion uint = 8
+
+// backfillBatchSize is the number of events updated at once when backfilling columns added to existing tables.
+const backfillBatchSize = 1000
+
+// commit and buildTime are set at build time with
+// -ldflags "-X main.commit=$(git rev-parse HEAD) -X main.buildTime=$(date -u +%FT%TZ)".
+var (
+	commit    string
+	buildTime string
+)
+
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	serve()
+}
+
+func serve() {
 	e := echo.New()
+
+	// client addresses key rate limits and dedup, so forwarding headers are only trusted from known proxies
+	ipExtractor, err := newIPExtractor(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.IPExtractor = ipExtractor
+
+	shutdownTracing, err := tracing.Setup(os.Getenv("OTEL_TRACES_EXPORTER"), os.Stdout)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	e.Use(tracing.Middleware())
+
+	gormDB, err := openDatabase()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	serviceMetrics := metrics.New(sqlDB)
+	e.Use(serviceMetrics.Middleware())
+
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins: []string{"http://localhost"},
-		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept},
+		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization, idempotency.HeaderIdempotencyKey, stream.HeaderLastEventID, "traceparent", "tracestate"},
 	}))
 
-	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	limiter, err := newLimiter(os.Getenv("RATE_LIMIT_CONFIG"))
 	if err != nil {
 		e.Logger.Fatal(err)
 	}
-	gormDB.AutoMigrate(&view.View{}, &click.Click{})
+	e.Use(limiter.Middleware())
 
-	clickRepository := click.NewSQLiteRepository(gormDB)
-	viewRepository := view.NewSQLiteRepository(gormDB)
+	apiSpec, err := spec.Load()
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	validator, err := spec.NewValidator(apiSpec)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	svc, err := newService(e, gormDB, serviceMetrics, validator.Middleware())
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	notifierCtx, stopNotifier := context.WithCancel(context.Background())
+	notifierDone := make(chan struct{})
+	go func() {
+		defer close(notifierDone)
+		svc.notifier.Run(notifierCtx)
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := e.Start(":8080"); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	grpcAddress := os.Getenv("GRPC_ADDR")
+	if grpcAddress == "" {
+		grpcAddress = defaultGRPCAddress
+	}
+	grpcListener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		e.Logger.Fatal(err)
+	}
+	go func() {
+		if err := svc.grpcServer.Serve(grpcListener); err != nil {
+			e.Logger.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	svc.clickHub.Close()
+	svc.viewHub.Close()
+	// in-flight calls are given the same time to finish as HTTP requests
+	grpcStopped := make(chan struct{})
+	go func() {
+		svc.grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		svc.grpcServer.Stop()
+	}
+	stopNotifier()
+	<-notifierDone
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		e.Logger.Error(err)
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		e.Logger.Error(err)
+	}
+}
 
-	clickHandler := click.NewHandler(clickRepository)
-	viewHandler := view.NewHandler(viewRepository)
+// service holds the components shared by the HTTP and gRPC APIs,
+// which have to be started and stopped along with them.
+type service struct {
+	grpcServer *grpc.Server
+	notifier   *webhook.Notifier
+	clickHub   *stream.Hub[click.Click]
+	viewHub    *stream.Hub[view.View]
+}
+
+// newService registers HTTP routes on e and creates the gRPC server, both backed by gormDB.
+// Requests are checked by validate only after they are authenticated, so the specification
+// isn't revealed to unauthenticated callers by validation errors.
+func newService(e *echo.Echo, gormDB *gorm.DB, serviceMetrics *metrics.Metrics, validate echo.MiddlewareFunc) (*service, error) {
+	clickRepository := metrics.NewClickRepository(click.NewSQLiteRepository(gormDB), serviceMetrics)
+	viewRepository := metrics.NewViewRepository(view.NewSQLiteRepository(gormDB), serviceMetrics)
+
+	webhookRepository := webhook.NewSQLiteRepository(gormDB)
+	notifier := webhook.NewNotifier(webhookRepository, clickRepository, viewRepository, webhook.DefaultConfig)
+	serviceMetrics.RegisterQueue("webhook_events", notifier.Len)
+	// events are ingested through repositories which count them in heavy hitters sketches
+	// and pass them on to the webhook notifier
+	topURLs := topk.NewTracker(topk.DefaultCapacity, topk.DefaultSlots)
+	clickIngestRepository := webhook.NewClickRepository(topk.NewClickRepository(clickRepository, topURLs), notifier)
+	viewIngestRepository := webhook.NewViewRepository(topk.NewViewRepository(viewRepository, topURLs), notifier)
+	projectRepository := project.NewSQLiteRepository(gormDB)
+
+	var (
+		clickDedupWindow *dedup.Window[click.Click]
+		viewDedupWindow  *dedup.Window[view.View]
+	)
+	if window, err := durationFromEnv("DEDUP_WINDOW", 0); err != nil {
+		return nil, err
+	} else if window > 0 {
+		clickDedupWindow = dedup.NewWindow[click.Click](window)
+		viewDedupWindow = dedup.NewWindow[view.View](window)
+	}
+
+	idempotencyWindow, err := durationFromEnv("IDEMPOTENCY_WINDOW", idempotency.DefaultWindow)
+	if err != nil {
+		return nil, err
+	}
+	idempotencyGuard := idempotency.NewGuard(idempotency.NewSQLiteRepository(gormDB), idempotencyWindow)
+
+	clickHub := stream.NewHub[click.Click](stream.DefaultHistorySize, stream.DefaultBufferSize)
+	viewHub := stream.NewHub[view.View](stream.DefaultHistorySize, stream.DefaultBufferSize)
+	serviceMetrics.RegisterQueue("click_stream", clickHub.Len)
+	serviceMetrics.RegisterQueue("view_stream", viewHub.Len)
+
+	counters := live.NewCounters(live.DefaultMaxURLs)
+
+	// short links are restricted to the same hosts as /r
+	redirectAllowlist := click.NewAllowlist(os.Getenv("REDIRECT_ALLOWLIST"))
+	clickHandler := click.NewHandler(clickIngestRepository, clickDedupWindow, clickHub, counters, redirectAllowlist)
+	viewHandler := view.NewHandler(viewIngestRepository, viewDedupWindow, viewHub, counters)
+	liveHandler := live.NewHandler(counters, live.DefaultInterval)
+	importHandler := importer.NewHandler(importer.NewImporter(clickIngestRepository, viewIngestRepository, importer.DefaultBatchSize))
+	webhookHandler := webhook.NewHandler(webhookRepository)
+	graphqlHandler := gql.NewHandler(clickRepository, viewRepository)
+	projectHandler := project.NewHandler(projectRepository)
+	analyticsHandler := analytics.NewHandler(
+		analytics.NewSQLiteRepository(gormDB),
+		analytics.NewSketchRanker(topURLs),
+		analytics.NewEventCounter(clickRepository, viewRepository),
+	)
+
+	publicURL := os.Getenv("PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = defaultPublicURL
+	}
+	linkHandler := shortlink.NewHandler(shortlink.NewSQLiteRepository(gormDB), clickRepository, &clickHandler, publicURL, redirectAllowlist)
+
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, err
+	}
+	checker := health.NewChecker(health.DefaultTimeout)
+	checker.Add("database", health.Database(sqlDB))
+	checker.Add("schema", health.Schema(gormDB, schemaVersion))
+	checker.Add("webhook_queue", health.Queue(notifier.Len, notifier.Cap()))
+	healthHandler := health.NewHandler(checker, health.NewBuildInfo(commit, buildTime, schemaVersion))
+
+	readAuth := validated(project.Auth(projectRepository, project.ScopeRead), validate)
+	writeAuth := validated(project.Auth(projectRepository, project.ScopeWrite), validate)
+	manageAuth := validated(project.Auth(projectRepository, project.ScopeManage), validate)
+	// EventSource and WebSocket can't send headers, so stream keys may be passed as a query parameter
+	streamAuth := validated(project.AuthWithLookup(projectRepository, project.ScopeRead, project.DefaultKeyLookup+",query:key"), validate)
+	// the same goes for tracking pixels in emails and links, and beacons sent by tracker.js
+	trackAuth := validated(project.AuthWithLookup(projectRepository, project.ScopeWrite, project.DefaultKeyLookup+",query:key"), validate)
+
+	e.GET("/clicks", clickHandler.Filter, readAuth)
+	e.POST("/clicks", clickHandler.Create, trackAuth, idempotencyGuard.Middleware())
+	e.DELETE("/clicks", clickHandler.DeleteMatching, manageAuth)
+	e.GET("/clicks/stream", clickHandler.Stream, streamAuth)
+	e.GET("/clicks/:id", clickHandler.Get, readAuth)
+	e.PATCH("/clicks/:id", clickHandler.Update, manageAuth)
+	e.DELETE("/clicks/:id", clickHandler.Delete, manageAuth)
+	e.POST("/clicks/:id/restore", clickHandler.Restore, manageAuth)
+	e.GET("/views", viewHandler.Filter, readAuth)
+	e.POST("/views", viewHandler.Create, trackAuth, idempotencyGuard.Middleware())
+	e.DELETE("/views", viewHandler.DeleteMatching, manageAuth)
+	e.GET("/views/stream", viewHandler.Stream, streamAuth)
+	e.GET("/views/:id", viewHandler.Get, readAuth)
+	e.PATCH("/views/:id", viewHandler.Update, manageAuth)
+	e.DELETE("/views/:id", viewHandler.Delete, manageAuth)
+	e.POST("/views/:id/restore", viewHandler.Restore, manageAuth)
+	e.GET("/tracker.js", tracker.Serve, validate)
+	e.GET("/v.gif", viewHandler.Pixel, trackAuth)
+	e.GET("/r", clickHandler.Redirect, trackAuth)
+	e.GET("/s/:code", linkHandler.Resolve, validate)
+	e.POST("/links", linkHandler.Create, manageAuth)
+	e.GET("/links", linkHandler.List, readAuth)
+	e.GET("/links/:id", linkHandler.Get, readAuth)
+	e.PUT("/links/:id", linkHandler.Update, manageAuth)
+	e.DELETE("/links/:id", linkHandler.Delete, manageAuth)
+	e.GET("/links/:id/stats", linkHandler.Stats, readAuth)
+	e.POST("/import", importHandler.Import, writeAuth)
+	e.GET("/funnel", analyticsHandler.Funnel, readAuth)
+	e.GET("/sessions", analyticsHandler.Sessions, readAuth)
+	e.GET("/paths", analyticsHandler.Paths, readAuth)
+	e.GET("/stats/top", analyticsHandler.Top, readAuth)
+	e.GET("/stats/count", analyticsHandler.Count, readAuth)
+	e.GET("/live", liveHandler.Subscribe, streamAuth)
+	e.GET("/graphql", graphqlHandler.Query, readAuth)
+	e.POST("/graphql", graphqlHandler.Query, readAuth)
+	e.POST("/webhooks", webhookHandler.Create, manageAuth)
+	e.GET("/webhooks", webhookHandler.List, manageAuth)
+	e.GET("/webhooks/:id", webhookHandler.Get, manageAuth)
+	e.DELETE("/webhooks/:id", webhookHandler.Delete, manageAuth)
+	e.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries, manageAuth)
+	e.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver, manageAuth)
+	e.GET("/metrics", serviceMetrics.Handler(), validate)
+	e.GET("/healthz", healthHandler.Live, validate)
+	e.GET("/readyz", healthHandler.Ready, validate)
+	e.GET("/version", healthHandler.Version, validate)
+
+	auth := rpc.NewAuth(projectRepository)
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(auth.Unary()),
+		grpc.ChainStreamInterceptor(auth.Stream()),
+	)
+	pb.RegisterClicksAndViewsServer(grpcServer, rpc.NewServer(
+		clickIngestRepository,
+		viewIngestRepository,
+		// gRPC API records events with the same dedup windows, hubs and counters as HTTP handlers
+		click.NewRecorder(clickIngestRepository, clickDedupWindow, clickHub, counters),
+		view.NewRecorder(viewIngestRepository, viewDedupWindow, viewHub, counters),
+	))
+
+	admin := e.Group("/admin", validated(adminAuth(os.Getenv("ADMIN_API_KEY")), validate))
+	admin.POST("/projects", projectHandler.CreateProject)
+	admin.GET("/projects", projectHandler.ListProjects)
+	admin.POST("/projects/:id/keys", projectHandler.CreateKey)
+	admin.GET("/projects/:id/keys", projectHandler.ListKeys)
+	admin.DELETE("/keys/:id", projectHandler.DeleteKey)
+	admin.DELETE("/projects/:id/clicks", clickHandler.Purge)
+	admin.DELETE("/projects/:id/views", viewHandler.Purge)
+
+	return &service{
+		grpcServer: grpcServer,
+		notifier:   notifier,
+		clickHub:   clickHub,
+		viewHub:    viewHub,
+	}, nil
+}
+
+// durationFromEnv parses a duration from environment variable, or returns fallback if it's not set.
+func durationFromEnv(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return d, nil
+}
+
+// newIPExtractor returns an extractor of client addresses. Without trusted proxies, given as a comma separated
+// list of CIDR ranges, the address of the connection is used, otherwise X-Forwarded-For set by them.
+func newIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	var trusted []echo.TrustOption
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		trusted = append(trusted, echo.TrustIPRange(ipRange))
+	}
+
+	if len(trusted) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	// echo trusts loopback, link-local and private addresses by default, only the configured ranges are trusted here
+	options := append([]echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}, trusted...)
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
+// newLimiter creates a rate limiter configured from a given file, or with the default
+// configuration if there is none. The file is read again on every SIGHUP.
+func newLimiter(configPath string) (*ratelimit.Limiter, error) {
+	if configPath == "" {
+		return ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.DefaultConfig), nil
+	}
+
+	config, err := ratelimit.LoadConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), config)
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := limiter.Reload(configPath); err != nil {
+				log.Printf("reloading rate limits: %v", err)
+				continue
+			}
+			log.Printf("rate limits reloaded from %s", configPath)
+		}
+	}()
+
+	return limiter, nil
+}
+
+func openDatabase() (*gorm.DB, error) {
+	gormDB, err := gorm.Open(sqlite.Open(databaseFile), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := gormDB.Use(tracing.Plugin{}); err != nil {
+		return nil, err
+	}
+
+	// external IDs used to be unique globally, now they are unique per project
+	for _, legacy := range []struct {
+		model any
+		index string
+	}{
+		{&view.ViewDAO{}, "idx_views_external_id"},
+		{&click.ClickDAO{}, "idx_clicks_external_id"},
+	} {
+		if gormDB.Migrator().HasIndex(legacy.model, legacy.index) {
+			if err := gormDB.Migrator().DropIndex(legacy.model, legacy.index); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	applied, err := health.AppliedSchemaVersion(context.Background(), gormDB)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := gormDB.AutoMigrate(
+		&view.ViewDAO{},
+		&click.ClickDAO{},
+		&project.ProjectDAO{},
+		&project.KeyDAO{},
+		&idempotency.RecordDAO{},
+		&webhook.WebhookDAO{},
+		&webhook.DeliveryDAO{},
+		&webhook.AttemptDAO{},
+		&shortlink.LinkDAO{},
+	); err != nil {
+		return nil, err
+	}
+
+	// hosts and paths are split out of URLs since version 6
+	if applied < 6 {
+		if _, err := click.NewSQLiteRepository(gormDB).BackfillURLParts(context.Background(), backfillBatchSize); err != nil {
+			return nil, err
+		}
+		if _, err := view.NewSQLiteRepository(gormDB).BackfillURLParts(context.Background(), backfillBatchSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// creation times are stored in UTC since version 8, so they compare correctly as text
+	if applied < 8 {
+		if _, err := click.NewSQLiteRepository(gormDB).BackfillUTC(context.Background(), backfillBatchSize); err != nil {
+			return nil, err
+		}
+		if _, err := view.NewSQLiteRepository(gormDB).BackfillUTC(context.Background(), backfillBatchSize); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := health.RecordSchemaVersion(gormDB, schemaVersion); err != nil {
+		return nil, err
+	}
+
+	return gormDB, nil
+}
+
+// validated chains validate after auth, so that only authenticated requests are validated.
+func validated(auth, validate echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return auth(validate(next))
+	}
+}
+
+// adminAuth accepts requests carrying the admin key as a bearer token.
+// All requests are rejected when the admin key is not configured.
+func adminAuth(adminKey string) echo.MiddlewareFunc {
+	return middleware.KeyAuthWithConfig(middleware.KeyAuthConfig{
+		Validator: func(key string, c echo.Context) (bool, error) {
+			return adminKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(adminKey)) == 1, nil
+		},
+		ErrorHandler: project.Unauthorized,
+	})
+}
+
+// runImport implements "import" subcommand which loads a CSV or NDJSON file into the database.
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	projectID := fs.Uint("project", 0, "ID of the project events are imported into (required)")
+	format := fs.String("format", "", "input format, csv or ndjson (default: derived from file extension)")
+	batchSize := fs.Int("batch", importer.DefaultBatchSize, "number of records persisted in a single batch")
+	checkpointPath := fs.String("checkpoint", "", "checkpoint file used to resume interrupted import (default: <file>.checkpoint)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: main import -project <id> [flags] <file>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one input file")
+	}
+	if *projectID == 0 {
+		return fmt.Errorf("-project flag is required")
+	}
+
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	if *checkpointPath == "" {
+		*checkpointPath = path + ".checkpoint"
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	reader, err := importer.NewReader(*format, f)
+	if err != nil {
+		return err
+	}
+
+	gormDB, err := openDatabase()
+	if err != nil {
+		return err
+	}
+
+	if _, err := project.NewSQLiteRepository(gormDB).GetProject(context.Background(), *projectID); err != nil {
+		return fmt.Errorf("project %d: %w", *projectID, err)
+	}
+
+	checkpoint := importer.NewCheckpoint(*checkpointPath)
+	offset, err := checkpoint.Load()
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		log.Printf("resuming import after %d records", offset)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	i := importer.NewImporter(click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB), *batchSize)
+	progress, err := i.Import(ctx, *projectID, reader, offset, func(p importer.Progress) {
+		log.Printf("read %d, imported %d, duplicates %d", p.Read, p.Imported, p.Duplicates)
+		if err := checkpoint.Save(p.Read); err != nil {
+			log.Printf("saving checkpoint: %v", err)
+		}
+	})
+	if err != nil {
+		if saveErr := checkpoint.Save(progress.Read); saveErr != nil {
+			log.Printf("saving checkpoint: %v", saveErr)
+		}
+		return fmt.Errorf("import stopped after %d records, run the same command again to resume: %w", progress.Read, err)
+	}
 
-	e.GET("/clicks", clickHandler.Filter)
-	e.POST("/clicks", clickHandler.Create)
-	e.GET("/views", viewHandler.Filter)
-	e.POST("/views", viewHandler.Create)
+	log.Printf("import finished: read %d, imported %d, duplicates %d", progress.Read, progress.Imported, progress.Duplicates)
 
-	e.Logger.Fatal(e.Start(":8080"))
+	return checkpoint.Clear()
 }
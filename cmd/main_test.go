@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPExtractor(t *testing.T) {
+	tests := []struct {
+		testName       string
+		trustedProxies string
+		remoteAddr     string
+		expected       string
+	}{
+		{testName: "no trusted proxies", remoteAddr: "203.0.113.7:1234", expected: "203.0.113.7"},
+		{testName: "private address isn't trusted by default", remoteAddr: "10.0.0.1:1234", expected: "10.0.0.1"},
+		{testName: "trusted proxy", trustedProxies: "10.0.0.0/8, 192.0.2.0/24", remoteAddr: "10.0.0.1:1234", expected: "198.51.100.1"},
+		{testName: "untrusted proxy", trustedProxies: "192.0.2.0/24", remoteAddr: "10.0.0.1:1234", expected: "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			extract, err := newIPExtractor(test.trustedProxies)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = test.remoteAddr
+			req.Header.Set("X-Forwarded-For", "198.51.100.1")
+			req.Header.Set("X-Real-IP", "198.51.100.2")
+			assert.Equal(t, test.expected, extract(req))
+		})
+	}
+
+	_, err := newIPExtractor("10.0.0.1")
+	assert.Error(t, err, "ranges must be given in CIDR notation")
+}
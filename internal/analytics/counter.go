@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// EventCounter is a Counter backed by click and view repositories,
+// so events are bucketed the same way as everywhere else.
+type EventCounter struct {
+	clickRepository click.Repository
+	viewRepository  view.Repository
+}
+
+// Series implements Counter interface.
+// Buckets of a bounded CountFilter include empty ones, so series of different periods can be aligned.
+func (c *EventCounter) Series(ctx context.Context, filter CountFilter) (Series, error) {
+	var (
+		series Series
+		err    error
+	)
+	switch filter.Type {
+	case TypeClick:
+		series, err = c.clicks(ctx, filter)
+	case TypeView:
+		series, err = c.views(ctx, filter)
+	default:
+		return Series{}, fmt.Errorf("unsupported event type %q", filter.Type)
+	}
+	if err != nil {
+		return Series{}, err
+	}
+
+	if starts := filter.Buckets(); starts != nil {
+		counts := make(map[int64]int64, len(series.Buckets))
+		for _, b := range series.Buckets {
+			counts[b.Start.Unix()] = b.Count
+		}
+		series.Buckets = make([]Bucket, 0, len(starts))
+		for _, start := range starts {
+			series.Buckets = append(series.Buckets, Bucket{Start: start, Count: counts[start.Unix()]})
+		}
+	}
+
+	return series, nil
+}
+
+func (c *EventCounter) clicks(ctx context.Context, filter CountFilter) (Series, error) {
+	clickFilter := click.Filter{ProjectID: filter.ProjectID, URL: filter.URL, After: filter.After, Before: filter.Before}
+
+	total, err := c.clickRepository.Count(ctx, clickFilter)
+	if err != nil {
+		return Series{}, err
+	}
+	series := Series{Total: total}
+	if filter.Period == "" {
+		return series, nil
+	}
+
+	groups, err := c.clickRepository.Aggregate(ctx, clickFilter, click.GroupBy{Period: click.Period(filter.Period), Location: filter.location()})
+	if err != nil {
+		return Series{}, err
+	}
+	for _, g := range groups {
+		series.Buckets = append(series.Buckets, Bucket{Start: g.Period, Count: g.Count})
+	}
+
+	return series, nil
+}
+
+func (c *EventCounter) views(ctx context.Context, filter CountFilter) (Series, error) {
+	viewFilter := view.Filter{ProjectID: filter.ProjectID, URL: filter.URL, After: filter.After, Before: filter.Before}
+
+	total, err := c.viewRepository.Count(ctx, viewFilter)
+	if err != nil {
+		return Series{}, err
+	}
+	series := Series{Total: total}
+	if filter.Period == "" {
+		return series, nil
+	}
+
+	groups, err := c.viewRepository.Aggregate(ctx, viewFilter, view.GroupBy{Period: view.Period(filter.Period), Location: filter.location()})
+	if err != nil {
+		return Series{}, err
+	}
+	for _, g := range groups {
+		series.Buckets = append(series.Buckets, Bucket{Start: g.Period, Count: g.Count})
+	}
+
+	return series, nil
+}
+
+// NewEventCounter is an EventCounter constructor.
+func NewEventCounter(clickRepository click.Repository, viewRepository view.Repository) *EventCounter {
+	return &EventCounter{
+		clickRepository: clickRepository,
+		viewRepository:  viewRepository,
+	}
+}
@@ -0,0 +1,128 @@
+package analytics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+func TestEventCounter(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	counter := NewEventCounter(click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB))
+
+	tests := map[string]struct {
+		filter   CountFilter
+		expected Series
+	}{
+		"total": {
+			filter:   CountFilter{ProjectID: 1, Type: TypeClick},
+			expected: Series{Total: 2},
+		},
+		"unbounded buckets": {
+			filter:   CountFilter{ProjectID: 1, Type: TypeView, Period: PeriodDay},
+			expected: Series{Total: 8, Buckets: []Bucket{{Start: start.Truncate(24 * time.Hour), Count: 8}}},
+		},
+		"bounded buckets include empty ones": {
+			filter: CountFilter{ProjectID: 1, Type: TypeView, After: start, Before: start.Add(3 * time.Hour), Period: PeriodHour},
+			expected: Series{Total: 5, Buckets: []Bucket{
+				{Start: start, Count: 3},
+				{Start: start.Add(time.Hour), Count: 0},
+				{Start: start.Add(2 * time.Hour), Count: 2},
+			}},
+		},
+		"single URL": {
+			filter: CountFilter{ProjectID: 1, Type: TypeView, URL: "/c", After: start, Before: start.Add(24 * time.Hour), Period: PeriodDay},
+			expected: Series{Total: 2, Buckets: []Bucket{
+				{Start: start.Truncate(24 * time.Hour), Count: 2},
+				{Start: start.Truncate(24 * time.Hour).Add(24 * time.Hour), Count: 0},
+			}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			series, err := counter.Series(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected.Total, series.Total)
+			if !assert.Equal(t, len(test.expected.Buckets), len(series.Buckets)) {
+				return
+			}
+			for i := range series.Buckets {
+				assert.True(t, test.expected.Buckets[i].Start.Equal(series.Buckets[i].Start))
+				assert.Equal(t, test.expected.Buckets[i].Count, series.Buckets[i].Count)
+			}
+		})
+	}
+
+	_, err := counter.Series(context.Background(), CountFilter{ProjectID: 1, Type: "scroll"})
+	assert.Error(t, err)
+}
+
+func TestCountFilterBuckets(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	after, _ := time.Parse(time.RFC3339, "2024-01-30T10:30:00Z")
+
+	tests := map[string]struct {
+		filter   CountFilter
+		expected []string
+	}{
+		"hours": {
+			filter:   CountFilter{After: after, Before: after.Add(2 * time.Hour), Period: PeriodHour},
+			expected: []string{"2024-01-30T10:00:00Z", "2024-01-30T11:00:00Z", "2024-01-30T12:00:00Z"},
+		},
+		"days in time zone": {
+			filter:   CountFilter{After: after, Before: after.Add(24 * time.Hour), Period: PeriodDay, Location: berlin},
+			expected: []string{"2024-01-30T00:00:00+01:00", "2024-01-31T00:00:00+01:00"},
+		},
+		"months": {
+			filter:   CountFilter{After: after, Before: after.AddDate(0, 2, 0), Period: PeriodMonth},
+			expected: []string{"2024-01-01T00:00:00Z", "2024-02-01T00:00:00Z", "2024-03-01T00:00:00Z"},
+		},
+		"unbounded": {
+			filter:   CountFilter{After: after, Period: PeriodDay},
+			expected: nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var starts []string
+			for _, start := range test.filter.Buckets() {
+				starts = append(starts, start.Format(time.RFC3339))
+			}
+			assert.Equal(t, test.expected, starts)
+		})
+	}
+}
+
+func TestCountFilterCompared(t *testing.T) {
+	after, _ := time.Parse(time.RFC3339, "2024-03-01T00:00:00Z")
+	filter := CountFilter{ProjectID: 1, Type: TypeView, After: after, Before: after.Add(7 * 24 * time.Hour), Period: PeriodDay}
+
+	compared, err := filter.Compared(ComparePreviousPeriod)
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02-23T00:00:00Z", compared.After.Format(time.RFC3339))
+	assert.Equal(t, "2024-03-01T00:00:00Z", compared.Before.Format(time.RFC3339))
+
+	compared, err = filter.Compared(ComparePreviousYear)
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-03-01T00:00:00Z", compared.After.Format(time.RFC3339))
+	assert.Equal(t, "2023-03-08T00:00:00Z", compared.Before.Format(time.RFC3339))
+
+	_, err = filter.Compared("previous_century")
+	assert.Error(t, err)
+
+	_, err = CountFilter{After: after}.Compared(ComparePreviousPeriod)
+	assert.Error(t, err)
+}
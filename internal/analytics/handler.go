@@ -0,0 +1,678 @@
+package analytics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+)
+
+// FunnelRequestDTO represents HTTP request model.
+// Every Step is given as "type:url", such as "view:https://example.com/pricing", and Window as a duration,
+// such as "72h". Before and After accept anything timeparam.Parse does.
+type FunnelRequestDTO struct {
+	Steps  []string       `query:"step"`
+	Window string         `query:"window"`
+	Before timeparam.Time `query:"before"`
+	After  timeparam.Time `query:"after"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (f FunnelRequestDTO) ToDomain() (Funnel, error) {
+	if len(f.Steps) < 2 || len(f.Steps) > MaxSteps {
+		return Funnel{}, fmt.Errorf("funnel must have 2 to %d steps", MaxSteps)
+	}
+
+	funnel := Funnel{
+		Steps:  make([]Step, 0, len(f.Steps)),
+		Window: DefaultWindow,
+		After:  f.After.Time,
+		Before: f.Before.Time,
+	}
+	for _, s := range f.Steps {
+		eventType, url, _ := strings.Cut(s, ":")
+		if eventType != TypeClick && eventType != TypeView || url == "" {
+			return Funnel{}, fmt.Errorf("step %q must be click:<url> or view:<url>", s)
+		}
+		funnel.Steps = append(funnel.Steps, Step{Type: eventType, URL: url})
+	}
+
+	if f.Window != "" {
+		window, err := time.ParseDuration(f.Window)
+		if err != nil || window <= 0 {
+			return Funnel{}, errors.New("window must be a positive duration, such as 72h")
+		}
+		funnel.Window = window
+	}
+
+	return funnel, nil
+}
+
+// FunnelDTO represents HTTP response model.
+type FunnelDTO struct {
+	Window string    `json:"window"`
+	Steps  []StepDTO `json:"steps"`
+}
+
+// StepDTO represents HTTP response model.
+// Conversion is the share of visitors of the first step who reached this one,
+// StepConversion the share of visitors of the previous step, who otherwise dropped off.
+type StepDTO struct {
+	Type           string  `json:"type"`
+	URL            string  `json:"url"`
+	Visitors       int64   `json:"visitors"`
+	Conversion     float64 `json:"conversion"`
+	StepConversion float64 `json:"stepConversion"`
+	DropOff        int64   `json:"dropOff"`
+}
+
+// NewFunnelDTO is a FunnelDTO constructor.
+func NewFunnelDTO(funnel Funnel, results []StepResult) FunnelDTO {
+	dto := FunnelDTO{Window: funnel.Window.String(), Steps: make([]StepDTO, 0, len(results))}
+
+	for i, result := range results {
+		step := StepDTO{Type: result.Step.Type, URL: result.Step.URL, Visitors: result.Visitors}
+		if i == 0 {
+			if result.Visitors > 0 {
+				step.Conversion, step.StepConversion = 1, 1
+			}
+		} else {
+			first, previous := results[0].Visitors, results[i-1].Visitors
+			step.Conversion = ratio(result.Visitors, first)
+			step.StepConversion = ratio(result.Visitors, previous)
+			step.DropOff = previous - result.Visitors
+		}
+		dto.Steps = append(dto.Steps, step)
+	}
+
+	return dto
+}
+
+// Limits of the number of results.
+const (
+	DefaultSessionLimit = 100
+	DefaultPathLimit    = 10
+	DefaultTopLimit     = 10
+	MaxLimit            = 1000
+)
+
+// SessionFilterDTO represents HTTP request model.
+// Timeout is a duration, such as "30m". Before and After accept anything timeparam.Parse does.
+type SessionFilterDTO struct {
+	VisitorID string         `query:"visitorId"`
+	Before    timeparam.Time `query:"before"`
+	After     timeparam.Time `query:"after"`
+	Timeout   string         `query:"timeout"`
+	Limit     int            `query:"limit"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (f SessionFilterDTO) ToDomain() (SessionFilter, error) {
+	timeout, err := parseTimeout(f.Timeout)
+	if err != nil {
+		return SessionFilter{}, err
+	}
+	limit, err := parseLimit(f.Limit, DefaultSessionLimit)
+	if err != nil {
+		return SessionFilter{}, err
+	}
+
+	return SessionFilter{
+		VisitorID: f.VisitorID,
+		After:     f.After.Time,
+		Before:    f.Before.Time,
+		Timeout:   timeout,
+		Limit:     limit,
+	}, nil
+}
+
+// SessionDTO represents HTTP response model. Duration is in seconds.
+type SessionDTO struct {
+	VisitorID string  `json:"visitorId"`
+	Start     string  `json:"start"`
+	End       string  `json:"end"`
+	Duration  float64 `json:"duration"`
+	PageViews int64   `json:"pageViews"`
+	Clicks    int64   `json:"clicks"`
+	EntryURL  string  `json:"entryUrl"`
+	ExitURL   string  `json:"exitUrl"`
+}
+
+// NewSessionDTO is a SessionDTO constructor.
+func NewSessionDTO(s Session) SessionDTO {
+	return SessionDTO{
+		VisitorID: s.VisitorID,
+		Start:     s.Start.Format(time.RFC3339Nano),
+		End:       s.End.Format(time.RFC3339Nano),
+		Duration:  s.Duration().Seconds(),
+		PageViews: s.PageViews,
+		Clicks:    s.Clicks,
+		EntryURL:  s.EntryURL,
+		ExitURL:   s.ExitURL,
+	}
+}
+
+// PathFilterDTO represents HTTP request model.
+// Timeout is a duration, such as "30m". Before and After accept anything timeparam.Parse does.
+type PathFilterDTO struct {
+	URL     string         `query:"url"`
+	Before  timeparam.Time `query:"before"`
+	After   timeparam.Time `query:"after"`
+	Timeout string         `query:"timeout"`
+	Limit   int            `query:"limit"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (f PathFilterDTO) ToDomain() (PathFilter, int, error) {
+	if f.URL == "" {
+		return PathFilter{}, 0, errors.New("url is required")
+	}
+	timeout, err := parseTimeout(f.Timeout)
+	if err != nil {
+		return PathFilter{}, 0, err
+	}
+	limit, err := parseLimit(f.Limit, DefaultPathLimit)
+	if err != nil {
+		return PathFilter{}, 0, err
+	}
+
+	return PathFilter{
+		URL:     f.URL,
+		After:   f.After.Time,
+		Before:  f.Before.Time,
+		Timeout: timeout,
+	}, limit, nil
+}
+
+// PathsDTO represents HTTP response model.
+// Views is the number of times URL was viewed, and Exits the number of times a session ended there.
+// Next holds the most common URLs viewed right after it.
+type PathsDTO struct {
+	URL   string          `json:"url"`
+	Views int64           `json:"views"`
+	Exits int64           `json:"exits"`
+	Next  []TransitionDTO `json:"next"`
+}
+
+// TransitionDTO represents HTTP response model.
+// Share is the share of Views of the URL followed by this one.
+type TransitionDTO struct {
+	URL   string  `json:"url"`
+	Count int64   `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// NewPathsDTO is a PathsDTO constructor. It keeps at most limit Transitions.
+func NewPathsDTO(url string, transitions []Transition, limit int) PathsDTO {
+	dto := PathsDTO{URL: url, Next: make([]TransitionDTO, 0, limit)}
+
+	for _, t := range transitions {
+		dto.Views += t.Count
+		if t.URL == "" {
+			dto.Exits = t.Count
+		}
+	}
+	for _, t := range transitions {
+		if t.URL != "" && len(dto.Next) < limit {
+			dto.Next = append(dto.Next, TransitionDTO{URL: t.URL, Count: t.Count, Share: ratio(t.Count, dto.Views)})
+		}
+	}
+
+	return dto
+}
+
+// Modes of ranking URLs.
+const (
+	ModeTop      = "top"
+	ModeTrending = "trending"
+)
+
+// DefaultTrendingPeriod is the period compared with the previous one in trending mode when not bounded.
+const DefaultTrendingPeriod = 24 * time.Hour
+
+// TopFilterDTO represents HTTP request model.
+// In trending mode Before defaults to now and After to DefaultTrendingPeriod before it.
+// Approximate asks for counts of in memory sketches instead of the stored events.
+type TopFilterDTO struct {
+	Type        string         `query:"type"`
+	Mode        string         `query:"mode"`
+	Before      timeparam.Time `query:"before"`
+	After       timeparam.Time `query:"after"`
+	Limit       int            `query:"limit"`
+	Approximate bool           `query:"approximate"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (f TopFilterDTO) ToDomain(now time.Time) (TopFilter, error) {
+	if f.Type != TypeClick && f.Type != TypeView {
+		return TopFilter{}, errors.New("type must be click or view")
+	}
+	if f.Mode != "" && f.Mode != ModeTop && f.Mode != ModeTrending {
+		return TopFilter{}, errors.New("mode must be top or trending")
+	}
+	limit, err := parseLimit(f.Limit, DefaultTopLimit)
+	if err != nil {
+		return TopFilter{}, err
+	}
+
+	filter := TopFilter{
+		Type:   f.Type,
+		After:  f.After.Time,
+		Before: f.Before.Time,
+		Limit:  limit,
+	}
+	if f.Mode == ModeTrending {
+		if filter.Before.IsZero() {
+			filter.Before = now
+		}
+		if filter.After.IsZero() {
+			filter.After = filter.Before.Add(-DefaultTrendingPeriod)
+		}
+	}
+	if !filter.After.IsZero() && !filter.Before.IsZero() && !filter.After.Before(filter.Before) {
+		return TopFilter{}, errors.New("after must be earlier than before")
+	}
+
+	return filter, nil
+}
+
+// TopDTO represents HTTP response model. Bounds are omitted when not set.
+type TopDTO struct {
+	Type        string      `json:"type"`
+	Mode        string      `json:"mode"`
+	After       string      `json:"after,omitempty"`
+	Before      string      `json:"before,omitempty"`
+	Approximate bool        `json:"approximate"`
+	URLs        []TopURLDTO `json:"urls"`
+}
+
+// TopURLDTO represents HTTP response model.
+// Previous, Change and Growth are only set in trending mode, Growth only when there were previous events.
+type TopURLDTO struct {
+	URL      string   `json:"url"`
+	Count    int64    `json:"count"`
+	Previous *int64   `json:"previous,omitempty"`
+	Change   *int64   `json:"change,omitempty"`
+	Growth   *float64 `json:"growth,omitempty"`
+}
+
+// NewTopDTO is a TopDTO constructor.
+func NewTopDTO(filter TopFilter, approximate bool, counts []URLCount) TopDTO {
+	dto := newTopDTO(filter, ModeTop, approximate)
+	for _, c := range counts {
+		dto.URLs = append(dto.URLs, TopURLDTO{URL: c.URL, Count: c.Count})
+	}
+
+	return dto
+}
+
+// NewTrendingDTO is a TopDTO constructor for trending mode.
+func NewTrendingDTO(filter TopFilter, approximate bool, trends []Trend) TopDTO {
+	dto := newTopDTO(filter, ModeTrending, approximate)
+	for _, t := range trends {
+		previous, change := t.Previous, t.Change()
+		url := TopURLDTO{URL: t.URL, Count: t.Count, Previous: &previous, Change: &change}
+		if previous > 0 {
+			growth := ratio(change, previous)
+			url.Growth = &growth
+		}
+		dto.URLs = append(dto.URLs, url)
+	}
+
+	return dto
+}
+
+func newTopDTO(filter TopFilter, mode string, approximate bool) TopDTO {
+	return TopDTO{
+		Type:        filter.Type,
+		Mode:        mode,
+		After:       formatTime(filter.After),
+		Before:      formatTime(filter.Before),
+		Approximate: approximate,
+		URLs:        []TopURLDTO{},
+	}
+}
+
+// CountFilterDTO represents HTTP request model.
+// Period splits counts into time buckets starting in the time zone of Tz, UTC by default.
+// Compare, when set, requires After, and Before defaults to now.
+type CountFilterDTO struct {
+	Type    string         `query:"type"`
+	URL     string         `query:"url"`
+	Before  timeparam.Time `query:"before"`
+	After   timeparam.Time `query:"after"`
+	Period  string         `query:"period"`
+	Tz      string         `query:"tz"`
+	Compare string         `query:"compare"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (f CountFilterDTO) ToDomain(now time.Time) (CountFilter, error) {
+	if f.Type != TypeClick && f.Type != TypeView {
+		return CountFilter{}, errors.New("type must be click or view")
+	}
+	if f.Period != "" && f.Period != PeriodHour && f.Period != PeriodDay && f.Period != PeriodMonth {
+		return CountFilter{}, errors.New("period must be one of hour, day or month")
+	}
+	if f.Compare != "" && f.Compare != ComparePreviousPeriod && f.Compare != ComparePreviousYear {
+		return CountFilter{}, errors.New("compare must be previous_period or previous_year")
+	}
+	loc, err := timeparam.Location(f.Tz)
+	if err != nil {
+		return CountFilter{}, err
+	}
+
+	filter := CountFilter{
+		Type:     f.Type,
+		URL:      f.URL,
+		After:    f.After.Time,
+		Before:   f.Before.Time,
+		Period:   f.Period,
+		Location: loc,
+	}
+	if f.Compare != "" {
+		if filter.After.IsZero() {
+			return CountFilter{}, errors.New("compare requires after")
+		}
+		if filter.Before.IsZero() {
+			filter.Before = now
+		}
+	}
+	if !filter.After.IsZero() && !filter.Before.IsZero() && !filter.After.Before(filter.Before) {
+		return CountFilter{}, errors.New("after must be earlier than before")
+	}
+	if len(filter.Buckets()) > MaxBuckets {
+		return CountFilter{}, fmt.Errorf("period must split the bounds into at most %d buckets", MaxBuckets)
+	}
+
+	return filter, nil
+}
+
+// CountDTO represents HTTP response model. Bounds are omitted when not set.
+// When compared, Previous holds the earlier period, and Change and ChangePercent the difference
+// of Total from its total, the latter only when the earlier period had events.
+type CountDTO struct {
+	Type          string      `json:"type"`
+	URL           string      `json:"url,omitempty"`
+	After         string      `json:"after,omitempty"`
+	Before        string      `json:"before,omitempty"`
+	Period        string      `json:"period,omitempty"`
+	Compare       string      `json:"compare,omitempty"`
+	Total         int64       `json:"total"`
+	Previous      *PeriodDTO  `json:"previous,omitempty"`
+	Change        *int64      `json:"change,omitempty"`
+	ChangePercent *float64    `json:"changePercent,omitempty"`
+	Buckets       []BucketDTO `json:"buckets"`
+}
+
+// PeriodDTO represents HTTP response model.
+type PeriodDTO struct {
+	After  string `json:"after"`
+	Before string `json:"before"`
+	Total  int64  `json:"total"`
+}
+
+// BucketDTO represents HTTP response model. Period is the start of the bucket.
+// When compared, buckets are aligned with the ones of the earlier period in order,
+// and Previous fields hold the aligned bucket.
+type BucketDTO struct {
+	Period         string   `json:"period"`
+	Count          int64    `json:"count"`
+	PreviousPeriod string   `json:"previousPeriod,omitempty"`
+	PreviousCount  *int64   `json:"previousCount,omitempty"`
+	Change         *int64   `json:"change,omitempty"`
+	ChangePercent  *float64 `json:"changePercent,omitempty"`
+}
+
+// NewCountDTO is a CountDTO constructor.
+func NewCountDTO(filter CountFilter, series Series) CountDTO {
+	dto := CountDTO{
+		Type:    filter.Type,
+		URL:     filter.URL,
+		After:   formatTime(filter.After),
+		Before:  formatTime(filter.Before),
+		Period:  filter.Period,
+		Total:   series.Total,
+		Buckets: make([]BucketDTO, 0, len(series.Buckets)),
+	}
+	for _, b := range series.Buckets {
+		dto.Buckets = append(dto.Buckets, BucketDTO{Period: b.Start.Format(time.RFC3339), Count: b.Count})
+	}
+
+	return dto
+}
+
+// NewComparedCountDTO is a CountDTO constructor for a Series compared with the one of an earlier period.
+func NewComparedCountDTO(filter CountFilter, series Series, compare string, compared CountFilter, previous Series) CountDTO {
+	dto := NewCountDTO(filter, series)
+	dto.Compare = compare
+	dto.Previous = &PeriodDTO{After: formatTime(compared.After), Before: formatTime(compared.Before), Total: previous.Total}
+	dto.Change, dto.ChangePercent = delta(series.Total, previous.Total)
+
+	for i := range dto.Buckets {
+		if i < len(previous.Buckets) {
+			b := previous.Buckets[i]
+			dto.Buckets[i].PreviousPeriod = b.Start.Format(time.RFC3339)
+			dto.Buckets[i].PreviousCount = &b.Count
+			dto.Buckets[i].Change, dto.Buckets[i].ChangePercent = delta(dto.Buckets[i].Count, b.Count)
+		}
+	}
+
+	return dto
+}
+
+// Handler defines all API methods for analytics.
+type Handler struct {
+	repository Repository
+	sketch     Ranker
+	counter    Counter
+}
+
+// Funnel implements handler for Funnel HTTP request.
+// It counts visitors who reached each step of a funnel given by "step" query parameters, in order.
+func (h *Handler) Funnel(c echo.Context) error {
+	var funnelDTO FunnelRequestDTO
+	if err := c.Bind(&funnelDTO); err != nil {
+		return err
+	}
+
+	funnel, err := funnelDTO.ToDomain()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	funnel.ProjectID = project.ID(c)
+
+	results, err := h.repository.Funnel(c.Request().Context(), funnel)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewFunnelDTO(funnel, results))
+}
+
+// Sessions implements handler for Sessions HTTP request.
+// It groups events of visitors into sessions, which end after "timeout" of inactivity, the most recent first.
+func (h *Handler) Sessions(c echo.Context) error {
+	var filterDTO SessionFilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	filter, err := filterDTO.ToDomain()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	sessions, err := h.repository.Sessions(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	response := make([]SessionDTO, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, NewSessionDTO(session))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Paths implements handler for Paths HTTP request.
+// It returns the most common URLs viewed right after the one given in "url" query parameter.
+func (h *Handler) Paths(c echo.Context) error {
+	var filterDTO PathFilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	filter, limit, err := filterDTO.ToDomain()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	transitions, err := h.repository.Paths(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewPathsDTO(filter.URL, transitions, limit))
+}
+
+// Top implements handler for Top HTTP request.
+// It returns the most active URLs of a "type" of events, or in trending mode the ones
+// with the largest increase of events over the previous period of the same length.
+func (h *Handler) Top(c echo.Context) error {
+	var filterDTO TopFilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	var ranker Ranker = h.repository
+	if filterDTO.Approximate {
+		if h.sketch == nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "approximate counts are not available")
+		}
+		ranker = h.sketch
+	}
+
+	if filterDTO.Mode == ModeTrending {
+		trends, err := ranker.Trending(c.Request().Context(), filter)
+		if errors.Is(err, ErrNotKept) {
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		} else if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, NewTrendingDTO(filter, filterDTO.Approximate, trends))
+	}
+
+	counts, err := ranker.Top(c.Request().Context(), filter)
+	if errors.Is(err, ErrNotKept) {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	} else if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewTopDTO(filter, filterDTO.Approximate, counts))
+}
+
+// Count implements handler for Count HTTP request.
+// It counts events of a "type", in total and split into time buckets of "period", and compares
+// them with an earlier period bucket by bucket when "compare" is set.
+func (h *Handler) Count(c echo.Context) error {
+	var filterDTO CountFilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	series, err := h.counter.Series(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+	if filterDTO.Compare == "" {
+		return c.JSON(http.StatusOK, NewCountDTO(filter, series))
+	}
+
+	compared, err := filter.Compared(filterDTO.Compare)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	previous, err := h.counter.Series(c.Request().Context(), compared)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewComparedCountDTO(filter, series, filterDTO.Compare, compared, previous))
+}
+
+// NewHandler is a Handler constructor. Sketch may be nil, in which case approximate counts aren't available.
+func NewHandler(repository Repository, sketch Ranker, counter Counter) Handler {
+	return Handler{
+		repository: repository,
+		sketch:     sketch,
+		counter:    counter,
+	}
+}
+
+func parseTimeout(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultTimeout, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout <= 0 {
+		return 0, errors.New("timeout must be a positive duration, such as 30m")
+	}
+	return timeout, nil
+}
+
+func parseLimit(value, fallback int) (int, error) {
+	if value == 0 {
+		return fallback, nil
+	}
+	if value < 0 || value > MaxLimit {
+		return 0, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+	}
+	return value, nil
+}
+
+// delta returns the difference of a count from the previous one, and its percentage of the previous count
+// unless it's zero.
+func delta(count, previous int64) (*int64, *float64) {
+	change := count - previous
+	if previous == 0 {
+		return &change, nil
+	}
+	percent := ratio(change, previous) * 100
+	return &change, &percent
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func ratio(n, of int64) float64 {
+	if of == 0 {
+		return 0
+	}
+	return float64(n) / float64(of)
+}
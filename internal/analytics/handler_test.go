@@ -0,0 +1,379 @@
+package analytics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/topk"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+func TestHandlerFunnel(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createEvents(t, gormDB, start)
+
+	h := NewHandler(NewSQLiteRepository(gormDB), nil, nil)
+
+	tests := map[string]struct {
+		query          url.Values
+		expectedStatus int
+		expectedJSON   string
+	}{
+		"funnel": {
+			query: url.Values{
+				"step":  {"view:" + pricing.URL, "click:" + signup.URL, "view:" + welcome.URL},
+				"after": {"2024-01-01T00:00:00Z"},
+			},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"window":"24h0m0s","steps":[` +
+				`{"type":"view","url":"https://example.com/pricing","visitors":4,"conversion":1,"stepConversion":1,"dropOff":0},` +
+				`{"type":"click","url":"https://example.com/signup","visitors":2,"conversion":0.5,"stepConversion":0.5,"dropOff":2},` +
+				`{"type":"view","url":"https://example.com/welcome","visitors":1,"conversion":0.25,"stepConversion":0.5,"dropOff":1}]}`,
+		},
+		"nobody converts": {
+			query:          url.Values{"step": {"view:https://example.com/none", "click:" + signup.URL}, "window": {"72h"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"window":"72h0m0s","steps":[` +
+				`{"type":"view","url":"https://example.com/none","visitors":0,"conversion":0,"stepConversion":0,"dropOff":0},` +
+				`{"type":"click","url":"https://example.com/signup","visitors":0,"conversion":0,"stepConversion":0,"dropOff":0}]}`,
+		},
+		"single step": {
+			query:          url.Values{"step": {"view:" + pricing.URL}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown event type": {
+			query:          url.Values{"step": {"view:" + pricing.URL, "scroll:" + signup.URL}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid window": {
+			query:          url.Values{"step": {"view:" + pricing.URL, "click:" + signup.URL}, "window": {"-1h"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid time": {
+			query:          url.Values{"step": {"view:" + pricing.URL, "click:" + signup.URL}, "after": {"yesterday"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/funnel?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			err := h.Funnel(c)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.JSONEq(t, test.expectedJSON, rec.Body.String())
+		})
+	}
+}
+
+func TestHandlerSessions(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	h := NewHandler(NewSQLiteRepository(gormDB), nil, nil)
+
+	tests := map[string]struct {
+		query          url.Values
+		expectedStatus int
+		expectedJSON   string
+	}{
+		"visitor": {
+			query:          url.Values{"visitorId": {"s2"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `[{"visitorId":"s2","start":"2024-01-02T10:00:00Z","end":"2024-01-02T10:01:00Z","duration":60,` +
+				`"pageViews":2,"clicks":0,"entryUrl":"/a","exitUrl":"/b"}]`,
+		},
+		"limited": {
+			query:          url.Values{"timeout": {"3h"}, "limit": {"1"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `[{"visitorId":"s3","start":"2024-01-02T10:03:00Z","end":"2024-01-02T10:03:00Z","duration":0,` +
+				`"pageViews":0,"clicks":1,"entryUrl":"/x","exitUrl":"/x"}]`,
+		},
+		"longer timeout": {
+			query:          url.Values{"visitorId": {"s1"}, "timeout": {"3h"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `[{"visitorId":"s1","start":"2024-01-02T10:00:00Z","end":"2024-01-02T12:15:00Z","duration":8100,` +
+				`"pageViews":5,"clicks":1,"entryUrl":"/a","exitUrl":"/c"}]`,
+		},
+		"invalid timeout": {
+			query:          url.Values{"timeout": {"soon"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid limit": {
+			query:          url.Values{"limit": {"5000"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/sessions?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			err := h.Sessions(c)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.expectedJSON, rec.Body.String())
+		})
+	}
+}
+
+func TestHandlerPaths(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	h := NewHandler(NewSQLiteRepository(gormDB), nil, nil)
+
+	tests := map[string]struct {
+		query          url.Values
+		expectedStatus int
+		expectedJSON   string
+	}{
+		"next pages": {
+			query:          url.Values{"url": {"/b"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON:   `{"url":"/b","views":2,"exits":1,"next":[{"url":"/c","count":1,"share":0.5}]}`,
+		},
+		"limited": {
+			query:          url.Values{"url": {"/a"}, "limit": {"1"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON:   `{"url":"/a","views":3,"exits":0,"next":[{"url":"/b","count":2,"share":0.6666666666666666}]}`,
+		},
+		"missing url": {
+			query:          url.Values{},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/paths?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			err := h.Paths(c)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.expectedJSON, rec.Body.String())
+		})
+	}
+}
+
+func TestHandlerTop(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	now := time.Now()
+	tracker := topk.NewTracker(10, 4)
+	tracker.Add(1, topk.EventView, "/a", now)
+	tracker.Add(1, topk.EventView, "/a", now)
+	tracker.Add(1, topk.EventView, "/b", now)
+
+	h := NewHandler(NewSQLiteRepository(gormDB), NewSketchRanker(tracker), nil)
+
+	tests := map[string]struct {
+		query          url.Values
+		expectedStatus int
+		expectedJSON   string
+	}{
+		"top": {
+			query:          url.Values{"type": {"view"}, "limit": {"2"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"type":"view","mode":"top","approximate":false,` +
+				`"urls":[{"url":"/a","count":4},{"url":"/b","count":2}]}`,
+		},
+		"trending": {
+			query:          url.Values{"type": {"view"}, "mode": {"trending"}, "after": {"2024-01-02T10:02:00Z"}, "before": {"2024-01-02T10:12:00Z"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"type":"view","mode":"trending","after":"2024-01-02T10:02:00Z","before":"2024-01-02T10:12:00Z","approximate":false,` +
+				`"urls":[{"url":"/c","count":1,"previous":0,"change":1}]}`,
+		},
+		"approximate": {
+			query:          url.Values{"type": {"view"}, "approximate": {"true"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"type":"view","mode":"top","approximate":true,` +
+				`"urls":[{"url":"/a","count":2},{"url":"/b","count":1}]}`,
+		},
+		"approximate counts not kept": {
+			query:          url.Values{"type": {"view"}, "approximate": {"true"}, "after": {"2024-01-02T10:00:00Z"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"missing type": {
+			query:          url.Values{},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown mode": {
+			query:          url.Values{"type": {"click"}, "mode": {"hot"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"reversed bounds": {
+			query:          url.Values{"type": {"click"}, "after": {"2024-01-03T00:00:00Z"}, "before": {"2024-01-02T00:00:00Z"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/stats/top?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			err := h.Top(c)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.expectedJSON, rec.Body.String())
+		})
+	}
+
+	h = NewHandler(NewSQLiteRepository(gormDB), nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/stats/top?type=view&approximate=true", nil)
+	c := echo.New().NewContext(req, httptest.NewRecorder())
+	err := h.Top(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+	}
+}
+
+func TestHandlerCount(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	h := NewHandler(NewSQLiteRepository(gormDB), nil, NewEventCounter(click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB)))
+
+	tests := map[string]struct {
+		query          url.Values
+		expectedStatus int
+		expectedJSON   string
+	}{
+		"total": {
+			query:          url.Values{"type": {"click"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON:   `{"type":"click","total":2,"buckets":[]}`,
+		},
+		"buckets": {
+			query:          url.Values{"type": {"view"}, "url": {"/c"}, "period": {"day"}, "tz": {"America/New_York"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON:   `{"type":"view","url":"/c","period":"day","total":2,"buckets":[{"period":"2024-01-02T00:00:00-05:00","count":2}]}`,
+		},
+		"previous period": {
+			query:          url.Values{"type": {"view"}, "period": {"hour"}, "after": {"2024-01-02T11:00:00Z"}, "before": {"2024-01-02T13:00:00Z"}, "compare": {"previous_period"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"type":"view","after":"2024-01-02T11:00:00Z","before":"2024-01-02T13:00:00Z","period":"hour","compare":"previous_period",` +
+				`"total":2,"previous":{"after":"2024-01-02T09:00:00Z","before":"2024-01-02T11:00:00Z","total":6},"change":-4,"changePercent":-66.66666666666666,` +
+				`"buckets":[{"period":"2024-01-02T11:00:00Z","count":0,"previousPeriod":"2024-01-02T09:00:00Z","previousCount":0,"change":0},` +
+				`{"period":"2024-01-02T12:00:00Z","count":2,"previousPeriod":"2024-01-02T10:00:00Z","previousCount":6,"change":-4,"changePercent":-66.66666666666666}]}`,
+		},
+		"previous year": {
+			query:          url.Values{"type": {"view"}, "after": {"2025-01-02T10:00:00Z"}, "before": {"2025-01-02T13:00:00Z"}, "compare": {"previous_year"}},
+			expectedStatus: http.StatusOK,
+			expectedJSON: `{"type":"view","after":"2025-01-02T10:00:00Z","before":"2025-01-02T13:00:00Z","compare":"previous_year",` +
+				`"total":0,"previous":{"after":"2024-01-02T10:00:00Z","before":"2024-01-02T13:00:00Z","total":5},"change":-5,"changePercent":-100,"buckets":[]}`,
+		},
+		"compare without after": {
+			query:          url.Values{"type": {"view"}, "compare": {"previous_period"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown comparison": {
+			query:          url.Values{"type": {"view"}, "after": {"2024-01-01T00:00:00Z"}, "compare": {"last_week"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"too many buckets": {
+			query:          url.Values{"type": {"view"}, "period": {"hour"}, "after": {"2020-01-01T00:00:00Z"}, "before": {"2024-01-01T00:00:00Z"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unknown time zone": {
+			query:          url.Values{"type": {"view"}, "period": {"day"}, "tz": {"Mars/Olympus"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/stats/count?"+test.query.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			err := h.Count(c)
+			if test.expectedStatus != http.StatusOK {
+				if assert.Error(t, err) {
+					assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				}
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.expectedJSON, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,258 @@
+// analytics package analyses behaviour of visitors across both clicks and views,
+// such as their conversion through funnels, their sessions and paths through a site.
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Event types a Step can match.
+const (
+	TypeClick = "click"
+	TypeView  = "view"
+)
+
+// MaxSteps is the largest number of Steps in a Funnel.
+const MaxSteps = 10
+
+// DefaultWindow is the time visitors have to complete a Funnel when not set.
+const DefaultWindow = 24 * time.Hour
+
+// DefaultTimeout is the inactivity after which a Session ends when not set.
+const DefaultTimeout = 30 * time.Minute
+
+// Step is a single step of a Funnel, taken by a Click or a View of a URL.
+type Step struct {
+	Type string
+	URL  string
+}
+
+// Funnel holds ordered Steps visitors are expected to take.
+// A visitor reaches a Step after taking all of the previous ones in order, within Window
+// since their first occurrence of the first Step. Only events with a visitor ID,
+// created between After and Before, are taken into account. Zero bounds are ignored.
+type Funnel struct {
+	ProjectID uint
+	Steps     []Step
+	Window    time.Duration
+	After     time.Time
+	Before    time.Time
+}
+
+// StepResult holds the number of visitors who reached a single Step of a Funnel.
+type StepResult struct {
+	Step     Step
+	Visitors int64
+}
+
+// SessionFilter holds parameters for reconstructing Sessions.
+// Only events with a visitor ID, created between After and Before, are taken into account,
+// so Sessions crossing the bounds are cut short. Zero values are ignored, except for Timeout.
+type SessionFilter struct {
+	ProjectID uint
+	VisitorID string
+	After     time.Time
+	Before    time.Time
+	Timeout   time.Duration
+	// Limit is the maximum number of Sessions, the most recent ones are returned.
+	Limit int
+}
+
+// Session is a sequence of events of a single visitor, none of them more than a timeout apart.
+// EntryURL and ExitURL are the first and the last viewed URLs, or clicked ones in Sessions without Views.
+type Session struct {
+	VisitorID string
+	Start     time.Time
+	End       time.Time
+	PageViews int64
+	Clicks    int64
+	EntryURL  string
+	ExitURL   string
+}
+
+// Duration returns the time between the first and the last event of the Session.
+func (s Session) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// PathFilter holds parameters for finding Transitions from a URL.
+// Views of the same visitor more than Timeout apart belong to different sessions,
+// so they aren't a Transition. Bounds are treated as in SessionFilter.
+type PathFilter struct {
+	ProjectID uint
+	URL       string
+	After     time.Time
+	Before    time.Time
+	Timeout   time.Duration
+}
+
+// Transition holds the number of times visitors viewed URL right after the URL of a PathFilter.
+// Empty URL holds the number of times their session ended there instead.
+type Transition struct {
+	URL   string
+	Count int64
+}
+
+// TopFilter holds parameters for finding the most active URLs of a single event Type.
+// Zero bounds are ignored, except in Trending, where they are both required.
+type TopFilter struct {
+	ProjectID uint
+	Type      string
+	After     time.Time
+	Before    time.Time
+	Limit     int
+}
+
+// Previous returns the period of the same length right before the one of the TopFilter.
+func (f TopFilter) Previous() (after, before time.Time) {
+	return f.After.Add(-f.Before.Sub(f.After)), f.After
+}
+
+// URLCount holds the number of events of a URL.
+type URLCount struct {
+	URL   string
+	Count int64
+}
+
+// Trend holds the number of events of a URL in a period and in the previous one of the same length.
+type Trend struct {
+	URL      string
+	Count    int64
+	Previous int64
+}
+
+// Change returns the difference between the number of events in the period and the previous one.
+func (t Trend) Change() int64 {
+	return t.Count - t.Previous
+}
+
+// Periods of time buckets events are counted in.
+const (
+	PeriodHour  = "hour"
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// Comparisons of a period with an earlier one.
+const (
+	ComparePreviousPeriod = "previous_period"
+	ComparePreviousYear   = "previous_year"
+)
+
+// MaxBuckets is the largest number of time buckets a bounded CountFilter may be split into.
+const MaxBuckets = 10_000
+
+// CountFilter holds parameters for counting events of a single Type, optionally of a single URL.
+// Events are split into time buckets of Period starting in Location, nil Location is UTC.
+// Zero bounds are ignored.
+type CountFilter struct {
+	ProjectID uint
+	Type      string
+	URL       string
+	After     time.Time
+	Before    time.Time
+	Period    string
+	Location  *time.Location
+}
+
+// Compared returns the CountFilter of the earlier period a given comparison is made with.
+// The previous period is of the same length, right before this one, and the previous year
+// is this period a year earlier. Both bounds have to be set.
+func (f CountFilter) Compared(compare string) (CountFilter, error) {
+	if f.After.IsZero() || f.Before.IsZero() {
+		return CountFilter{}, errors.New("comparison requires both after and before")
+	}
+
+	compared := f
+	switch compare {
+	case ComparePreviousPeriod:
+		compared.After, compared.Before = f.After.Add(-f.Before.Sub(f.After)), f.After
+	case ComparePreviousYear:
+		compared.After, compared.Before = f.After.In(f.location()).AddDate(-1, 0, 0), f.Before.In(f.location()).AddDate(-1, 0, 0)
+	default:
+		return CountFilter{}, fmt.Errorf("unsupported comparison %q", compare)
+	}
+
+	return compared, nil
+}
+
+// Buckets returns starts of all time buckets between the bounds, including the one After falls into.
+// It returns nil when the CountFilter has no Period or isn't bounded.
+func (f CountFilter) Buckets() []time.Time {
+	if f.Period == "" || f.After.IsZero() || f.Before.IsZero() {
+		return nil
+	}
+
+	var starts []time.Time
+	for start := f.truncate(f.After); start.Before(f.Before) && len(starts) <= MaxBuckets; start = f.next(start) {
+		starts = append(starts, start)
+	}
+
+	return starts
+}
+
+func (f CountFilter) location() *time.Location {
+	if f.Location == nil {
+		return time.UTC
+	}
+	return f.Location
+}
+
+func (f CountFilter) truncate(t time.Time) time.Time {
+	t = t.In(f.location())
+	switch f.Period {
+	case PeriodHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case PeriodMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (f CountFilter) next(start time.Time) time.Time {
+	switch f.Period {
+	case PeriodHour:
+		return start.Add(time.Hour)
+	case PeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Bucket holds the number of events in a single time bucket.
+type Bucket struct {
+	Start time.Time
+	Count int64
+}
+
+// Series holds the number of events matching a CountFilter, in total and per time bucket.
+type Series struct {
+	Total   int64
+	Buckets []Bucket
+}
+
+// Counter defines an API for counting events.
+type Counter interface {
+	Series(context.Context, CountFilter) (Series, error)
+}
+
+// Repository defines a storage API for analysing events.
+type Repository interface {
+	Funnel(context.Context, Funnel) ([]StepResult, error)
+	Sessions(context.Context, SessionFilter) ([]Session, error)
+	Paths(context.Context, PathFilter) ([]Transition, error)
+	Top(context.Context, TopFilter) ([]URLCount, error)
+	Trending(context.Context, TopFilter) ([]Trend, error)
+}
+
+// Ranker defines an API for finding the most active URLs, implemented by both
+// the Repository and approximate in memory counts.
+type Ranker interface {
+	Top(context.Context, TopFilter) ([]URLCount, error)
+	Trending(context.Context, TopFilter) ([]Trend, error)
+}
@@ -0,0 +1,285 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracing"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"gorm.io/gorm"
+)
+
+var tracer = otel.Tracer("google.com/ivan-sabo/clicks-and-views/internal/analytics")
+
+// tables maps event types to tables they are stored in.
+var tables = map[string]string{
+	TypeClick: click.ClickDAO{}.TableName(),
+	TypeView:  view.ViewDAO{}.TableName(),
+}
+
+// SQLiteRepository is a SQLite implementation of analytics Repository.
+// It queries tables of click and view packages directly, so every query has to skip deleted events.
+type SQLiteRepository struct {
+	db *gorm.DB
+}
+
+// Funnel counts visitors who reached each of the Funnel Steps.
+// Every Step is a common table expression holding visitors who reached it, along with the time
+// they started the Funnel and the time of the earliest event matching the Step. Every event matching
+// the first Step is a start of its own, so a visitor who comes back after the window has passed
+// can still complete the Funnel, and is counted once however many times it was started.
+// Taking the earliest event of the other Steps is enough, since a later one can't make the rest
+// of the Funnel any shorter.
+func (r *SQLiteRepository) Funnel(ctx context.Context, funnel Funnel) ([]StepResult, error) {
+	ctx, span := tracer.Start(ctx, "analytics.SQLiteRepository.Funnel")
+	defer span.End()
+
+	var ctes, counts []string
+	var args []any
+	for i, step := range funnel.Steps {
+		table, ok := tables[step.Type]
+		if !ok {
+			return nil, fmt.Errorf("unsupported event type %q", step.Type)
+		}
+
+		var cte strings.Builder
+		if i == 0 {
+			fmt.Fprintf(&cte, "step0 AS (SELECT DISTINCT e.visitor_id, e.created_at AS started, e.created_at AS at FROM %s e"+
+				" WHERE e.project_id = ? AND e.url = ? AND e.visitor_id <> '' AND e.deleted_at IS NULL", table)
+			args = append(args, funnel.ProjectID, step.URL)
+			if !funnel.After.IsZero() {
+				cte.WriteString(" AND e.created_at > ?")
+				args = append(args, funnel.After.UTC())
+			}
+		} else {
+			fmt.Fprintf(&cte, "step%d AS (SELECT s.visitor_id, s.started, MIN(e.created_at) AS at FROM step%d s"+
+				" JOIN %s e ON e.visitor_id = s.visitor_id"+
+				" WHERE e.project_id = ? AND e.url = ? AND e.deleted_at IS NULL AND e.created_at > s.at"+
+				" AND julianday(e.created_at) <= julianday(s.started) + ?", i, i-1, table)
+			args = append(args, funnel.ProjectID, step.URL, funnel.Window.Hours()/24)
+		}
+		if !funnel.Before.IsZero() {
+			cte.WriteString(" AND e.created_at < ?")
+			args = append(args, funnel.Before.UTC())
+		}
+		if i == 0 {
+			cte.WriteString(")")
+		} else {
+			cte.WriteString(" GROUP BY s.visitor_id, s.started)")
+		}
+
+		ctes = append(ctes, cte.String())
+		counts = append(counts, fmt.Sprintf("SELECT %d AS step, COUNT(DISTINCT visitor_id) AS visitors FROM step%d", i, i))
+	}
+
+	var rows []struct {
+		Step     int
+		Visitors int64
+	}
+	query := "WITH " + strings.Join(ctes, ", ") + " " + strings.Join(counts, " UNION ALL ")
+	result := r.db.WithContext(ctx).Raw(query, args...).Scan(&rows)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	results := make([]StepResult, len(funnel.Steps))
+	for i, step := range funnel.Steps {
+		results[i].Step = step
+	}
+	for _, row := range rows {
+		results[row.Step].Visitors = row.Visitors
+	}
+
+	return results, nil
+}
+
+// Sessions reconstructs Sessions of visitors from their Clicks and Views, the most recent first.
+// Events are numbered into Sessions by a running sum of gaps longer than the timeout.
+func (r *SQLiteRepository) Sessions(ctx context.Context, filter SessionFilter) ([]Session, error) {
+	ctx, span := tracer.Start(ctx, "analytics.SQLiteRepository.Sessions")
+	defer span.End()
+
+	views, viewArgs := events(TypeView, filter.ProjectID, filter.VisitorID, filter.After, filter.Before)
+	clicks, clickArgs := events(TypeClick, filter.ProjectID, filter.VisitorID, filter.After, filter.Before)
+
+	query := "WITH events AS (" + views + " UNION ALL " + clicks + "), " +
+		"gaps AS (SELECT *, julianday(created_at) - julianday(LAG(created_at) OVER (PARTITION BY visitor_id ORDER BY created_at)) AS gap FROM events), " +
+		"numbered AS (SELECT *, SUM(CASE WHEN gap IS NULL OR gap > ? THEN 1 ELSE 0 END)" +
+		" OVER (PARTITION BY visitor_id ORDER BY created_at ROWS UNBOUNDED PRECEDING) AS session FROM gaps), " +
+		"sessions AS (SELECT *," +
+		" FIRST_VALUE(url) OVER (PARTITION BY visitor_id, session ORDER BY type = 'view' DESC, created_at) AS entry_url," +
+		" FIRST_VALUE(url) OVER (PARTITION BY visitor_id, session ORDER BY type = 'view' DESC, created_at DESC) AS exit_url FROM numbered) " +
+		"SELECT visitor_id, strftime('%Y-%m-%dT%H:%M:%fZ', MIN(created_at)) AS started, strftime('%Y-%m-%dT%H:%M:%fZ', MAX(created_at)) AS ended," +
+		" SUM(type = 'view') AS page_views, SUM(type = 'click') AS clicks, MIN(entry_url) AS entry_url, MIN(exit_url) AS exit_url " +
+		"FROM sessions GROUP BY visitor_id, session ORDER BY started DESC, visitor_id"
+	args := append(append(viewArgs, clickArgs...), filter.Timeout.Hours()/24)
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	var rows []struct {
+		VisitorID string
+		Started   string
+		Ended     string
+		PageViews int64
+		Clicks    int64
+		EntryURL  string
+		ExitURL   string
+	}
+	result := r.db.WithContext(ctx).Raw(query, args...).Scan(&rows)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	sessions := make([]Session, 0, len(rows))
+	for _, row := range rows {
+		start, err := time.Parse(time.RFC3339Nano, row.Started)
+		if err != nil {
+			return nil, err
+		}
+		end, err := time.Parse(time.RFC3339Nano, row.Ended)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, Session{
+			VisitorID: row.VisitorID,
+			Start:     start,
+			End:       end,
+			PageViews: row.PageViews,
+			Clicks:    row.Clicks,
+			EntryURL:  row.EntryURL,
+			ExitURL:   row.ExitURL,
+		})
+	}
+
+	return sessions, nil
+}
+
+// Paths counts Transitions from the URL of a PathFilter, the most common first.
+// Each View of the URL is paired with the next View of the same visitor.
+func (r *SQLiteRepository) Paths(ctx context.Context, filter PathFilter) ([]Transition, error) {
+	ctx, span := tracer.Start(ctx, "analytics.SQLiteRepository.Paths")
+	defer span.End()
+
+	views, args := events(TypeView, filter.ProjectID, "", filter.After, filter.Before)
+
+	query := "WITH pages AS (" + views + "), " +
+		"nexts AS (SELECT url, LEAD(url) OVER w AS next_url, julianday(LEAD(created_at) OVER w) - julianday(created_at) AS gap" +
+		" FROM pages WINDOW w AS (PARTITION BY visitor_id ORDER BY created_at)) " +
+		"SELECT CASE WHEN next_url IS NULL OR gap > ? THEN '' ELSE next_url END AS next_url, COUNT(*) AS count " +
+		"FROM nexts WHERE url = ? GROUP BY 1 ORDER BY count DESC, next_url"
+	args = append(args, filter.Timeout.Hours()/24, filter.URL)
+
+	var rows []struct {
+		NextURL string
+		Count   int64
+	}
+	result := r.db.WithContext(ctx).Raw(query, args...).Scan(&rows)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transitions := make([]Transition, 0, len(rows))
+	for _, row := range rows {
+		transitions = append(transitions, Transition{URL: row.NextURL, Count: row.Count})
+	}
+
+	return transitions, nil
+}
+
+// Top counts events of a TopFilter per URL, returning the most active URLs first.
+func (r *SQLiteRepository) Top(ctx context.Context, filter TopFilter) ([]URLCount, error) {
+	ctx, span := tracer.Start(ctx, "analytics.SQLiteRepository.Top")
+	defer span.End()
+
+	table, ok := tables[filter.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported event type %q", filter.Type)
+	}
+
+	tx := r.db.WithContext(ctx).Table(table).Select("url, COUNT(*) AS count").Where("project_id = ? AND deleted_at IS NULL", filter.ProjectID)
+	if !filter.After.IsZero() {
+		tx = tx.Where("created_at > ?", filter.After.UTC())
+	}
+	if !filter.Before.IsZero() {
+		tx = tx.Where("created_at < ?", filter.Before.UTC())
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+
+	counts := []URLCount{}
+	result := tx.Group("url").Order("count DESC, url").Scan(&counts)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return counts, nil
+}
+
+// Trending counts events of a TopFilter per URL, along with events of the previous period of the same length.
+// Only URLs with more events than in the previous period are returned, the largest increase first.
+func (r *SQLiteRepository) Trending(ctx context.Context, filter TopFilter) ([]Trend, error) {
+	ctx, span := tracer.Start(ctx, "analytics.SQLiteRepository.Trending")
+	defer span.End()
+
+	table, ok := tables[filter.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported event type %q", filter.Type)
+	}
+	previousAfter, _ := filter.Previous()
+
+	tx := r.db.WithContext(ctx).Table(table).
+		Select("url, SUM(created_at > ?) AS count, SUM(created_at <= ?) AS previous", filter.After.UTC(), filter.After.UTC()).
+		Where("project_id = ? AND deleted_at IS NULL AND created_at > ? AND created_at < ?", filter.ProjectID, previousAfter.UTC(), filter.Before.UTC()).
+		Group("url").Having("count > previous").Order("count - previous DESC, count DESC, url")
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+
+	trends := []Trend{}
+	result := tx.Scan(&trends)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return trends, nil
+}
+
+// events selects events of a given type which have a visitor ID, limited by project, visitor and bounds.
+func events(eventType string, projectID uint, visitorID string, after, before time.Time) (string, []any) {
+	query := fmt.Sprintf("SELECT visitor_id, created_at, url, '%s' AS type FROM %s WHERE project_id = ? AND visitor_id <> '' AND deleted_at IS NULL", eventType, tables[eventType])
+	args := []any{projectID}
+
+	if visitorID != "" {
+		query += " AND visitor_id = ?"
+		args = append(args, visitorID)
+	}
+	if !after.IsZero() {
+		query += " AND created_at > ?"
+		args = append(args, after.UTC())
+	}
+	if !before.IsZero() {
+		query += " AND created_at < ?"
+		args = append(args, before.UTC())
+	}
+
+	return query, args
+}
+
+// NewSQLiteRepository is a SQLiteRepository constructor.
+func NewSQLiteRepository(db *gorm.DB) *SQLiteRepository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
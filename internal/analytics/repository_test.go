@@ -0,0 +1,390 @@
+package analytics
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var (
+	pricing = Step{Type: TypeView, URL: "https://example.com/pricing"}
+	signup  = Step{Type: TypeClick, URL: "https://example.com/signup"}
+	welcome = Step{Type: TypeView, URL: "https://example.com/welcome"}
+)
+
+func TestFunnel(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createEvents(t, gormDB, start)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	tests := map[string]struct {
+		funnel   Funnel
+		expected []int64
+	}{
+		"all steps": {
+			funnel:   Funnel{ProjectID: 1, Steps: []Step{pricing, signup, welcome}, Window: 24 * time.Hour},
+			expected: []int64{4, 2, 1},
+		},
+		"longer window": {
+			funnel:   Funnel{ProjectID: 1, Steps: []Step{pricing, signup, welcome}, Window: 72 * time.Hour},
+			expected: []int64{4, 3, 1},
+		},
+		"shorter window": {
+			funnel:   Funnel{ProjectID: 1, Steps: []Step{pricing, signup, welcome}, Window: 90 * time.Minute},
+			expected: []int64{4, 2, 0},
+		},
+		"bounded": {
+			funnel:   Funnel{ProjectID: 1, Steps: []Step{pricing, signup, welcome}, Window: 24 * time.Hour, Before: start.Add(90 * time.Minute)},
+			expected: []int64{4, 2, 0},
+		},
+		"reversed": {
+			funnel:   Funnel{ProjectID: 1, Steps: []Step{signup, pricing}, Window: 24 * time.Hour},
+			expected: []int64{4, 1},
+		},
+		"other project": {
+			funnel:   Funnel{ProjectID: 2, Steps: []Step{pricing, signup}, Window: 24 * time.Hour},
+			expected: []int64{1, 0},
+		},
+		"repeated first step": {
+			// the visitor comes back to pricing after the window has passed and signs up then
+			funnel:   Funnel{ProjectID: 3, Steps: []Step{pricing, signup}, Window: 24 * time.Hour},
+			expected: []int64{1, 1},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			results, err := sqliteRepo.Funnel(context.Background(), test.funnel)
+			assert.NoError(t, err)
+
+			visitors := make([]int64, 0, len(results))
+			for i, result := range results {
+				assert.Equal(t, test.funnel.Steps[i], result.Step)
+				visitors = append(visitors, result.Visitors)
+			}
+			assert.Equal(t, test.expected, visitors)
+		})
+	}
+
+	_, err := sqliteRepo.Funnel(context.Background(), Funnel{ProjectID: 1, Steps: []Step{{Type: "scroll", URL: "x"}}})
+	assert.Error(t, err)
+}
+
+func TestSessions(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	tests := map[string]struct {
+		filter   SessionFilter
+		expected []Session
+	}{
+		"all": {
+			filter: SessionFilter{ProjectID: 1, Timeout: 30 * time.Minute},
+			expected: []Session{
+				{VisitorID: "s1", Start: start.Add(130 * time.Minute), End: start.Add(135 * time.Minute), PageViews: 2, EntryURL: "/a", ExitURL: "/c"},
+				{VisitorID: "s3", Start: start.Add(3 * time.Minute), End: start.Add(3 * time.Minute), Clicks: 1, EntryURL: "/x", ExitURL: "/x"},
+				{VisitorID: "s1", Start: start, End: start.Add(10 * time.Minute), PageViews: 3, Clicks: 1, EntryURL: "/a", ExitURL: "/c"},
+				{VisitorID: "s2", Start: start, End: start.Add(time.Minute), PageViews: 2, EntryURL: "/a", ExitURL: "/b"},
+			},
+		},
+		"visitor with longer timeout": {
+			filter: SessionFilter{ProjectID: 1, VisitorID: "s1", Timeout: 3 * time.Hour},
+			expected: []Session{
+				{VisitorID: "s1", Start: start, End: start.Add(135 * time.Minute), PageViews: 5, Clicks: 1, EntryURL: "/a", ExitURL: "/c"},
+			},
+		},
+		"bounded and limited": {
+			filter: SessionFilter{ProjectID: 1, Before: start.Add(time.Hour), Timeout: 30 * time.Minute, Limit: 2},
+			expected: []Session{
+				{VisitorID: "s3", Start: start.Add(3 * time.Minute), End: start.Add(3 * time.Minute), Clicks: 1, EntryURL: "/x", ExitURL: "/x"},
+				{VisitorID: "s1", Start: start, End: start.Add(10 * time.Minute), PageViews: 3, Clicks: 1, EntryURL: "/a", ExitURL: "/c"},
+			},
+		},
+		"other project": {
+			filter:   SessionFilter{ProjectID: 2, Timeout: 30 * time.Minute},
+			expected: []Session{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sessions, err := sqliteRepo.Sessions(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, sessions)
+		})
+	}
+}
+
+func TestPaths(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	tests := map[string]struct {
+		filter   PathFilter
+		expected []Transition
+	}{
+		"most common first": {
+			filter:   PathFilter{ProjectID: 1, URL: "/a", Timeout: 30 * time.Minute},
+			expected: []Transition{{URL: "/b", Count: 2}, {URL: "/c", Count: 1}},
+		},
+		"exits": {
+			filter:   PathFilter{ProjectID: 1, URL: "/b", Timeout: 30 * time.Minute},
+			expected: []Transition{{URL: "", Count: 1}, {URL: "/c", Count: 1}},
+		},
+		"sessions end after timeout": {
+			filter:   PathFilter{ProjectID: 1, URL: "/c", Timeout: 30 * time.Minute},
+			expected: []Transition{{URL: "", Count: 2}},
+		},
+		"longer timeout": {
+			filter:   PathFilter{ProjectID: 1, URL: "/c", Timeout: 3 * time.Hour},
+			expected: []Transition{{URL: "", Count: 1}, {URL: "/a", Count: 1}},
+		},
+		"bounded": {
+			filter:   PathFilter{ProjectID: 1, URL: "/a", After: start.Add(time.Hour), Timeout: 30 * time.Minute},
+			expected: []Transition{{URL: "/c", Count: 1}},
+		},
+		"unknown URL": {
+			filter:   PathFilter{ProjectID: 1, URL: "/x", Timeout: 30 * time.Minute},
+			expected: []Transition{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			transitions, err := sqliteRepo.Paths(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, transitions)
+		})
+	}
+}
+
+func TestTop(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	tests := map[string]struct {
+		filter   TopFilter
+		expected []URLCount
+	}{
+		"views": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView},
+			expected: []URLCount{{URL: "/a", Count: 4}, {URL: "/b", Count: 2}, {URL: "/c", Count: 2}},
+		},
+		"clicks": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeClick},
+			expected: []URLCount{{URL: "/x", Count: 2}},
+		},
+		"bounded": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView, After: start.Add(time.Hour), Before: start.Add(3 * time.Hour)},
+			expected: []URLCount{{URL: "/a", Count: 1}, {URL: "/c", Count: 1}},
+		},
+		"limited": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView, Limit: 1},
+			expected: []URLCount{{URL: "/a", Count: 4}},
+		},
+		"other project": {
+			filter:   TopFilter{ProjectID: 2, Type: TypeView},
+			expected: []URLCount{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			counts, err := sqliteRepo.Top(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, counts)
+		})
+	}
+
+	_, err := sqliteRepo.Top(context.Background(), TopFilter{ProjectID: 1, Type: "scroll"})
+	assert.Error(t, err)
+}
+
+func TestTrending(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	tests := map[string]struct {
+		filter   TopFilter
+		expected []Trend
+	}{
+		"new URLs": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView, After: start.Add(2 * time.Hour), Before: start.Add(3 * time.Hour)},
+			expected: []Trend{{URL: "/a", Count: 1}, {URL: "/c", Count: 1}},
+		},
+		"growing URLs": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView, After: start.Add(2 * time.Minute), Before: start.Add(12 * time.Minute)},
+			expected: []Trend{{URL: "/c", Count: 1}},
+		},
+		"declining URLs": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeView, After: start.Add(time.Hour), Before: start.Add(3 * time.Hour)},
+			expected: []Trend{},
+		},
+		"clicks": {
+			filter:   TopFilter{ProjectID: 1, Type: TypeClick, After: start, Before: start.Add(10 * time.Minute), Limit: 1},
+			expected: []Trend{{URL: "/x", Count: 2}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			trends, err := sqliteRepo.Trending(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, trends)
+		})
+	}
+}
+
+func TestDeletedEvents(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	createSessionEvents(t, gormDB, start)
+
+	// views of s2 and the click of s3
+	views := view.NewSQLiteRepository(gormDB)
+	assert.NoError(t, views.Delete(context.Background(), 1, 6))
+	assert.NoError(t, views.Delete(context.Background(), 1, 7))
+	assert.NoError(t, click.NewSQLiteRepository(gormDB).Delete(context.Background(), 1, 2))
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	sessions, err := sqliteRepo.Sessions(context.Background(), SessionFilter{ProjectID: 1, Timeout: 30 * time.Minute})
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, "s1", session.VisitorID)
+	}
+
+	transitions, err := sqliteRepo.Paths(context.Background(), PathFilter{ProjectID: 1, URL: "/a", Timeout: 30 * time.Minute})
+	assert.NoError(t, err)
+	assert.Equal(t, []Transition{{URL: "/b", Count: 1}, {URL: "/c", Count: 1}}, transitions)
+
+	counts, err := sqliteRepo.Top(context.Background(), TopFilter{ProjectID: 1, Type: TypeView, Limit: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []URLCount{{URL: "/a", Count: 3}}, counts)
+
+	trends, err := sqliteRepo.Trending(context.Background(), TopFilter{ProjectID: 1, Type: TypeClick, After: start, Before: start.Add(time.Hour)})
+	assert.NoError(t, err)
+	assert.Equal(t, []Trend{{URL: "/x", Count: 1}}, trends)
+
+	results, err := sqliteRepo.Funnel(context.Background(), Funnel{ProjectID: 1, Steps: []Step{{Type: TypeView, URL: "/a"}, {Type: TypeView, URL: "/b"}}, Window: time.Hour})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), results[0].Visitors)
+	assert.Equal(t, int64(1), results[1].Visitors)
+}
+
+// createSessionEvents stores events of three visitors: s1 comes back after two hours,
+// s2 leaves after two pages and s3 only clicks a link.
+func createSessionEvents(t *testing.T, gormDB *gorm.DB, start time.Time) {
+	t.Helper()
+
+	_, err := view.NewSQLiteRepository(gormDB).CreateBatch(context.Background(), view.ViewCollection{
+		{ProjectID: 1, VisitorID: "s1", URL: "/a", CreatedAt: start},
+		{ProjectID: 1, VisitorID: "s1", URL: "/b", CreatedAt: start.Add(5 * time.Minute)},
+		{ProjectID: 1, VisitorID: "s1", URL: "/c", CreatedAt: start.Add(10 * time.Minute)},
+		{ProjectID: 1, VisitorID: "s1", URL: "/a", CreatedAt: start.Add(130 * time.Minute)},
+		{ProjectID: 1, VisitorID: "s1", URL: "/c", CreatedAt: start.Add(135 * time.Minute)},
+		{ProjectID: 1, VisitorID: "s2", URL: "/a", CreatedAt: start},
+		{ProjectID: 1, VisitorID: "s2", URL: "/b", CreatedAt: start.Add(time.Minute)},
+		{ProjectID: 1, URL: "/a", CreatedAt: start},
+	})
+	assert.NoError(t, err)
+
+	_, err = click.NewSQLiteRepository(gormDB).CreateBatch(context.Background(), click.ClickCollection{
+		{ProjectID: 1, VisitorID: "s1", URL: "/x", CreatedAt: start.Add(6 * time.Minute)},
+		{ProjectID: 1, VisitorID: "s3", URL: "/x", CreatedAt: start.Add(3 * time.Minute)},
+	})
+	assert.NoError(t, err)
+}
+
+// createEvents stores events of visitors going through the pricing, signup and welcome funnel:
+// a completes it, b signs up without reaching the welcome page, c signs up before seeing the pricing,
+// d signs up two days later, and the anonymous visitor and e of another project only see the pricing.
+func createEvents(t *testing.T, gormDB *gorm.DB, start time.Time) {
+	t.Helper()
+
+	_, err := view.NewSQLiteRepository(gormDB).CreateBatch(context.Background(), view.ViewCollection{
+		{ProjectID: 1, VisitorID: "a", URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 1, VisitorID: "a", URL: welcome.URL, CreatedAt: start.Add(2 * time.Hour)},
+		{ProjectID: 1, VisitorID: "b", URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 1, VisitorID: "b", URL: pricing.URL, CreatedAt: start.Add(30 * time.Minute)},
+		{ProjectID: 1, VisitorID: "c", URL: pricing.URL, CreatedAt: start.Add(time.Hour)},
+		{ProjectID: 1, VisitorID: "d", URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 1, URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 2, VisitorID: "e", URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 3, VisitorID: "f", URL: pricing.URL, CreatedAt: start},
+		{ProjectID: 3, VisitorID: "f", URL: pricing.URL, CreatedAt: start.Add(72 * time.Hour)},
+	})
+	assert.NoError(t, err)
+
+	_, err = click.NewSQLiteRepository(gormDB).CreateBatch(context.Background(), click.ClickCollection{
+		{ProjectID: 1, VisitorID: "a", URL: signup.URL, CreatedAt: start.Add(time.Hour)},
+		{ProjectID: 1, VisitorID: "b", URL: signup.URL, CreatedAt: start.Add(time.Hour)},
+		{ProjectID: 1, VisitorID: "c", URL: signup.URL, CreatedAt: start},
+		{ProjectID: 1, VisitorID: "d", URL: signup.URL, CreatedAt: start.Add(48 * time.Hour)},
+		{ProjectID: 2, VisitorID: "a", URL: signup.URL, CreatedAt: start.Add(time.Hour)},
+		{ProjectID: 3, VisitorID: "f", URL: signup.URL, CreatedAt: start.Add(73 * time.Hour)},
+	})
+	assert.NoError(t, err)
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&click.ClickDAO{}, &view.ViewDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
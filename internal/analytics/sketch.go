@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/topk"
+)
+
+// ErrNotKept is returned when approximate counts of a period are no longer kept.
+var ErrNotKept = errors.New("approximate counts are only kept")
+
+// SketchRanker is a Ranker backed by heavy hitters sketches of a topk.Tracker, maintained on ingestion.
+// Counts are approximate, may be higher than the true ones, and cover only whole hours of recent events
+// seen by this instance. Event types are the same as the ones of the Tracker.
+type SketchRanker struct {
+	tracker *topk.Tracker
+}
+
+// Top implements Ranker interface. Zero After stands for the oldest kept counts.
+func (r *SketchRanker) Top(ctx context.Context, filter TopFilter) ([]URLCount, error) {
+	if err := r.kept(filter.After); err != nil {
+		return nil, err
+	}
+
+	top := r.tracker.Sketch(filter.ProjectID, filter.Type, filter.After, filter.Before).Top(limit(filter.Limit))
+	counts := make([]URLCount, 0, len(top))
+	for _, c := range top {
+		counts = append(counts, URLCount{URL: c.URL, Count: int64(c.Count)})
+	}
+
+	return counts, nil
+}
+
+// Trending implements Ranker interface.
+func (r *SketchRanker) Trending(ctx context.Context, filter TopFilter) ([]Trend, error) {
+	previousAfter, previousBefore := filter.Previous()
+	if err := r.kept(previousAfter); err != nil {
+		return nil, err
+	}
+
+	current := r.tracker.Sketch(filter.ProjectID, filter.Type, filter.After, filter.Before)
+	previous := r.tracker.Sketch(filter.ProjectID, filter.Type, previousAfter, previousBefore)
+
+	trends := []Trend{}
+	for _, c := range current.Top(-1) {
+		trend := Trend{URL: c.URL, Count: int64(c.Count), Previous: int64(previous.Get(c.URL).Count)}
+		if trend.Change() > 0 {
+			trends = append(trends, trend)
+		}
+	}
+	sort.SliceStable(trends, func(i, j int) bool {
+		if trends[i].Change() != trends[j].Change() {
+			return trends[i].Change() > trends[j].Change()
+		}
+		return trends[i].Count > trends[j].Count
+	})
+	if n := limit(filter.Limit); n >= 0 && n < len(trends) {
+		trends = trends[:n]
+	}
+
+	return trends, nil
+}
+
+// kept checks whether counts since a given time are still kept.
+func (r *SketchRanker) kept(after time.Time) error {
+	if since := r.tracker.Since(time.Now()); !after.IsZero() && after.Before(since) {
+		return fmt.Errorf("%w since %s", ErrNotKept, since.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// NewSketchRanker is a SketchRanker constructor.
+func NewSketchRanker(tracker *topk.Tracker) *SketchRanker {
+	return &SketchRanker{
+		tracker: tracker,
+	}
+}
+
+// limit maps a TopFilter Limit to the one of topk.Sketch, where all are returned when negative.
+func limit(n int) int {
+	if n <= 0 {
+		return -1
+	}
+	return n
+}
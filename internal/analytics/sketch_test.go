@@ -0,0 +1,41 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/topk"
+)
+
+func TestSketchRanker(t *testing.T) {
+	now := time.Now()
+	tracker := topk.NewTracker(10, 4)
+	for _, url := range []string{"/a", "/a", "/a", "/b"} {
+		tracker.Add(1, topk.EventView, url, now.Add(-time.Hour))
+	}
+	for _, url := range []string{"/a", "/b", "/b", "/c", "/c", "/c"} {
+		tracker.Add(1, topk.EventView, url, now)
+	}
+	ranker := NewSketchRanker(tracker)
+
+	current := TopFilter{ProjectID: 1, Type: TypeView, After: now.Truncate(time.Hour), Before: now.Truncate(time.Hour).Add(time.Hour)}
+
+	counts, err := ranker.Top(context.Background(), TopFilter{ProjectID: 1, Type: TypeView, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []URLCount{{URL: "/a", Count: 4}, {URL: "/b", Count: 3}}, counts)
+
+	trends, err := ranker.Trending(context.Background(), current)
+	assert.NoError(t, err)
+	assert.Equal(t, []Trend{{URL: "/c", Count: 3}, {URL: "/b", Count: 2, Previous: 1}}, trends)
+
+	current.Limit = 1
+	trends, err = ranker.Trending(context.Background(), current)
+	assert.NoError(t, err)
+	assert.Equal(t, []Trend{{URL: "/c", Count: 3}}, trends)
+
+	_, err = ranker.Top(context.Background(), TopFilter{ProjectID: 1, Type: TypeView, After: now.Add(-24 * time.Hour)})
+	assert.True(t, errors.Is(err, ErrNotKept))
+}
@@ -1,15 +1,30 @@
 package click
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/ratelimit"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracker"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 )
 
 // ClickDTO represents HTTP request/response model.
+// EventID is an optional client generated identifier, used to recognise resubmissions.
+// VisitorID is an optional client generated identifier of the visitor, used to analyse funnels.
 type ClickDTO struct {
 	ID        uint   `json:"id,omitempty"`
+	EventID   string `json:"eventId,omitempty"`
+	VisitorID string `json:"visitorId,omitempty"`
 	URL       string `json:"url" validate:"required,url"`
 	CreatedAt string `json:"createdAt,omitempty"`
 }
@@ -17,10 +32,40 @@ type ClickDTO struct {
 // ToDomain maps DTO model into domain model.
 func (c ClickDTO) ToDomain() Click {
 	return Click{
-		URL: c.URL,
+		ExternalID: c.EventID,
+		VisitorID:  c.VisitorID,
+		URL:        c.URL,
 	}
 }
 
+// Validate checks that the ClickDTO can be created.
+func (c ClickDTO) Validate() error {
+	if c.URL == "" {
+		return errors.New("url is required")
+	}
+	return nil
+}
+
+// ClickPatchDTO represents HTTP request model of correcting a Click. Only the fields present are changed.
+type ClickPatchDTO struct {
+	URL       *string `json:"url"`
+	VisitorID *string `json:"visitorId"`
+}
+
+// Apply changes a Click with the fields present in the ClickPatchDTO.
+func (p ClickPatchDTO) Apply(click Click) (Click, error) {
+	if p.URL != nil {
+		if *p.URL == "" {
+			return Click{}, errors.New("url must not be empty")
+		}
+		click.URL = *p.URL
+	}
+	if p.VisitorID != nil {
+		click.VisitorID = *p.VisitorID
+	}
+	return click, nil
+}
+
 // ClickDTOCollection represents ClickDTO collection.
 type ClickDTOCollection []ClickDTO
 
@@ -36,39 +81,117 @@ func NewClickDTOCollection(clickCollection ClickCollection) ClickDTOCollection {
 }
 
 // FilterDTO represents HTTP request model.
+// URL may be repeated to match any of the URLs, and NotURL, given as "url!=", excludes URLs.
+// URLGlob and Path are patterns, where '*' matches any characters and '?' a single one.
+// Host matches the host of URLs exactly, and Domain also its subdomains.
+// Before and After accept RFC 3339 timestamps, Unix epoch seconds and expressions relative to now, such as "now-7d".
+// AfterID and Limit page through results, which are ordered by ID.
+// Q is an expression of the query language, such as `url ~ "/blog/" or createdAt > now-1d`.
 type FilterDTO struct {
-	URL    string    `query:"url"`
-	Before time.Time `query:"before"`
-	After  time.Time `query:"after"`
+	URL       []string       `query:"url"`
+	NotURL    []string       `query:"url!"`
+	URLPrefix string         `query:"urlPrefix"`
+	URLGlob   string         `query:"urlGlob"`
+	Host      string         `query:"host"`
+	Domain    string         `query:"domain"`
+	Path      string         `query:"path"`
+	Before    timeparam.Time `query:"before"`
+	After     timeparam.Time `query:"after"`
+	AfterID   uint           `query:"afterId"`
+	Limit     int            `query:"limit"`
+	Q         string         `query:"q"`
 }
 
-// ToDomain maps DTO model into domain model.
-func (f *FilterDTO) ToDomain() Filter {
-	return Filter{
-		URL:    f.URL,
-		Before: f.Before,
-		After:  f.After,
+// ToDomain maps DTO model into domain model. Relative times of Q are resolved against now.
+func (f *FilterDTO) ToDomain(now time.Time) (Filter, error) {
+	condition, err := query.ParseAndCompile(f.Q, queryFields, now)
+	if err != nil {
+		return Filter{}, err
 	}
+
+	return Filter{
+		URLMatch: urlmatch.Match{
+			URLs:    urlmatch.NonEmpty(f.URL),
+			NotURLs: urlmatch.NonEmpty(f.NotURL),
+			Prefix:  f.URLPrefix,
+			Glob:    f.URLGlob,
+			Host:    f.Host,
+			Domain:  f.Domain,
+			Path:    f.Path,
+		},
+		Before:  f.Before.Time,
+		After:   f.After.Time,
+		Query:   condition,
+		AfterID: f.AfterID,
+		Limit:   f.Limit,
+	}, nil
+}
+
+// DeletedDTO represents HTTP response model of deleting Clicks matching a filter.
+type DeletedDTO struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// PurgeDTO represents HTTP request model. DeletedBefore limits purging to Clicks deleted before it,
+// all deleted Clicks are purged when it is not given.
+type PurgeDTO struct {
+	DeletedBefore timeparam.Time `query:"deletedBefore"`
+}
+
+// PurgedDTO represents HTTP response model of purging deleted Clicks.
+type PurgedDTO struct {
+	Purged int64 `json:"purged"`
 }
 
 // Handler defines all API methods for Click.
 type Handler struct {
 	clickRepository Repository
+	dedupWindow     *dedup.Window[Click]
+	hub             *stream.Hub[Click]
+	counters        *live.Counters
+	allowlist       Allowlist
 }
 
 // Create implements handler for Create Click HTTP request.
+// Resubmitting a known EventID, or submitting the same URL again from the same visitor
+// within the dedup window, returns the original Click instead of creating a new one.
+// A JSON array of Clicks is validated as a whole, created one by one and responded to with an array.
 func (h *Handler) Create(c echo.Context) error {
-	var clickDTO ClickDTO
-	if err := c.Bind(&clickDTO); err != nil {
+	clickDTOs, batch, err := tracker.Bind[ClickDTO](c)
+	if err != nil {
 		return err
 	}
-
-	click, err := h.clickRepository.Create(c.Request().Context(), clickDTO.ToDomain())
-	if err != nil {
+	if err := tracker.Validate(clickDTOs, batch); err != nil {
+		return err
+	}
+	// every event of a batch counts against rate limits, not only the request
+	if err := ratelimit.Charge(c, len(clickDTOs)-1); err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusCreated, NewClickDTO(click))
+	response := make(ClickDTOCollection, 0, len(clickDTOs))
+	for _, clickDTO := range clickDTOs {
+		click := clickDTO.ToDomain()
+		click.ProjectID = project.ID(c)
+
+		click, err := h.Record(c, click)
+		if err != nil {
+			return err
+		}
+		response = append(response, NewClickDTO(click))
+	}
+
+	if batch {
+		return c.JSON(http.StatusCreated, response)
+	}
+	return c.JSON(http.StatusCreated, response[0])
+}
+
+// Record records a Click submitted through any of the endpoints, so they all deduplicate,
+// stream and count Clicks the same way, including the gRPC API. A resubmitted Click is returned in place of the new one.
+func (h *Handler) Record(c echo.Context, click Click) (Click, error) {
+	recorder := NewRecorder(h.clickRepository, h.dedupWindow, h.hub, h.counters)
+	return recorder.Record(c.Request().Context(), dedup.Visitor(c.Request(), c.RealIP()), click)
 }
 
 // Filter implements handler for Filter Click HTTP request.
@@ -78,7 +201,20 @@ func (h *Handler) Filter(c echo.Context) error {
 		return err
 	}
 
-	clickCollection, err := h.clickRepository.Filter(c.Request().Context(), filterDTO.ToDomain())
+	if filterDTO.Limit < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must not be negative")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	clickCollection, err := h.clickRepository.Filter(c.Request().Context(), filter)
 	if err != nil {
 		return err
 	}
@@ -86,10 +222,185 @@ func (h *Handler) Filter(c echo.Context) error {
 	return c.JSON(http.StatusOK, NewClickDTOCollection(clickCollection))
 }
 
+// Stream implements handler for Stream Click HTTP request.
+// Newly created Clicks are pushed to the client as Server-Sent Events, optionally limited to matching URLs.
+// Clients reconnecting with Last-Event-ID header receive Clicks they have missed in the meantime.
+func (h *Handler) Stream(c echo.Context) error {
+	if h.hub == nil {
+		return echo.ErrNotFound
+	}
+
+	var filterDTO FilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	// streamed events are matched in memory, which the query language doesn't support
+	if filterDTO.Q != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is not supported by streams")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var match func(Click) bool
+	if !filter.URLMatch.IsZero() {
+		match = func(click Click) bool {
+			return filter.URLMatch.Matches(click.URL)
+		}
+	}
+
+	subscription := h.hub.Subscribe(project.ID(c), match, stream.LastEventID(c))
+
+	return stream.Serve(c, h.hub, subscription, "click", func(click Click) any {
+		return NewClickDTO(click)
+	})
+}
+
+// Get implements handler for Get Click HTTP request.
+func (h *Handler) Get(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	click, err := h.clickRepository.Get(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewClickDTO(click))
+}
+
+// Update implements handler for Update Click HTTP request, which corrects a recorded Click.
+func (h *Handler) Update(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var patchDTO ClickPatchDTO
+	if err := c.Bind(&patchDTO); err != nil {
+		return err
+	}
+
+	click, err := h.clickRepository.Get(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+	click, err = patchDTO.Apply(click)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	click, err = h.clickRepository.Update(c.Request().Context(), click)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewClickDTO(click))
+}
+
+// Delete implements handler for Delete Click HTTP request.
+// The Click is only marked as deleted and kept until it is purged.
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.clickRepository.Delete(c.Request().Context(), project.ID(c), id); err != nil {
+		return notFound(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Restore implements handler for Restore Click HTTP request, which undoes deleting a Click until it is purged.
+func (h *Handler) Restore(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	click, err := h.clickRepository.Restore(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewClickDTO(click))
+}
+
+// DeleteMatching implements handler for Delete Clicks HTTP request.
+// It takes the same filters as Filter, at least one of them is required so all Clicks of a project
+// are not deleted by accident.
+func (h *Handler) DeleteMatching(c echo.Context) error {
+	var filterDTO FilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	if filterDTO.Limit != 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit is not supported when deleting")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if filter.IsZero() {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one filter is required")
+	}
+	filter.ProjectID = project.ID(c)
+
+	deleted, err := h.clickRepository.DeleteMatching(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, DeletedDTO{Deleted: deleted})
+}
+
+// Purge implements handler for Purge Clicks HTTP request, which is meant for administrators.
+// It permanently removes deleted Clicks of the project given in the path.
+func (h *Handler) Purge(c echo.Context) error {
+	projectID, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var purgeDTO PurgeDTO
+	if err := c.Bind(&purgeDTO); err != nil {
+		return err
+	}
+
+	purged, err := h.clickRepository.Purge(c.Request().Context(), projectID, purgeDTO.DeletedBefore.Time)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, PurgedDTO{Purged: purged})
+}
+
 // NewHandler is a Handler constructor.
-func NewHandler(clickRepository Repository) Handler {
+// Nil dedupWindow disables deduplication of repeated submissions by the same visitor,
+// nil hub disables streaming of created Clicks and nil counters disables counting them.
+// Empty allowlist rejects all redirects.
+func NewHandler(clickRepository Repository, dedupWindow *dedup.Window[Click], hub *stream.Hub[Click], counters *live.Counters, allowlist Allowlist) Handler {
 	return Handler{
 		clickRepository: clickRepository,
+		dedupWindow:     dedupWindow,
+		hub:             hub,
+		counters:        counters,
+		allowlist:       allowlist,
 	}
 }
 
@@ -97,7 +408,24 @@ func NewHandler(clickRepository Repository) Handler {
 func NewClickDTO(c Click) ClickDTO {
 	return ClickDTO{
 		ID:        c.ID,
+		EventID:   c.ExternalID,
+		VisitorID: c.VisitorID,
 		URL:       c.URL,
-		CreatedAt: c.CreatedAt.Format(time.DateTime),
+		CreatedAt: c.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
 	}
+	return err
 }
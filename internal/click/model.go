@@ -4,28 +4,98 @@ package click
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 )
 
 // Click represents entity model of a single click.
+// VisitorID is an optional identifier of the visitor, which links events of the same visitor together.
 type Click struct {
-	ID        uint
-	URL       string
-	CreatedAt time.Time
+	ID         uint
+	ProjectID  uint
+	ExternalID string
+	VisitorID  string
+	URL        string
+	CreatedAt  time.Time
 }
 
 // ClickCollection represents a collection of Click domain entities.
 type ClickCollection []Click
 
 // Filter holds parameters available for filtering Clicks.
+// Results are always limited to a single project.
 type Filter struct {
-	URL    string
+	ProjectID uint
+	URL       string
+	// URLMatch holds further conditions on URLs, which have to hold along with URL.
+	URLMatch urlmatch.Match
+	// Query is a condition compiled from the query language, which has to hold along with the other filters.
+	Query  query.Condition
 	After  time.Time
 	Before time.Time
+	// AfterID skips Clicks up to and including a given ID, results are ordered by ID
+	// so it can be used along with Limit to paginate through them.
+	AfterID uint
+	// Limit is the maximum number of results, zero means no limit.
+	Limit int
 }
 
+// IsZero reports whether the Filter has no conditions besides the project, so it matches all of its Clicks.
+func (f Filter) IsZero() bool {
+	return f.URL == "" && f.URLMatch.IsZero() && f.Query.IsZero() && f.After.IsZero() && f.Before.IsZero() && f.AfterID == 0
+}
+
+// Period is the length of time buckets Clicks are grouped into.
+type Period string
+
+// Supported periods.
+const (
+	PeriodHour  Period = "hour"
+	PeriodDay   Period = "day"
+	PeriodMonth Period = "month"
+)
+
+// GroupBy holds grouping of aggregated Clicks. Zero value aggregates all Clicks into a single Group.
+// Time buckets start at the full hour, day or month in Location, nil Location is UTC.
+type GroupBy struct {
+	URL      bool
+	Period   Period
+	Location *time.Location
+}
+
+// Group holds the number of Clicks in a single group.
+// URL and Period are set only when Clicks are grouped by them, Period is the start of a time bucket in GroupBy.Location.
+type Group struct {
+	URL    string
+	Period time.Time
+	Count  int64
+}
+
+// GroupCollection represents a collection of Groups.
+type GroupCollection []Group
+
+// ErrDuplicate is returned by Repository.Create, along with the already stored entity,
+// when a Click with the same ExternalID exists in the project.
+var ErrDuplicate = errors.New("duplicate click")
+
+// ErrNotFound is returned when a Click doesn't exist in the project, or it was deleted.
+var ErrNotFound = errors.New("click not found")
+
 // Repository defines a storage API for Click entity.
+// Deleted Clicks are kept until purged, but are not returned, counted or deleted again.
 type Repository interface {
 	Create(context.Context, Click) (Click, error)
+	CreateBatch(context.Context, ClickCollection) (int64, error)
 	Filter(context.Context, Filter) (ClickCollection, error)
+	Count(context.Context, Filter) (int64, error)
+	Aggregate(context.Context, Filter, GroupBy) (GroupCollection, error)
+	Get(ctx context.Context, projectID, id uint) (Click, error)
+	Update(context.Context, Click) (Click, error)
+	Delete(ctx context.Context, projectID, id uint) error
+	Restore(ctx context.Context, projectID, id uint) (Click, error)
+	DeleteMatching(context.Context, Filter) (int64, error)
+	Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error)
 }
@@ -0,0 +1,76 @@
+package click
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+)
+
+// Recorder records Clicks submitted through any of the APIs, so they all deduplicate,
+// stream and count Clicks the same way.
+type Recorder struct {
+	repository  Repository
+	dedupWindow *dedup.Window[Click]
+	hub         *stream.Hub[Click]
+	counters    *live.Counters
+}
+
+// Record creates a Click submitted by a visitor, an identifier of the client such as dedup.AnonymousVisitor,
+// which is used only if the Click carries no VisitorID. Resubmitting a known ExternalID, or submitting
+// the same URL again from the same visitor within the dedup window, returns the original Click
+// instead of creating a new one.
+func (r Recorder) Record(ctx context.Context, visitor string, click Click) (Click, error) {
+	var dedupKey string
+	if r.dedupWindow != nil {
+		if click.VisitorID != "" {
+			// visitors sharing an address and a browser are told apart, and a visitor is recognised across devices
+			visitor = "id:" + click.VisitorID
+		}
+		dedupKey = fmt.Sprintf("%d|%s|%s", click.ProjectID, visitor, click.URL)
+		// reserving the key makes concurrent identical submissions wait for the first one
+		original, ok, err := r.dedupWindow.Reserve(ctx, dedupKey)
+		if err != nil {
+			return Click{}, err
+		}
+		if ok {
+			return original, nil
+		}
+	}
+
+	click, err := r.repository.Create(ctx, click)
+	if err != nil && !errors.Is(err, ErrDuplicate) {
+		if r.dedupWindow != nil {
+			r.dedupWindow.Release(dedupKey)
+		}
+		return Click{}, err
+	}
+
+	if r.hub != nil && err == nil {
+		r.hub.Publish(click.ProjectID, click)
+	}
+	if r.counters != nil && err == nil {
+		r.counters.Add(click.ProjectID, click.URL, live.KindClick, click.CreatedAt)
+	}
+
+	if r.dedupWindow != nil {
+		r.dedupWindow.Put(dedupKey, click)
+	}
+
+	return click, nil
+}
+
+// NewRecorder is a Recorder constructor.
+// Nil dedupWindow disables deduplication of repeated submissions by the same visitor,
+// nil hub disables streaming of created Clicks and nil counters disables counting them.
+func NewRecorder(repository Repository, dedupWindow *dedup.Window[Click], hub *stream.Hub[Click], counters *live.Counters) Recorder {
+	return Recorder{
+		repository:  repository,
+		dedupWindow: dedupWindow,
+		hub:         hub,
+		counters:    counters,
+	}
+}
@@ -0,0 +1,67 @@
+package click
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// Allowlist holds hosts Redirect is allowed to redirect to.
+// A host starting with "*." matches all of its subdomains, but not the domain itself.
+type Allowlist []string
+
+// Allows reports whether target is an absolute http or https URL on an allowed host.
+func (a Allowlist) Allows(target *url.URL) bool {
+	if target.Scheme != "http" && target.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(target.Hostname())
+	for _, allowed := range a {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// NewAllowlist parses a comma separated list of hosts, e.g. "example.com,*.example.com".
+func NewAllowlist(hosts string) Allowlist {
+	var allowlist Allowlist
+	for _, host := range strings.Split(hosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			allowlist = append(allowlist, host)
+		}
+	}
+	return allowlist
+}
+
+// Redirect implements handler for Redirect HTTP request.
+// It records a Click on the target URL given in "to" query parameter and redirects to it,
+// which tracks clicks on outbound links without any JavaScript. Targets are restricted
+// to the allowlist, so the endpoint can't be used as an open redirect.
+func (h *Handler) Redirect(c echo.Context) error {
+	to := c.QueryParam("to")
+	target, err := url.Parse(to)
+	if to == "" || err != nil || !h.allowlist.Allows(target) {
+		return echo.NewHTTPError(http.StatusBadRequest, "to must be a URL on an allowed host")
+	}
+
+	// a link should keep working even if the click can't be recorded
+	if _, err := h.Record(c, Click{ProjectID: project.ID(c), VisitorID: c.QueryParam("visitorId"), URL: to}); err != nil {
+		c.Logger().Error(err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Redirect(http.StatusFound, to)
+}
@@ -0,0 +1,80 @@
+package click
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+func TestAllowlist(t *testing.T) {
+	allowlist := NewAllowlist("example.com, *.example.org")
+
+	tests := map[string]bool{
+		"https://example.com/page":       true,
+		"http://EXAMPLE.com":             true,
+		"https://www.example.com":        false,
+		"https://shop.example.org/a?b=c": true,
+		"https://example.org":            false,
+		"https://evilexample.org":        false,
+		"https://example.com.evil.net":   false,
+		"javascript://example.com/%0a":   false,
+		"//example.com":                  false,
+	}
+
+	for target, expected := range tests {
+		t.Run(target, func(t *testing.T) {
+			u, err := url.Parse(target)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, allowlist.Allows(u))
+		})
+	}
+}
+
+func TestHandlerRedirect(t *testing.T) {
+	tests := []struct {
+		testName       string
+		to             string
+		createErr      error
+		expectedStatus int
+	}{
+		{testName: "allowed", to: "https://example.com/page?a=b", expectedStatus: http.StatusFound},
+		{testName: "recording fails", to: "https://example.com/page", createErr: errors.New("database is locked"), expectedStatus: http.StatusFound},
+		{testName: "not allowed", to: "https://evil.net", expectedStatus: http.StatusBadRequest},
+		{testName: "missing", to: "", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/r?"+url.Values{"to": {test.to}}.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			clickRepository := &ClickRepositoryMock{}
+			clickRepository.On("Create", mock.Anything, Click{ProjectID: 1, URL: test.to}).
+				Return(Click{ID: 1, ProjectID: 1, URL: test.to}, test.createErr)
+
+			h := &Handler{clickRepository: clickRepository, allowlist: NewAllowlist("example.com")}
+			err := h.Redirect(c)
+			if test.expectedStatus != http.StatusFound {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				clickRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, test.to, rec.Header().Get(echo.HeaderLocation))
+			assert.Equal(t, "no-store", rec.Header().Get(echo.HeaderCacheControl))
+			clickRepository.AssertExpectations(t)
+		})
+	}
+}
@@ -2,16 +2,34 @@ package click
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracing"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var tracer = otel.Tracer("google.com/ivan-sabo/clicks-and-views/internal/click")
+
 // ClickDAO represents a single database entry.
 type ClickDAO struct {
-	ID        uint `gorm:"primarykey"`
-	CreatedAt time.Time
-	URL       string
+	ID         uint    `gorm:"primarykey"`
+	ProjectID  uint    `gorm:"index;uniqueIndex:idx_clicks_project_external_id"`
+	ExternalID *string `gorm:"uniqueIndex:idx_clicks_project_external_id"`
+	VisitorID  string  `gorm:"index"`
+	CreatedAt  time.Time
+	URL        string
+	Host       string         `gorm:"index"`
+	Path       string         `gorm:"index"`
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
 }
 
 // ClickDAOCollection represents a collection of Click database model.
@@ -34,24 +52,39 @@ func (ClickDAO) TableName() string {
 }
 
 // NewClickDAO maps Click entity model into database model.
+// CreatedAt is stored in UTC, so it can be compared as text, and host and path are split out of URL to be matched.
 func NewClickDAO(c Click) ClickDAO {
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = time.Now()
 	}
-	return ClickDAO{
+	c.CreatedAt = c.CreatedAt.UTC()
+	dao := ClickDAO{
 		ID:        c.ID,
+		ProjectID: c.ProjectID,
+		VisitorID: c.VisitorID,
 		CreatedAt: c.CreatedAt,
 		URL:       c.URL,
 	}
+	dao.Host, dao.Path = urlmatch.Split(c.URL)
+	if c.ExternalID != "" {
+		dao.ExternalID = &c.ExternalID
+	}
+	return dao
 }
 
 // ToModel maps database model into domain model.
 func (c *ClickDAO) ToDomain() Click {
-	return Click{
+	click := Click{
 		ID:        c.ID,
+		ProjectID: c.ProjectID,
+		VisitorID: c.VisitorID,
 		CreatedAt: c.CreatedAt,
 		URL:       c.URL,
 	}
+	if c.ExternalID != nil {
+		click.ExternalID = *c.ExternalID
+	}
+	return click
 }
 
 // SQLiteRepository is a SQLite implementation of Click repository.
@@ -60,38 +93,405 @@ type SQLiteRepository struct {
 }
 
 // Create persists Click entity.
+// If a Click with the same ExternalID already exists in the project, it is returned
+// along with ErrDuplicate and nothing is persisted.
 func (r *SQLiteRepository) Create(ctx context.Context, click Click) (Click, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Create")
+	defer span.End()
+
 	dao := NewClickDAO(click)
 
-	result := r.db.WithContext(ctx).Create(&dao)
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&dao)
+	tracing.RecordQuery(span, result)
 	if result.Error != nil {
 		return Click{}, result.Error
 	}
 
+	if result.RowsAffected == 0 {
+		// the original may have been deleted since, it is still the one the external ID belongs to
+		var existing ClickDAO
+		err := r.db.WithContext(ctx).Unscoped().
+			Where("project_id = ? AND external_id = ?", dao.ProjectID, dao.ExternalID).
+			First(&existing).Error
+		if err != nil {
+			tracing.RecordError(span, err)
+			return Click{}, err
+		}
+		return existing.ToDomain(), ErrDuplicate
+	}
+
 	return dao.ToDomain(), nil
 }
 
+// CreateBatch persists multiple Click entities in a single statement.
+// Entities whose ExternalID is already stored in the same project are skipped, so the same batch
+// can be safely submitted more than once. It returns the number of inserted rows.
+func (r *SQLiteRepository) CreateBatch(ctx context.Context, clicks ClickCollection) (int64, error) {
+	if len(clicks) == 0 {
+		return 0, nil
+	}
+
+	daos := make(ClickDAOCollection, 0, len(clicks))
+	for _, click := range clicks {
+		daos = append(daos, NewClickDAO(click))
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&daos)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
 // Filter applies provided filters and returns resulting subset.
 func (r *SQLiteRepository) Filter(ctx context.Context, filter Filter) (ClickCollection, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Filter")
+	defer span.End()
+
 	var clicks ClickDAOCollection
 
-	tx := r.db.WithContext(ctx)
+	tx := r.filtered(ctx, filter).Order("id")
+	if filter.AfterID > 0 {
+		tx = tx.Where("id > ?", filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+
+	result := tx.Find(&clicks)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return ClickCollection{}, result.Error
+	}
+
+	return clicks.ToDomain(), nil
+}
+
+// Count returns the number of Clicks matching provided filters.
+func (r *SQLiteRepository) Count(ctx context.Context, filter Filter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Count")
+	defer span.End()
+
+	var count int64
+	result := r.filtered(ctx, filter).Model(&ClickDAO{}).Count(&count)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+// periodFormats map periods to strftime formats truncating timestamps to the start of a period.
+var periodFormats = map[Period]string{
+	PeriodHour:  "%Y-%m-%d %H:00:00",
+	PeriodDay:   "%Y-%m-%d 00:00:00",
+	PeriodMonth: "%Y-%m-01 00:00:00",
+}
+
+// Aggregate counts Clicks matching provided filters in groups, ordered by Period and URL.
+func (r *SQLiteRepository) Aggregate(ctx context.Context, filter Filter, groupBy GroupBy) (GroupCollection, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Aggregate")
+	defer span.End()
+
+	columns := []string{"COUNT(*) AS count"}
+	var groups []string
+	if groupBy.URL {
+		columns = append(columns, "url")
+		groups = append(groups, "url")
+	}
+	loc := groupBy.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	var args []any
+	if groupBy.Period != "" {
+		format, ok := periodFormats[groupBy.Period]
+		if !ok {
+			return nil, fmt.Errorf("unsupported period %q", groupBy.Period)
+		}
+		modifier, modifierArgs, err := r.localTime(ctx, filter, loc)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("strftime('%s', created_at%s) AS period", format, modifier))
+		args = modifierArgs
+		groups = append([]string{"period"}, groups...)
+	}
+
+	tx := r.filtered(ctx, filter).Model(&ClickDAO{}).Select(strings.Join(columns, ", "), args...)
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+
+	var rows []struct {
+		URL    string
+		Period string
+		Count  int64
+	}
+	result := tx.Scan(&rows)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	collection := make(GroupCollection, 0, len(rows))
+	for _, row := range rows {
+		group := Group{URL: row.URL, Count: row.Count}
+		if row.Period != "" {
+			period, err := time.ParseInLocation(time.DateTime, row.Period, loc)
+			if err != nil {
+				return nil, err
+			}
+			group.Period = period
+		}
+		collection = append(collection, group)
+	}
+
+	return collection, nil
+}
+
+// localTime returns a strftime modifier shifting created_at from UTC into loc, along with its arguments.
+// Offsets of loc are looked up between the bounds of the filter, or between the earliest and the latest
+// Click matching it when not bounded, so daylight saving time is taken into account.
+func (r *SQLiteRepository) localTime(ctx context.Context, filter Filter, loc *time.Location) (string, []any, error) {
+	if loc == time.UTC {
+		return "", nil, nil
+	}
+
+	from, to := filter.After, filter.Before
+	if from.IsZero() || to.IsZero() {
+		var bounds struct {
+			First sql.NullInt64
+			Last  sql.NullInt64
+		}
+		err := r.filtered(ctx, filter).Model(&ClickDAO{}).
+			Select("MIN(CAST(strftime('%s', created_at) AS INTEGER)) AS first, MAX(CAST(strftime('%s', created_at) AS INTEGER)) AS last").
+			Scan(&bounds).Error
+		if err != nil {
+			return "", nil, err
+		}
+		if from.IsZero() {
+			from = time.Unix(bounds.First.Int64, 0)
+		}
+		if to.IsZero() {
+			to = time.Unix(bounds.Last.Int64+1, 0)
+		}
+	}
+
+	offsets := timeparam.Offsets(loc, from, to)
+	if len(offsets) == 1 {
+		return ", ?", []any{fmt.Sprintf("%+d seconds", offsets[0].Seconds)}, nil
+	}
+
+	var b strings.Builder
+	var args []any
+	b.WriteString(", CASE")
+	for i := len(offsets) - 1; i > 0; i-- {
+		b.WriteString(" WHEN created_at >= ? THEN ?")
+		args = append(args, offsets[i].Since.UTC(), fmt.Sprintf("%+d seconds", offsets[i].Seconds))
+	}
+	b.WriteString(" ELSE ? END")
+	args = append(args, fmt.Sprintf("%+d seconds", offsets[0].Seconds))
+
+	return b.String(), args, nil
+}
+
+// filtered starts a query limited by provided filters.
+func (r *SQLiteRepository) filtered(ctx context.Context, filter Filter) *gorm.DB {
+	tx := r.db.WithContext(ctx).Where("project_id = ?", filter.ProjectID)
 
 	if filter.URL != "" {
 		tx = tx.Where("url = ?", filter.URL)
 	}
+	tx = urlmatch.Apply(tx, filter.URLMatch)
+	tx = query.Apply(tx, filter.Query)
 	if !filter.After.IsZero() {
-		tx = tx.Where("created_at > ?", filter.After)
+		tx = tx.Where("created_at > ?", filter.After.UTC())
 	}
 	if !filter.Before.IsZero() {
-		tx = tx.Where("created_at < ?", filter.Before)
+		tx = tx.Where("created_at < ?", filter.Before.UTC())
 	}
 
-	if tx.Find(&clicks).Error != nil {
-		return ClickCollection{}, r.db.Error
+	return tx
+}
+
+// Get returns a Click of a project, or ErrNotFound.
+func (r *SQLiteRepository) Get(ctx context.Context, projectID, id uint) (Click, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Get")
+	defer span.End()
+
+	var dao ClickDAO
+	result := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&dao)
+	tracing.RecordQuery(span, result)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return Click{}, ErrNotFound
+	}
+	if result.Error != nil {
+		return Click{}, result.Error
 	}
 
-	return clicks.ToDomain(), nil
+	return dao.ToDomain(), nil
+}
+
+// Delete marks a Click of a project as deleted, or returns ErrNotFound.
+func (r *SQLiteRepository) Delete(ctx context.Context, projectID, id uint) error {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Delete")
+	defer span.End()
+
+	result := softDelete(r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id))
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Update corrects the URL and the visitor of a Click of a project which isn't deleted,
+// or returns ErrNotFound. Its ID, event ID and creation time are kept.
+func (r *SQLiteRepository) Update(ctx context.Context, c Click) (Click, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Update")
+	defer span.End()
+
+	host, path := urlmatch.Split(c.URL)
+	result := r.db.WithContext(ctx).Model(&ClickDAO{}).Where("project_id = ? AND id = ?", c.ProjectID, c.ID).
+		Updates(map[string]any{"url": c.URL, "host": host, "path": path, "visitor_id": c.VisitorID})
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return Click{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Click{}, ErrNotFound
+	}
+
+	return r.Get(ctx, c.ProjectID, c.ID)
+}
+
+// Restore clears the deletion mark of a Click of a project which isn't purged yet, or returns ErrNotFound.
+// Restoring a Click which isn't deleted returns it unchanged.
+func (r *SQLiteRepository) Restore(ctx context.Context, projectID, id uint) (Click, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Restore")
+	defer span.End()
+
+	result := r.db.WithContext(ctx).Unscoped().Model(&ClickDAO{}).
+		Where("project_id = ? AND id = ? AND deleted_at IS NOT NULL", projectID, id).Update("deleted_at", nil)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return Click{}, result.Error
+	}
+
+	return r.Get(ctx, projectID, id)
+}
+
+// DeleteMatching marks Clicks matching provided filters as deleted and returns their number.
+// Limit of the filter is ignored.
+func (r *SQLiteRepository) DeleteMatching(ctx context.Context, filter Filter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.DeleteMatching")
+	defer span.End()
+
+	tx := r.filtered(ctx, filter)
+	if filter.AfterID > 0 {
+		tx = tx.Where("id > ?", filter.AfterID)
+	}
+
+	result := softDelete(tx)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Purge permanently removes Clicks of a project which were deleted before a given time,
+// or all deleted ones when it is zero. It returns the number of removed Clicks.
+func (r *SQLiteRepository) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "click.SQLiteRepository.Purge")
+	defer span.End()
+
+	tx := r.db.WithContext(ctx).Unscoped().Where("project_id = ? AND deleted_at IS NOT NULL", projectID)
+	if !deletedBefore.IsZero() {
+		tx = tx.Where("deleted_at < ?", deletedBefore.UTC())
+	}
+
+	result := tx.Delete(&ClickDAO{})
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// softDelete marks Clicks selected by a query as deleted, unless they already are.
+// Deletion time is stored in UTC, so it can be compared as text like CreatedAt.
+func softDelete(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&ClickDAO{}).Update("deleted_at", time.Now().UTC())
+}
+
+// BackfillURLParts stores hosts and paths of Clicks stored before they were split out of URLs,
+// in batches of a given size. It returns the number of updated Clicks.
+func (r *SQLiteRepository) BackfillURLParts(ctx context.Context, batchSize int) (int64, error) {
+	var updated int64
+	var batch ClickDAOCollection
+	result := r.db.WithContext(ctx).Unscoped().Select("id", "url").Where("host = '' AND path = ''").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, dao := range batch {
+					host, path := urlmatch.Split(dao.URL)
+					if host == "" && path == "" {
+						continue
+					}
+					err := tx.Unscoped().Model(&ClickDAO{}).Where("id = ?", dao.ID).Updates(map[string]any{"host": host, "path": path}).Error
+					if err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			})
+		})
+
+	return updated, result.Error
+}
+
+// BackfillUTC converts creation times of Clicks stored with another offset than UTC, before they
+// were normalized on creation, in batches of a given size. It returns the number of updated Clicks.
+func (r *SQLiteRepository) BackfillUTC(ctx context.Context, batchSize int) (int64, error) {
+	var updated int64
+	var batch ClickDAOCollection
+	result := r.db.WithContext(ctx).Unscoped().Select("id", "created_at").Where("created_at NOT LIKE ?", "%+00:00").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, dao := range batch {
+					err := tx.Unscoped().Model(&ClickDAO{}).Where("id = ?", dao.ID).Update("created_at", dao.CreatedAt.UTC()).Error
+					if err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			})
+		})
+
+	return updated, result.Error
+}
+
+// queryFields are the fields of Clicks available in the query language, mapped to their columns.
+var queryFields = query.Fields{
+	"id":        {SQL: "id", Type: query.Int},
+	"eventId":   {SQL: "COALESCE(external_id, '')", Type: query.String},
+	"visitorId": {SQL: "visitor_id", Type: query.String},
+	"url":       {SQL: "url", Type: query.String},
+	"host":      {SQL: "host", Type: query.String},
+	"path":      {SQL: "path", Type: query.String},
+	"createdAt": {SQL: "created_at", Type: query.Time},
 }
 
 // NewSQLiteRepository is a SQLiteRepository constructor.
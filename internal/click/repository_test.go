@@ -7,6 +7,13 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracing"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -28,6 +35,55 @@ func TestCreate(t *testing.T) {
 	assert.Equal(t, click.URL, "test.url")
 }
 
+func TestCreateDuplicate(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	original, err := sqliteRepo.Create(context.Background(), Click{ProjectID: 1, ExternalID: "ext-1", URL: "test.url1"})
+	assert.NoError(t, err)
+
+	// the same external ID in another project is not a duplicate
+	_, err = sqliteRepo.Create(context.Background(), Click{ProjectID: 2, ExternalID: "ext-1", URL: "test.url1"})
+	assert.NoError(t, err)
+
+	duplicate, err := sqliteRepo.Create(context.Background(), Click{ProjectID: 1, ExternalID: "ext-1", URL: "test.url2"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Equal(t, original.ID, duplicate.ID)
+	assert.Equal(t, "test.url1", duplicate.URL)
+}
+
+func TestCreateBatch(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	clicks := ClickCollection{
+		{ExternalID: "ext-1", URL: "test.url1"},
+		{ExternalID: "ext-2", URL: "test.url2"},
+		{URL: "test.url3"},
+	}
+	inserted, err := sqliteRepo.CreateBatch(context.Background(), clicks)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), inserted)
+
+	// resubmitting known external IDs must not create duplicates
+	inserted, err = sqliteRepo.CreateBatch(context.Background(), clicks[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), inserted)
+
+	result, err := sqliteRepo.Filter(context.Background(), Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, result, 3)
+	assert.Equal(t, "ext-1", result[0].ExternalID)
+}
+
 func TestFilter(t *testing.T) {
 	gormDB := setupDatabase(t)
 	defer func() {
@@ -37,7 +93,7 @@ func TestFilter(t *testing.T) {
 	// setup test data
 	time1, _ := time.Parse(time.DateOnly, "2024-01-02")
 	time2, _ := time.Parse(time.DateOnly, "2024-04-02")
-	clicks := ClickCollection{
+	clicks := ClickDAOCollection{
 		{
 			URL:       "test.url1",
 			CreatedAt: time1,
@@ -46,6 +102,11 @@ func TestFilter(t *testing.T) {
 			URL:       "test.url2",
 			CreatedAt: time2,
 		},
+		{
+			ProjectID: 2,
+			URL:       "test.url1",
+			CreatedAt: time1,
+		},
 	}
 	gormDB.Create(clicks)
 	assert.NoError(t, gormDB.Error)
@@ -92,6 +153,37 @@ func TestFilter(t *testing.T) {
 			},
 			err: nil,
 		},
+		{
+			testName: "page",
+			param:    Filter{AfterID: 1, Limit: 1},
+			expectedResult: ClickCollection{
+				{
+					ID:        2,
+					URL:       "test.url2",
+					CreatedAt: time2,
+				},
+			},
+			err: nil,
+		},
+		{
+			testName:       "page past the end",
+			param:          Filter{AfterID: 2, Limit: 1},
+			expectedResult: ClickCollection{},
+			err:            nil,
+		},
+		{
+			testName: "filter by project",
+			param:    Filter{ProjectID: 2},
+			expectedResult: ClickCollection{
+				{
+					ID:        3,
+					ProjectID: 2,
+					URL:       "test.url1",
+					CreatedAt: time1,
+				},
+			},
+			err: nil,
+		},
 	}
 
 	for _, test := range tests {
@@ -110,12 +202,499 @@ func TestFilter(t *testing.T) {
 	}
 }
 
+func TestFilterURLMatch(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{URL: "https://example.com/blog/first"},
+		{URL: "https://Docs.Example.com/guide?page=2"},
+		{URL: "https://example.org/blog/second"},
+		{URL: "/pricing"},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		match    urlmatch.Match
+		expected []uint
+	}{
+		"any of URLs":   {urlmatch.Match{URLs: []string{"/pricing", "https://example.org/blog/second"}}, []uint{3, 4}},
+		"none of URLs":  {urlmatch.Match{NotURLs: []string{"/pricing", "https://example.org/blog/second"}}, []uint{1, 2}},
+		"prefix":        {urlmatch.Match{Prefix: "https://example."}, []uint{1, 3}},
+		"glob":          {urlmatch.Match{Glob: "*/blog/*"}, []uint{1, 3}},
+		"host":          {urlmatch.Match{Host: "DOCS.example.com"}, []uint{2}},
+		"domain":        {urlmatch.Match{Domain: "example.com"}, []uint{1, 2}},
+		"path":          {urlmatch.Match{Path: "/pricing"}, []uint{4}},
+		"all must hold": {urlmatch.Match{Glob: "*/blog/*", Domain: "example.com"}, []uint{1}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			clicks, err := sqliteRepo.Filter(context.Background(), Filter{URLMatch: test.match})
+			assert.NoError(t, err)
+
+			var ids []uint
+			for _, c := range clicks {
+				ids = append(ids, c.ID)
+			}
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
+
+func TestFilterQuery(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{VisitorID: "v1", URL: "https://example.com/blog/a"},
+		{ExternalID: "e2", URL: "https://example.com/blog/b"},
+		{VisitorID: "v1", URL: "https://example.com/pricing"},
+		{VisitorID: "v3", URL: "https://example.com/blog/c", CreatedAt: time.Now().Add(-48 * time.Hour)},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		query    string
+		expected []uint
+	}{
+		"fields":          {`url ~ "/blog/" and (visitorId = "v1" or eventId = "e2")`, []uint{1, 2}},
+		"host and path":   {`host = "example.com" and path != "/pricing" and not id = 1`, []uint{2, 4}},
+		"relative time":   {`createdAt < now-1d or id <= 1`, []uint{1, 4}},
+		"missing eventId": {`eventId = ""`, []uint{1, 3, 4}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			condition, err := query.ParseAndCompile(test.query, queryFields, time.Now())
+			assert.NoError(t, err)
+
+			clicks, err := sqliteRepo.Filter(context.Background(), Filter{Query: condition})
+			assert.NoError(t, err)
+
+			var ids []uint
+			for _, e := range clicks {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
+
+func TestBackfillURLParts(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	// Clicks stored before hosts and paths were split out of URLs
+	gormDB.Create(ClickDAOCollection{
+		{URL: "https://example.com/blog"},
+		{URL: "https://example.com"},
+		{URL: ""},
+	})
+	assert.NoError(t, gormDB.Error)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	updated, err := sqliteRepo.BackfillURLParts(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), updated)
+
+	clicks, err := sqliteRepo.Filter(context.Background(), Filter{URLMatch: urlmatch.Match{Host: "example.com", Path: "/"}})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 1)
+	assert.Equal(t, uint(2), clicks[0].ID)
+
+	updated, err = sqliteRepo.BackfillURLParts(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updated)
+}
+
+func TestBackfillUTC(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	// Clicks stored before creation times were normalized to UTC
+	createdAt := time.Date(2024, 1, 2, 3, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	gormDB.Create(ClickDAOCollection{
+		{URL: "test.url1", CreatedAt: createdAt},
+		{URL: "test.url2", CreatedAt: createdAt.UTC()},
+	})
+	assert.NoError(t, gormDB.Error)
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	updated, err := sqliteRepo.BackfillUTC(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+
+	var stored []string
+	assert.NoError(t, gormDB.Model(&ClickDAO{}).Order("id").Pluck("CAST(created_at AS TEXT)", &stored).Error)
+	assert.Equal(t, []string{"2024-01-02 01:00:00+00:00", "2024-01-02 01:00:00+00:00"}, stored)
+
+	updated, err = sqliteRepo.BackfillUTC(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updated)
+}
+
+func TestDelete(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, ExternalID: "ext-1", URL: "test.url1"},
+		{ProjectID: 1, URL: "test.url2"},
+		{ProjectID: 2, URL: "test.url1"},
+	})
+	assert.NoError(t, err)
+
+	click, err := sqliteRepo.Get(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "test.url1", click.URL)
+
+	// events of other projects can't be seen or deleted
+	_, err = sqliteRepo.Get(context.Background(), 1, 3)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, sqliteRepo.Delete(context.Background(), 1, 3), ErrNotFound)
+
+	assert.NoError(t, sqliteRepo.Delete(context.Background(), 1, 1))
+	assert.ErrorIs(t, sqliteRepo.Delete(context.Background(), 1, 1), ErrNotFound)
+	_, err = sqliteRepo.Get(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	clicks, err := sqliteRepo.Filter(context.Background(), Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 1)
+	count, err := sqliteRepo.Count(context.Background(), Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	groups, err := sqliteRepo.Aggregate(context.Background(), Filter{ProjectID: 1}, GroupBy{})
+	assert.NoError(t, err)
+	assert.Equal(t, GroupCollection{{Count: 1}}, groups)
+
+	// resubmitting the external ID of a deleted event doesn't bring it back
+	duplicate, err := sqliteRepo.Create(context.Background(), Click{ProjectID: 1, ExternalID: "ext-1", URL: "test.url1"})
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Equal(t, uint(1), duplicate.ID)
+	_, err = sqliteRepo.Get(context.Background(), 1, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// deleting is undone until the event is purged
+	_, err = sqliteRepo.Restore(context.Background(), 2, 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+	restored, err := sqliteRepo.Restore(context.Background(), 1, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ext-1", restored.ExternalID)
+	}
+	restored, err = sqliteRepo.Restore(context.Background(), 1, 1)
+	assert.NoError(t, err, "restoring an event which isn't deleted")
+	assert.Equal(t, uint(1), restored.ID)
+
+	purged, err := sqliteRepo.Purge(context.Background(), 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), purged, "restored events are not purged")
+}
+
+func TestUpdate(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	created, err := sqliteRepo.Create(context.Background(), Click{ProjectID: 1, ExternalID: "ext-1", URL: "https://example.com/a"})
+	assert.NoError(t, err)
+
+	created.URL = "https://staging.example.com/b"
+	created.VisitorID = "v1"
+	updated, err := sqliteRepo.Update(context.Background(), created)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "https://staging.example.com/b", updated.URL)
+		assert.Equal(t, "v1", updated.VisitorID)
+		assert.Equal(t, "ext-1", updated.ExternalID)
+		assert.True(t, created.CreatedAt.Equal(updated.CreatedAt))
+	}
+
+	// hosts and paths follow the URL
+	clicks, err := sqliteRepo.Filter(context.Background(), Filter{ProjectID: 1, URLMatch: urlmatch.Match{Host: "staging.example.com", Path: "/b"}})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 1)
+
+	// events of other projects and deleted events can't be changed
+	_, err = sqliteRepo.Update(context.Background(), Click{ID: created.ID, ProjectID: 2, URL: "test.url1"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, sqliteRepo.Delete(context.Background(), 1, created.ID))
+	_, err = sqliteRepo.Update(context.Background(), created)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDeleteMatching(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, URL: "https://staging.example.com/a"},
+		{ProjectID: 1, URL: "https://example.com/a"},
+		{ProjectID: 1, URL: "https://staging.example.com/b"},
+		{ProjectID: 2, URL: "https://staging.example.com/a"},
+	})
+	assert.NoError(t, err)
+
+	filter := Filter{ProjectID: 1, URLMatch: urlmatch.Match{Host: "staging.example.com"}, Limit: 1}
+	deleted, err := sqliteRepo.DeleteMatching(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted, "limit is ignored")
+
+	// deleted events are not deleted again
+	deleted, err = sqliteRepo.DeleteMatching(context.Background(), filter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+
+	clicks, err := sqliteRepo.Filter(context.Background(), Filter{})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 0)
+	clicks, err = sqliteRepo.Filter(context.Background(), Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, clicks, 1) {
+		assert.Equal(t, uint(2), clicks[0].ID)
+	}
+	clicks, err = sqliteRepo.Filter(context.Background(), Filter{ProjectID: 2})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 1)
+}
+
+func TestPurge(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, URL: "test.url1"},
+		{ProjectID: 1, URL: "test.url2"},
+		{ProjectID: 1, URL: "test.url3"},
+		{ProjectID: 2, URL: "test.url1"},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, sqliteRepo.Delete(context.Background(), 1, 1))
+	assert.NoError(t, sqliteRepo.Delete(context.Background(), 2, 4))
+	deletedBefore := time.Now()
+	// deletion times are stored with a precision the comparison can tell apart
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, sqliteRepo.Delete(context.Background(), 1, 2))
+
+	purged, err := sqliteRepo.Purge(context.Background(), 1, deletedBefore)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	purged, err = sqliteRepo.Purge(context.Background(), 1, time.Time{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	var remaining []uint
+	assert.NoError(t, gormDB.Unscoped().Model(&ClickDAO{}).Order("id").Pluck("id", &remaining).Error)
+	assert.Equal(t, []uint{3, 4}, remaining, "events which are not deleted and other projects are kept")
+}
+
+func TestCount(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	now := time.Now()
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, URL: "test.url1", CreatedAt: now.Add(-2 * time.Hour)},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: now.Add(-time.Minute)},
+		{ProjectID: 1, URL: "test.url2", CreatedAt: now.Add(-time.Minute)},
+		{ProjectID: 2, URL: "test.url1", CreatedAt: now.Add(-time.Minute)},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		filter   Filter
+		expected int64
+	}{
+		"project":         {filter: Filter{ProjectID: 1}, expected: 3},
+		"url":             {filter: Filter{ProjectID: 1, URL: "test.url1"}, expected: 2},
+		"url within hour": {filter: Filter{ProjectID: 1, URL: "test.url1", After: now.Add(-time.Hour)}, expected: 1},
+		"no match":        {filter: Filter{ProjectID: 3}, expected: 0},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			count, err := sqliteRepo.Count(context.Background(), test.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, count)
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	day1, _ := time.Parse(time.RFC3339, "2024-01-02T10:15:00Z")
+	day2, _ := time.Parse(time.RFC3339, "2024-01-03T01:30:00+02:00")
+	_, err := sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day1},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day1.Add(time.Minute)},
+		{ProjectID: 1, URL: "test.url2", CreatedAt: day1.Add(time.Hour)},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day2},
+		{ProjectID: 2, URL: "test.url1", CreatedAt: day1},
+	})
+	assert.NoError(t, err)
+
+	date := func(value string) time.Time {
+		t, _ := time.Parse(time.DateTime, value)
+		return t
+	}
+
+	tests := map[string]struct {
+		groupBy  GroupBy
+		expected GroupCollection
+	}{
+		"total": {
+			expected: GroupCollection{{Count: 4}},
+		},
+		"url": {
+			groupBy:  GroupBy{URL: true},
+			expected: GroupCollection{{URL: "test.url1", Count: 3}, {URL: "test.url2", Count: 1}},
+		},
+		"day in UTC": {
+			groupBy:  GroupBy{Period: PeriodDay},
+			expected: GroupCollection{{Period: date("2024-01-02 00:00:00"), Count: 4}},
+		},
+		"hour and url": {
+			groupBy: GroupBy{URL: true, Period: PeriodHour},
+			expected: GroupCollection{
+				{URL: "test.url1", Period: date("2024-01-02 10:00:00"), Count: 2},
+				{URL: "test.url2", Period: date("2024-01-02 11:00:00"), Count: 1},
+				{URL: "test.url1", Period: date("2024-01-02 23:00:00"), Count: 1},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			groups, err := sqliteRepo.Aggregate(context.Background(), Filter{ProjectID: 1}, test.groupBy)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, groups)
+		})
+	}
+
+	_, err = sqliteRepo.Aggregate(context.Background(), Filter{ProjectID: 1}, GroupBy{Period: "week"})
+	assert.Error(t, err)
+}
+
+func TestAggregateLocation(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	assert.NoError(t, err)
+
+	// daylight saving time starts in Zagreb on 2024-03-31, moving midnight from 23:00 to 22:00 UTC
+	_, err = sqliteRepo.CreateBatch(context.Background(), ClickCollection{
+		{ProjectID: 1, URL: "test.url", CreatedAt: time.Date(2024, 3, 30, 22, 30, 0, 0, time.UTC)},
+		{ProjectID: 1, URL: "test.url", CreatedAt: time.Date(2024, 3, 30, 23, 30, 0, 0, time.UTC)},
+		{ProjectID: 1, URL: "test.url", CreatedAt: time.Date(2024, 3, 31, 21, 30, 0, 0, time.UTC)},
+		{ProjectID: 1, URL: "test.url", CreatedAt: time.Date(2024, 3, 31, 22, 30, 0, 0, time.UTC)},
+	})
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		filter   Filter
+		expected map[string]int64
+	}{
+		"unbounded": {
+			filter: Filter{ProjectID: 1},
+			expected: map[string]int64{
+				"2024-03-30T00:00:00+01:00": 1,
+				"2024-03-31T00:00:00+01:00": 2,
+				"2024-04-01T00:00:00+02:00": 1,
+			},
+		},
+		"bounded": {
+			filter: Filter{ProjectID: 1, After: time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), Before: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+			expected: map[string]int64{
+				"2024-03-31T00:00:00+01:00": 2,
+				"2024-04-01T00:00:00+02:00": 1,
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			groups, err := sqliteRepo.Aggregate(context.Background(), test.filter, GroupBy{Period: PeriodDay, Location: zagreb})
+			assert.NoError(t, err)
+
+			actual := make(map[string]int64)
+			for _, group := range groups {
+				actual[group.Period.Format(time.RFC3339)] = group.Count
+			}
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestTracing(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(tracing.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	_, err := sqliteRepo.Create(context.Background(), Click{ProjectID: 1, URL: "test.url1"})
+	assert.NoError(t, err)
+	_, err = sqliteRepo.Filter(context.Background(), Filter{ProjectID: 1})
+	assert.NoError(t, err)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+
+	assert.Equal(t, "click.SQLiteRepository.Create", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int64("db.rows_affected", 1))
+
+	assert.Equal(t, "click.SQLiteRepository.Filter", spans[1].Name())
+	assert.Contains(t, spans[1].Attributes(), attribute.String("db.statement", "SELECT * FROM `clicks` WHERE project_id = ? AND `clicks`.`deleted_at` IS NULL ORDER BY id"))
+	assert.Contains(t, spans[1].Attributes(), attribute.Int64("db.rows_affected", 1))
+}
+
 func setupDatabase(t *testing.T) *gorm.DB {
 	t.Helper()
 
 	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
 	assert.NoError(t, err)
-	gormDB.AutoMigrate(&Click{})
+	gormDB.AutoMigrate(&ClickDAO{})
+	gormDB.Use(tracing.Plugin{})
 
 	return gormDB
 }
@@ -0,0 +1,145 @@
+// dedup package detects repeated events, such as accidental double clicks,
+// submitted by the same visitor within a short time window.
+package dedup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxEntries bounds memory used by a Window. When reached, expired entries are
+// removed and, if that's not enough, the whole Window is cleared.
+const maxEntries = 100_000
+
+type entry[T any] struct {
+	value   T
+	expires time.Time
+	// reserved is closed once a reserved entry is stored or released, it's nil for stored entries.
+	reserved chan struct{}
+}
+
+// Window remembers values for a limited time.
+type Window[T any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]entry[T]
+	now     func() time.Time
+}
+
+// Get returns the value stored under key, if it hasn't expired yet.
+func (w *Window[T]) Get(key string) (T, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	e, ok := w.entries[key]
+	if !ok || e.reserved != nil || !w.now().Before(e.expires) {
+		var zero T
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Reserve returns the value stored under key, if it hasn't expired yet. Otherwise it claims the key
+// for the caller, which must then either Put a value under it or Release it. Concurrent callers
+// of a claimed key wait until that happens, so only one of them claims it.
+func (w *Window[T]) Reserve(ctx context.Context, key string) (T, bool, error) {
+	var zero T
+	for {
+		w.mu.Lock()
+		now := w.now()
+		e, ok := w.entries[key]
+		if ok && e.reserved != nil {
+			w.mu.Unlock()
+			select {
+			case <-e.reserved:
+				continue
+			case <-ctx.Done():
+				return zero, false, ctx.Err()
+			}
+		}
+		if ok && now.Before(e.expires) {
+			w.mu.Unlock()
+			return e.value, true, nil
+		}
+
+		w.store(key, entry[T]{expires: now.Add(w.ttl), reserved: make(chan struct{})}, now)
+		w.mu.Unlock()
+		return zero, false, nil
+	}
+}
+
+// Put stores value under key for the duration of the window.
+func (w *Window[T]) Put(key string, value T) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.now()
+	w.store(key, entry[T]{value: value, expires: now.Add(w.ttl)}, now)
+}
+
+// Release gives up a key claimed with Reserve without storing a value.
+func (w *Window[T]) Release(key string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if e, ok := w.entries[key]; ok && e.reserved != nil {
+		close(e.reserved)
+		delete(w.entries, key)
+	}
+}
+
+// store replaces the entry under key, waking up callers waiting for the replaced one.
+func (w *Window[T]) store(key string, e entry[T], now time.Time) {
+	if old, ok := w.entries[key]; ok && old.reserved != nil {
+		close(old.reserved)
+		delete(w.entries, key)
+	}
+
+	if len(w.entries) >= maxEntries {
+		for k, old := range w.entries {
+			if !now.Before(old.expires) {
+				w.evict(k, old)
+			}
+		}
+		if len(w.entries) >= maxEntries {
+			for k, old := range w.entries {
+				w.evict(k, old)
+			}
+		}
+	}
+
+	w.entries[key] = e
+}
+
+func (w *Window[T]) evict(key string, e entry[T]) {
+	if e.reserved != nil {
+		close(e.reserved)
+	}
+	delete(w.entries, key)
+}
+
+// NewWindow is a Window constructor.
+func NewWindow[T any](ttl time.Duration) *Window[T] {
+	return &Window[T]{
+		ttl:     ttl,
+		entries: make(map[string]entry[T]),
+		now:     time.Now,
+	}
+}
+
+// Visitor returns an anonymous identifier of the client which sent the request,
+// derived from its IP address and user agent.
+func Visitor(r *http.Request, ip string) string {
+	return AnonymousVisitor(ip, r.UserAgent())
+}
+
+// AnonymousVisitor returns an anonymous identifier of a client with a given IP address and user agent.
+func AnonymousVisitor(ip, userAgent string) string {
+	sum := sha256.Sum256([]byte(ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,80 @@
+package dedup
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	now := time.Now()
+	w := NewWindow[int](time.Second)
+	w.now = func() time.Time { return now }
+
+	_, ok := w.Get("key")
+	assert.False(t, ok)
+
+	w.Put("key", 1)
+	value, ok := w.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	now = now.Add(time.Second)
+	_, ok = w.Get("key")
+	assert.False(t, ok)
+}
+
+func TestWindowReserve(t *testing.T) {
+	ctx := context.Background()
+	w := NewWindow[int](time.Minute)
+
+	_, ok, err := w.Reserve(ctx, "key")
+	assert.NoError(t, err)
+	assert.False(t, ok, "the first caller claims the key")
+
+	_, ok = w.Get("key")
+	assert.False(t, ok, "claimed key has no value")
+
+	type reserved struct {
+		value int
+		ok    bool
+	}
+	waiting := make(chan reserved)
+	go func() {
+		value, ok, _ := w.Reserve(ctx, "key")
+		waiting <- reserved{value: value, ok: ok}
+	}()
+
+	w.Put("key", 1)
+	assert.Equal(t, reserved{value: 1, ok: true}, <-waiting, "concurrent caller gets the stored value")
+
+	_, ok, err = w.Reserve(ctx, "other")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	go func() {
+		value, ok, _ := w.Reserve(ctx, "other")
+		waiting <- reserved{value: value, ok: ok}
+	}()
+	w.Release("other")
+	assert.Equal(t, reserved{}, <-waiting, "concurrent caller claims the released key")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _, err = w.Reserve(cancelled, "other")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestVisitor(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("User-Agent", "agent-1")
+
+	visitor := Visitor(req, "10.0.0.1")
+	assert.Equal(t, visitor, Visitor(req, "10.0.0.1"))
+	assert.NotEqual(t, visitor, Visitor(req, "10.0.0.2"))
+
+	req.Header.Set("User-Agent", "agent-2")
+	assert.NotEqual(t, visitor, Visitor(req, "10.0.0.1"))
+}
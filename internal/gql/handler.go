@@ -0,0 +1,75 @@
+// gql package implements GraphQL API, which lets clients query clicks, views
+// and their aggregates in a single request, in the shape they need.
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// Schema is the GraphQL schema served by Handler.
+//
+//go:embed schema.graphql
+var Schema string
+
+// MaxDepth limits nesting of queries.
+const MaxDepth = 10
+
+type projectIDKey struct{}
+
+// ProjectID returns ID of the project whose events are queried.
+func ProjectID(ctx context.Context) uint {
+	id, _ := ctx.Value(projectIDKey{}).(uint)
+	return id
+}
+
+// RequestDTO represents GraphQL request, sent either as JSON body or query parameters.
+type RequestDTO struct {
+	Query         string         `json:"query" query:"query"`
+	OperationName string         `json:"operationName" query:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// Handler serves GraphQL requests.
+type Handler struct {
+	schema *graphql.Schema
+}
+
+// Query implements handler for GraphQL HTTP request. Errors are reported in the response body,
+// along with partial data, as GraphQL clients expect.
+func (h *Handler) Query(c echo.Context) error {
+	var requestDTO RequestDTO
+	if err := c.Bind(&requestDTO); err != nil {
+		return err
+	}
+	if c.Request().Method == http.MethodGet {
+		if variables := c.QueryParam("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &requestDTO.Variables); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, "variables must be a JSON object")
+			}
+		}
+	}
+	if requestDTO.Query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "query is required")
+	}
+
+	ctx := context.WithValue(c.Request().Context(), projectIDKey{}, project.ID(c))
+	response := h.schema.Exec(ctx, requestDTO.Query, requestDTO.OperationName, requestDTO.Variables)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// NewHandler is a Handler constructor. It panics if the schema doesn't match the resolvers.
+func NewHandler(clickRepository click.Repository, viewRepository view.Repository) Handler {
+	return Handler{
+		schema: graphql.MustParseSchema(Schema, NewResolver(clickRepository, viewRepository), graphql.MaxDepth(MaxDepth)),
+	}
+}
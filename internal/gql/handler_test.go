@@ -0,0 +1,237 @@
+package gql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func TestQueryClicks(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	createdAt, _ := time.Parse(time.RFC3339, "2024-01-02T03:04:05Z")
+	clickRepository := click.NewSQLiteRepository(gormDB)
+	_, err := clickRepository.CreateBatch(context.Background(), click.ClickCollection{
+		{ProjectID: 1, ExternalID: "e1", VisitorID: "visitor1", URL: "test.url1", CreatedAt: createdAt},
+		{ProjectID: 1, URL: "test.url2", CreatedAt: createdAt},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: createdAt.Add(time.Hour)},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: createdAt.Add(2 * time.Hour)},
+		{ProjectID: 2, URL: "test.url1", CreatedAt: createdAt},
+	})
+	assert.NoError(t, err)
+
+	h := NewHandler(clickRepository, view.NewSQLiteRepository(gormDB))
+	query := `query($after: String) {
+		clicks(filter: {url: "test.url1", createdAt: {before: "2024-01-02T06:00:00Z"}}, first: 2, after: $after) {
+			edges { node { id eventId visitorId url createdAt } }
+			pageInfo { hasNextPage endCursor }
+			totalCount
+		}
+	}`
+
+	type page struct {
+		Clicks struct {
+			Edges []struct {
+				Node struct {
+					ID        string  `json:"id"`
+					EventID   *string `json:"eventId"`
+					VisitorID string  `json:"visitorId"`
+					URL       string  `json:"url"`
+					CreatedAt string  `json:"createdAt"`
+				} `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool    `json:"hasNextPage"`
+				EndCursor   *string `json:"endCursor"`
+			} `json:"pageInfo"`
+			TotalCount int `json:"totalCount"`
+		} `json:"clicks"`
+	}
+
+	var first page
+	resp := execute(t, h, query, map[string]any{})
+	assert.Empty(t, resp.Errors)
+	assert.NoError(t, json.Unmarshal(resp.Data, &first))
+	assert.Equal(t, 3, first.Clicks.TotalCount)
+	assert.True(t, first.Clicks.PageInfo.HasNextPage)
+	if assert.Len(t, first.Clicks.Edges, 2) {
+		assert.Equal(t, "1", first.Clicks.Edges[0].Node.ID)
+		assert.Equal(t, "e1", *first.Clicks.Edges[0].Node.EventID)
+		assert.Equal(t, "visitor1", first.Clicks.Edges[0].Node.VisitorID)
+		assert.Equal(t, "2024-01-02T03:04:05Z", first.Clicks.Edges[0].Node.CreatedAt)
+		assert.Equal(t, "3", first.Clicks.Edges[1].Node.ID)
+		assert.Nil(t, first.Clicks.Edges[1].Node.EventID)
+		assert.Empty(t, first.Clicks.Edges[1].Node.VisitorID)
+	}
+
+	var second page
+	resp = execute(t, h, query, map[string]any{"after": *first.Clicks.PageInfo.EndCursor})
+	assert.Empty(t, resp.Errors)
+	assert.NoError(t, json.Unmarshal(resp.Data, &second))
+	assert.False(t, second.Clicks.PageInfo.HasNextPage)
+	if assert.Len(t, second.Clicks.Edges, 1) {
+		assert.Equal(t, "4", second.Clicks.Edges[0].Node.ID)
+	}
+
+	resp = execute(t, h, query, map[string]any{"after": "nonsense"})
+	if assert.Len(t, resp.Errors, 1) {
+		assert.Equal(t, "invalid cursor", resp.Errors[0].Message)
+	}
+}
+
+func TestQueryAggregate(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	day1, _ := time.Parse(time.RFC3339, "2024-01-02T03:04:05Z")
+	day2 := day1.Add(24 * time.Hour)
+	clickRepository := click.NewSQLiteRepository(gormDB)
+	viewRepository := view.NewSQLiteRepository(gormDB)
+	_, err := clickRepository.CreateBatch(context.Background(), click.ClickCollection{
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day1},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day2},
+	})
+	assert.NoError(t, err)
+	_, err = viewRepository.CreateBatch(context.Background(), view.ViewCollection{
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day1},
+		{ProjectID: 1, URL: "test.url1", CreatedAt: day1},
+		{ProjectID: 1, URL: "test.url2", CreatedAt: day1},
+	})
+	assert.NoError(t, err)
+
+	h := NewHandler(clickRepository, viewRepository)
+
+	tests := []struct {
+		testName string
+		query    string
+		expected string
+	}{
+		{
+			testName: "totals",
+			query:    `{ aggregate { url period clicks views } }`,
+			expected: `{"aggregate":[{"url":null,"period":null,"clicks":2,"views":3}]}`,
+		},
+		{
+			testName: "by day and url",
+			query:    `{ aggregate(groupBy: [DAY, URL]) { url period clicks views } }`,
+			expected: `{"aggregate":[` +
+				`{"url":"test.url1","period":"2024-01-02T00:00:00Z","clicks":1,"views":2},` +
+				`{"url":"test.url2","period":"2024-01-02T00:00:00Z","clicks":0,"views":1},` +
+				`{"url":"test.url1","period":"2024-01-03T00:00:00Z","clicks":1,"views":0}]}`,
+		},
+		{
+			testName: "by day in time zone",
+			query:    `{ aggregate(groupBy: [DAY], tz: "America/Los_Angeles") { period clicks views } }`,
+			expected: `{"aggregate":[` +
+				`{"period":"2024-01-01T00:00:00-08:00","clicks":1,"views":3},` +
+				`{"period":"2024-01-02T00:00:00-08:00","clicks":1,"views":0}]}`,
+		},
+		{
+			testName: "filtered",
+			query:    `{ aggregate(filter: {url: "test.url2"}, groupBy: [URL]) { url clicks views } }`,
+			expected: `{"aggregate":[{"url":"test.url2","clicks":0,"views":1}]}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			resp := execute(t, h, test.query, nil)
+			assert.Empty(t, resp.Errors)
+			assert.JSONEq(t, test.expected, string(resp.Data))
+		})
+	}
+
+	resp := execute(t, h, `{ aggregate(groupBy: [DAY, HOUR]) { clicks } }`, nil)
+	if assert.Len(t, resp.Errors, 1) {
+		assert.Equal(t, "groupBy accepts at most one time bucket", resp.Errors[0].Message)
+	}
+
+	resp = execute(t, h, `{ aggregate(groupBy: [DAY], tz: "Nowhere") { clicks } }`, nil)
+	if assert.Len(t, resp.Errors, 1) {
+		assert.Equal(t, `unknown time zone "Nowhere"`, resp.Errors[0].Message)
+	}
+}
+
+func TestHandlerGet(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	h := NewHandler(click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB))
+
+	e := echo.New()
+	query := url.Values{
+		"query":     {`query($url: String) { views(filter: {url: $url}) { totalCount } }`},
+		"variables": {`{"url": "test.url1"}`},
+	}
+	req := httptest.NewRequest(http.MethodGet, "/graphql?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+
+	assert.NoError(t, h.Query(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"data":{"views":{"totalCount":0}}}`, rec.Body.String())
+}
+
+func execute(t *testing.T, h Handler, query string, variables map[string]any) response {
+	t.Helper()
+
+	body, _ := json.Marshal(RequestDTO{Query: query, Variables: variables})
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+
+	assert.NoError(t, h.Query(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var resp response
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&click.ClickDAO{}, &view.ViewDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,460 @@
+package gql
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// MaxPageSize is the largest number of edges a connection returns at once.
+const MaxPageSize = 1000
+
+// EventFilterInput represents EventFilter GraphQL input.
+type EventFilterInput struct {
+	URL       *string
+	CreatedAt *TimeRangeInput
+}
+
+// TimeRangeInput represents TimeRange GraphQL input.
+type TimeRangeInput struct {
+	After  *graphql.Time
+	Before *graphql.Time
+}
+
+// bounds returns the time range as Filter bounds, zero when not set.
+func (f *EventFilterInput) bounds() (after, before time.Time) {
+	if f == nil || f.CreatedAt == nil {
+		return time.Time{}, time.Time{}
+	}
+	if f.CreatedAt.After != nil {
+		after = f.CreatedAt.After.Time
+	}
+	if f.CreatedAt.Before != nil {
+		before = f.CreatedAt.Before.Time
+	}
+	return after, before
+}
+
+func (f *EventFilterInput) url() string {
+	if f == nil || f.URL == nil {
+		return ""
+	}
+	return *f.URL
+}
+
+// ToClickFilter maps GraphQL input into click.Filter of a given project.
+func (f *EventFilterInput) ToClickFilter(projectID uint) click.Filter {
+	after, before := f.bounds()
+	return click.Filter{ProjectID: projectID, URL: f.url(), After: after, Before: before}
+}
+
+// ToViewFilter maps GraphQL input into view.Filter of a given project.
+func (f *EventFilterInput) ToViewFilter(projectID uint) view.Filter {
+	after, before := f.bounds()
+	return view.Filter{ProjectID: projectID, URL: f.url(), After: after, Before: before}
+}
+
+// ConnectionArgs holds pagination arguments of a connection field.
+type ConnectionArgs struct {
+	Filter *EventFilterInput
+	First  int32
+	After  *string
+}
+
+// page validates pagination arguments and returns the ID to start after and the page size.
+func (a ConnectionArgs) page() (uint, int, error) {
+	if a.First < 0 || a.First > MaxPageSize {
+		return 0, 0, errors.New("first must be between 0 and " + strconv.Itoa(MaxPageSize))
+	}
+	if a.After == nil {
+		return 0, int(a.First), nil
+	}
+
+	id, err := decodeCursor(*a.After)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, int(a.First), nil
+}
+
+// Resolver is the root resolver of the GraphQL schema.
+type Resolver struct {
+	clickRepository click.Repository
+	viewRepository  view.Repository
+}
+
+// Clicks resolves clicks query.
+func (r *Resolver) Clicks(ctx context.Context, args ConnectionArgs) (*ClickConnectionResolver, error) {
+	afterID, first, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := args.Filter.ToClickFilter(ProjectID(ctx))
+	page := filter
+	page.AfterID = afterID
+	// one more than requested tells whether there is a next page
+	page.Limit = first + 1
+
+	clicks, err := r.clickRepository.Filter(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &ClickConnectionResolver{repository: r.clickRepository, filter: filter}
+	if len(clicks) > first {
+		clicks = clicks[:first]
+		connection.hasNextPage = true
+	}
+	connection.clicks = clicks
+
+	return connection, nil
+}
+
+// Views resolves views query.
+func (r *Resolver) Views(ctx context.Context, args ConnectionArgs) (*ViewConnectionResolver, error) {
+	afterID, first, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	filter := args.Filter.ToViewFilter(ProjectID(ctx))
+	page := filter
+	page.AfterID = afterID
+	// one more than requested tells whether there is a next page
+	page.Limit = first + 1
+
+	views, err := r.viewRepository.Filter(ctx, page)
+	if err != nil {
+		return nil, err
+	}
+
+	connection := &ViewConnectionResolver{repository: r.viewRepository, filter: filter}
+	if len(views) > first {
+		views = views[:first]
+		connection.hasNextPage = true
+	}
+	connection.views = views
+
+	return connection, nil
+}
+
+// Aggregate resolves aggregate query. Clicks and Views falling into the same group are joined into a single one.
+func (r *Resolver) Aggregate(ctx context.Context, args struct {
+	Filter  *EventFilterInput
+	GroupBy []string
+	Tz      string
+}) ([]*GroupResolver, error) {
+	loc, err := timeparam.Location(args.Tz)
+	if err != nil {
+		return nil, err
+	}
+
+	var byURL bool
+	var period string
+	for _, g := range args.GroupBy {
+		switch {
+		case g == "URL":
+			byURL = true
+		case period != "" && period != g:
+			return nil, errors.New("groupBy accepts at most one time bucket")
+		default:
+			period = g
+		}
+	}
+
+	projectID := ProjectID(ctx)
+	clickGroups, err := r.clickRepository.Aggregate(ctx, args.Filter.ToClickFilter(projectID), click.GroupBy{URL: byURL, Period: click.Period(periods[period]), Location: loc})
+	if err != nil {
+		return nil, err
+	}
+	viewGroups, err := r.viewRepository.Aggregate(ctx, args.Filter.ToViewFilter(projectID), view.GroupBy{URL: byURL, Period: view.Period(periods[period]), Location: loc})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		url    string
+		period time.Time
+	}
+	groups := make(map[key]*GroupResolver)
+	group := func(url string, start time.Time) *GroupResolver {
+		k := key{url: url, period: start}
+		if groups[k] == nil {
+			groups[k] = &GroupResolver{url: url, period: start, byURL: byURL, byPeriod: period != ""}
+		}
+		return groups[k]
+	}
+	for _, g := range clickGroups {
+		group(g.URL, g.Period).clicks += g.Count
+	}
+	for _, g := range viewGroups {
+		group(g.URL, g.Period).views += g.Count
+	}
+
+	result := make([]*GroupResolver, 0, len(groups))
+	for _, g := range groups {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].period.Equal(result[j].period) {
+			return result[i].period.Before(result[j].period)
+		}
+		return result[i].url < result[j].url
+	})
+
+	return result, nil
+}
+
+// periods maps GroupBy enum values to repository periods.
+var periods = map[string]string{
+	"HOUR":  string(click.PeriodHour),
+	"DAY":   string(click.PeriodDay),
+	"MONTH": string(click.PeriodMonth),
+}
+
+// NewResolver is a Resolver constructor.
+func NewResolver(clickRepository click.Repository, viewRepository view.Repository) *Resolver {
+	return &Resolver{
+		clickRepository: clickRepository,
+		viewRepository:  viewRepository,
+	}
+}
+
+// PageInfoResolver resolves PageInfo type.
+type PageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+// HasNextPage resolves PageInfo.hasNextPage field.
+func (r PageInfoResolver) HasNextPage() bool {
+	return r.hasNextPage
+}
+
+// EndCursor resolves PageInfo.endCursor field.
+func (r PageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+// ClickResolver resolves Click type.
+type ClickResolver struct {
+	click click.Click
+}
+
+// ID resolves Click.id field.
+func (r ClickResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(r.click.ID), 10))
+}
+
+// EventID resolves Click.eventId field.
+func (r ClickResolver) EventID() *string {
+	return optional(r.click.ExternalID)
+}
+
+// VisitorID resolves Click.visitorId field.
+func (r ClickResolver) VisitorID() string {
+	return r.click.VisitorID
+}
+
+// URL resolves Click.url field.
+func (r ClickResolver) URL() string {
+	return r.click.URL
+}
+
+// CreatedAt resolves Click.createdAt field.
+func (r ClickResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.click.CreatedAt}
+}
+
+// ClickEdgeResolver resolves ClickEdge type.
+type ClickEdgeResolver struct {
+	click click.Click
+}
+
+// Cursor resolves ClickEdge.cursor field.
+func (r ClickEdgeResolver) Cursor() string {
+	return encodeCursor(r.click.ID)
+}
+
+// Node resolves ClickEdge.node field.
+func (r ClickEdgeResolver) Node() ClickResolver {
+	return ClickResolver{click: r.click}
+}
+
+// ClickConnectionResolver resolves ClickConnection type.
+type ClickConnectionResolver struct {
+	repository  click.Repository
+	filter      click.Filter
+	clicks      click.ClickCollection
+	hasNextPage bool
+}
+
+// Edges resolves ClickConnection.edges field.
+func (r *ClickConnectionResolver) Edges() []ClickEdgeResolver {
+	edges := make([]ClickEdgeResolver, 0, len(r.clicks))
+	for _, c := range r.clicks {
+		edges = append(edges, ClickEdgeResolver{click: c})
+	}
+	return edges
+}
+
+// PageInfo resolves ClickConnection.pageInfo field.
+func (r *ClickConnectionResolver) PageInfo() PageInfoResolver {
+	info := PageInfoResolver{hasNextPage: r.hasNextPage}
+	if len(r.clicks) > 0 {
+		cursor := encodeCursor(r.clicks[len(r.clicks)-1].ID)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+// TotalCount resolves ClickConnection.totalCount field. It's counted only when requested.
+func (r *ClickConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.repository.Count(ctx, r.filter)
+	return int32(count), err
+}
+
+// ViewResolver resolves View type.
+type ViewResolver struct {
+	view view.View
+}
+
+// ID resolves View.id field.
+func (r ViewResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(r.view.ID), 10))
+}
+
+// EventID resolves View.eventId field.
+func (r ViewResolver) EventID() *string {
+	return optional(r.view.ExternalID)
+}
+
+// VisitorID resolves View.visitorId field.
+func (r ViewResolver) VisitorID() string {
+	return r.view.VisitorID
+}
+
+// URL resolves View.url field.
+func (r ViewResolver) URL() string {
+	return r.view.URL
+}
+
+// CreatedAt resolves View.createdAt field.
+func (r ViewResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.view.CreatedAt}
+}
+
+// ViewEdgeResolver resolves ViewEdge type.
+type ViewEdgeResolver struct {
+	view view.View
+}
+
+// Cursor resolves ViewEdge.cursor field.
+func (r ViewEdgeResolver) Cursor() string {
+	return encodeCursor(r.view.ID)
+}
+
+// Node resolves ViewEdge.node field.
+func (r ViewEdgeResolver) Node() ViewResolver {
+	return ViewResolver{view: r.view}
+}
+
+// ViewConnectionResolver resolves ViewConnection type.
+type ViewConnectionResolver struct {
+	repository  view.Repository
+	filter      view.Filter
+	views       view.ViewCollection
+	hasNextPage bool
+}
+
+// Edges resolves ViewConnection.edges field.
+func (r *ViewConnectionResolver) Edges() []ViewEdgeResolver {
+	edges := make([]ViewEdgeResolver, 0, len(r.views))
+	for _, v := range r.views {
+		edges = append(edges, ViewEdgeResolver{view: v})
+	}
+	return edges
+}
+
+// PageInfo resolves ViewConnection.pageInfo field.
+func (r *ViewConnectionResolver) PageInfo() PageInfoResolver {
+	info := PageInfoResolver{hasNextPage: r.hasNextPage}
+	if len(r.views) > 0 {
+		cursor := encodeCursor(r.views[len(r.views)-1].ID)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+// TotalCount resolves ViewConnection.totalCount field. It's counted only when requested.
+func (r *ViewConnectionResolver) TotalCount(ctx context.Context) (int32, error) {
+	count, err := r.repository.Count(ctx, r.filter)
+	return int32(count), err
+}
+
+// GroupResolver resolves Group type.
+type GroupResolver struct {
+	url      string
+	period   time.Time
+	byURL    bool
+	byPeriod bool
+	clicks   int64
+	views    int64
+}
+
+// URL resolves Group.url field.
+func (r *GroupResolver) URL() *string {
+	if !r.byURL {
+		return nil
+	}
+	return &r.url
+}
+
+// Period resolves Group.period field.
+func (r *GroupResolver) Period() *graphql.Time {
+	if !r.byPeriod {
+		return nil
+	}
+	return &graphql.Time{Time: r.period}
+}
+
+// Clicks resolves Group.clicks field.
+func (r *GroupResolver) Clicks() int32 {
+	return int32(r.clicks)
+}
+
+// Views resolves Group.views field.
+func (r *GroupResolver) Views() int32 {
+	return int32(r.views)
+}
+
+// encodeCursor returns an opaque cursor pointing after an event with a given ID.
+func encodeCursor(id uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("id:" + strconv.FormatUint(uint64(id), 10)))
+}
+
+func decodeCursor(cursor string) (uint, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err == nil && len(b) > 3 && string(b[:3]) == "id:" {
+		if id, err := strconv.ParseUint(string(b[3:]), 10, 0); err == nil {
+			return uint(id), nil
+		}
+	}
+	return 0, errors.New("invalid cursor")
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,83 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	statusOK          = "ok"
+	statusUnavailable = "unavailable"
+)
+
+// StatusDTO represents HTTP response model of liveness and readiness checks.
+// Checks maps names of failed checks to their errors and of passed ones to "ok".
+type StatusDTO struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+// BuildInfoDTO represents HTTP response model. BuildTime is omitted when unknown.
+type BuildInfoDTO struct {
+	Commit        string `json:"commit"`
+	BuildTime     string `json:"buildTime,omitempty"`
+	GoVersion     string `json:"goVersion"`
+	SchemaVersion uint   `json:"schemaVersion"`
+}
+
+// NewBuildInfoDTO is a BuildInfoDTO constructor.
+func NewBuildInfoDTO(b BuildInfo) BuildInfoDTO {
+	return BuildInfoDTO{
+		Commit:        b.Commit,
+		BuildTime:     b.BuildTime,
+		GoVersion:     b.GoVersion,
+		SchemaVersion: b.SchemaVersion,
+	}
+}
+
+// Handler represents health HTTP handlers.
+type Handler struct {
+	checker   *Checker
+	buildInfo BuildInfo
+}
+
+// Live reports that the process is running and able to handle requests.
+func (h Handler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, StatusDTO{Status: statusOK})
+}
+
+// Ready runs all readiness checks and responds with 503 if any of them fails.
+func (h Handler) Ready(c echo.Context) error {
+	response := StatusDTO{
+		Status: statusOK,
+		Checks: make(map[string]string),
+	}
+
+	for name, err := range h.checker.Run(c.Request().Context()) {
+		if err != nil {
+			response.Status = statusUnavailable
+			response.Checks[name] = err.Error()
+			continue
+		}
+		response.Checks[name] = statusOK
+	}
+
+	if response.Status != statusOK {
+		return c.JSON(http.StatusServiceUnavailable, response)
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// Version responds with build information.
+func (h Handler) Version(c echo.Context) error {
+	return c.JSON(http.StatusOK, NewBuildInfoDTO(h.buildInfo))
+}
+
+// NewHandler is a Handler constructor.
+func NewHandler(checker *Checker, buildInfo BuildInfo) Handler {
+	return Handler{
+		checker:   checker,
+		buildInfo: buildInfo,
+	}
+}
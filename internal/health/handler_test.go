@@ -0,0 +1,79 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerLive(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/healthz", nil), rec)
+
+	handler := NewHandler(NewChecker(time.Second), BuildInfo{})
+	assert.NoError(t, handler.Live(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+}
+
+func TestHandlerReady(t *testing.T) {
+	tests := map[string]struct {
+		queueDepth     int
+		databaseErr    error
+		expectedStatus int
+		expectedBody   string
+	}{
+		"ready": {
+			queueDepth:     1,
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"status":"ok","checks":{"database":"ok","queue":"ok"}}`,
+		},
+		"database unreachable": {
+			queueDepth:     1,
+			databaseErr:    errors.New("unable to open database file"),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"unavailable","checks":{"database":"unable to open database file","queue":"ok"}}`,
+		},
+		"queue saturated": {
+			queueDepth:     10,
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   `{"status":"unavailable","checks":{"database":"ok","queue":"queue saturated: 10 of 10"}}`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			checker := NewChecker(time.Second)
+			checker.Add("database", func(context.Context) error { return test.databaseErr })
+			checker.Add("queue", Queue(func() int { return test.queueDepth }, 10))
+
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+
+			handler := NewHandler(checker, BuildInfo{})
+			assert.NoError(t, handler.Ready(c))
+			assert.Equal(t, test.expectedStatus, rec.Code)
+			assert.JSONEq(t, test.expectedBody, rec.Body.String())
+		})
+	}
+}
+
+func TestHandlerVersion(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/version", nil), rec)
+
+	buildInfo := NewBuildInfo("abc123", "2024-01-02T03:04:05Z", 2)
+	handler := NewHandler(NewChecker(time.Second), buildInfo)
+	assert.NoError(t, handler.Version(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"commit":"abc123","buildTime":"2024-01-02T03:04:05Z","goVersion":"`+buildInfo.GoVersion+`","schemaVersion":2}`, rec.Body.String())
+}
@@ -0,0 +1,131 @@
+// health package reports liveness, readiness and build information of the service,
+// so orchestrators can tell a running process from one which can actually serve requests.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds the time all readiness checks may take together.
+const DefaultTimeout = 2 * time.Second
+
+// Check reports an error when a dependency of the service is not usable.
+type Check func(ctx context.Context) error
+
+// Checker runs named readiness checks.
+type Checker struct {
+	timeout time.Duration
+
+	mu     sync.RWMutex
+	checks map[string]Check
+}
+
+// Add registers a check under a given name, replacing any check with the same name.
+func (c *Checker) Add(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Run executes all checks concurrently and returns their errors by name.
+// The service is ready when all errors are nil.
+func (c *Checker) Run(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := make([]Check, 0, len(names))
+	for _, name := range names {
+		checks = append(checks, c.checks[name])
+	}
+	c.mu.RUnlock()
+
+	errs := make([]error, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, check)
+	}
+	wg.Wait()
+
+	results := make(map[string]error, len(names))
+	for i, name := range names {
+		results[name] = errs[i]
+	}
+
+	return results
+}
+
+// NewChecker is a Checker constructor.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Database checks that the database accepts connections.
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Queue checks that an in-process queue is not saturated, which is when
+// its depth reaches a given limit.
+func Queue(depth func() int, limit int) Check {
+	return func(context.Context) error {
+		if d := depth(); d >= limit {
+			return fmt.Errorf("queue saturated: %d of %d", d, limit)
+		}
+		return nil
+	}
+}
+
+// BuildInfo identifies the running build of the service.
+type BuildInfo struct {
+	Commit        string
+	BuildTime     string
+	GoVersion     string
+	SchemaVersion uint
+}
+
+// NewBuildInfo is a BuildInfo constructor. Commit and build time which are not
+// provided, e.g. through linker flags, are taken from version control information
+// embedded by the Go toolchain, in which case build time is the time of the commit.
+func NewBuildInfo(commit, buildTime string, schemaVersion uint) BuildInfo {
+	info := BuildInfo{
+		Commit:        commit,
+		BuildTime:     buildTime,
+		SchemaVersion: schemaVersion,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		info.GoVersion = bi.GoVersion
+		for _, setting := range bi.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = setting.Value
+			}
+		}
+	}
+
+	return info
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSchemaNotApplied is reported when the database schema is older than the one
+// the service was built for.
+var ErrSchemaNotApplied = errors.New("schema migrations not applied")
+
+// SchemaVersionDAO represents a single applied schema version.
+type SchemaVersionDAO struct {
+	Version   uint `gorm:"primarykey;autoIncrement:false"`
+	AppliedAt time.Time
+}
+
+// TableName overrides the table name used by SchemaVersionDAO to 'schema_versions'
+func (SchemaVersionDAO) TableName() string {
+	return "schema_versions"
+}
+
+// RecordSchemaVersion marks a schema version as applied.
+// It should be called once all migrations of that version are finished.
+func RecordSchemaVersion(db *gorm.DB, version uint) error {
+	if err := db.AutoMigrate(&SchemaVersionDAO{}); err != nil {
+		return err
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&SchemaVersionDAO{Version: version, AppliedAt: time.Now()}).Error
+}
+
+// AppliedSchemaVersion returns the latest applied schema version, which is zero for a new database.
+func AppliedSchemaVersion(ctx context.Context, db *gorm.DB) (uint, error) {
+	if !db.Migrator().HasTable(&SchemaVersionDAO{}) {
+		return 0, nil
+	}
+
+	var applied uint
+	err := db.WithContext(ctx).Model(&SchemaVersionDAO{}).Select("COALESCE(MAX(version), 0)").Scan(&applied).Error
+	return applied, err
+}
+
+// Schema checks that the database schema is at least at the expected version.
+func Schema(db *gorm.DB, expected uint) Check {
+	return func(ctx context.Context) error {
+		var applied uint
+		err := db.WithContext(ctx).Model(&SchemaVersionDAO{}).Select("COALESCE(MAX(version), 0)").Scan(&applied).Error
+		if err != nil {
+			return err
+		}
+		if applied < expected {
+			return fmt.Errorf("%w: expected version %d, found %d", ErrSchemaNotApplied, expected, applied)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,39 @@
+package health
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSchema(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	defer func() {
+		os.Remove("gorm.db")
+	}()
+
+	check := Schema(gormDB, 2)
+
+	applied, err := AppliedSchemaVersion(context.Background(), gormDB)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), applied)
+
+	// the table doesn't exist before the first migration
+	assert.Error(t, check(context.Background()))
+
+	assert.NoError(t, RecordSchemaVersion(gormDB, 1))
+	assert.ErrorIs(t, check(context.Background()), ErrSchemaNotApplied)
+
+	assert.NoError(t, RecordSchemaVersion(gormDB, 2))
+	assert.NoError(t, RecordSchemaVersion(gormDB, 2))
+	assert.NoError(t, check(context.Background()))
+
+	applied, err = AppliedSchemaVersion(context.Background(), gormDB)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), applied)
+}
@@ -0,0 +1,179 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// DefaultWindow is the time for which responses are kept when no other window is configured.
+const DefaultWindow = 24 * time.Hour
+
+// maxKeyLength limits the size of client generated keys.
+const maxKeyLength = 255
+
+// maxBodySize limits the size of request bodies which are read to be fingerprinted.
+const maxBodySize = 1 << 20
+
+// cleanupInterval is the number of saved Records between two removals of expired ones.
+const cleanupInterval = 100
+
+// Guard stores responses of idempotent requests and replays them on repetition.
+type Guard struct {
+	repository Repository
+	window     time.Duration
+	now        func() time.Time
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+	saves    int
+}
+
+// Middleware returns middleware which makes requests with Idempotency-Key header idempotent.
+// It must run after project authentication, since keys are scoped to a project.
+// Responses with 5xx status codes are not stored, so such requests can be retried.
+func (g *Guard) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+			if len(key) > maxKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("%s must not be longer than %d characters", HeaderIdempotencyKey, maxKeyLength))
+			}
+
+			projectID := project.ID(c)
+			ctx := c.Request().Context()
+
+			fingerprint, err := requestFingerprint(c)
+			if err != nil {
+				return err
+			}
+
+			lock := fmt.Sprintf("%d|%s", projectID, key)
+			if !g.acquire(lock) {
+				return echo.NewHTTPError(http.StatusConflict, "a request with the same "+HeaderIdempotencyKey+" is in progress")
+			}
+			defer g.release(lock)
+
+			record, err := g.repository.Find(ctx, projectID, key)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			if err == nil && g.now().Sub(record.CreatedAt) < g.window {
+				if record.Fingerprint != fingerprint {
+					return echo.NewHTTPError(http.StatusUnprocessableEntity, HeaderIdempotencyKey+" was already used for a different request")
+				}
+				c.Response().Header().Set("Idempotent-Replayed", "true")
+				return c.Blob(record.Status, record.ContentType, record.Body)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			if !c.Response().Committed || status >= http.StatusInternalServerError {
+				return nil
+			}
+
+			return g.save(ctx, Record{
+				ProjectID:   projectID,
+				Key:         key,
+				Fingerprint: fingerprint,
+				Status:      status,
+				ContentType: c.Response().Header().Get(echo.HeaderContentType),
+				Body:        recorder.body.Bytes(),
+				CreatedAt:   g.now(),
+			})
+		}
+	}
+}
+
+func (g *Guard) save(ctx context.Context, record Record) error {
+	if err := g.repository.Save(ctx, record); err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	g.saves++
+	cleanup := g.saves%cleanupInterval == 0
+	g.mu.Unlock()
+
+	if cleanup {
+		return g.repository.DeleteBefore(ctx, g.now().Add(-g.window))
+	}
+	return nil
+}
+
+func (g *Guard) acquire(lock string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.inFlight[lock]; ok {
+		return false
+	}
+	g.inFlight[lock] = struct{}{}
+	return true
+}
+
+func (g *Guard) release(lock string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.inFlight, lock)
+}
+
+// NewGuard is a Guard constructor.
+func NewGuard(repository Repository, window time.Duration) *Guard {
+	return &Guard{
+		repository: repository,
+		window:     window,
+		now:        time.Now,
+		inFlight:   make(map[string]struct{}),
+	}
+}
+
+// requestFingerprint hashes method, path and body of the request. The body is restored afterwards.
+// Bodies larger than maxBodySize are rejected with 413 status code.
+func requestFingerprint(c echo.Context) (string, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Response(), c.Request().Body, maxBodySize))
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return "", echo.NewHTTPError(http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not be larger than %d bytes", maxBodySize))
+	}
+	if err != nil {
+		return "", err
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", c.Request().Method, c.Request().URL.RequestURI())
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// responseRecorder copies the response body while it's being written.
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
@@ -0,0 +1,103 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// memoryRepository is a simple Repository used to test the Guard.
+type memoryRepository struct {
+	mu      sync.Mutex
+	records map[string]Record
+}
+
+func (r *memoryRepository) Find(_ context.Context, projectID uint, key string) (Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	record, ok := r.records[key]
+	if !ok || record.ProjectID != projectID {
+		return Record{}, ErrNotFound
+	}
+	return record, nil
+}
+
+func (r *memoryRepository) Save(_ context.Context, record Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[record.Key] = record
+	return nil
+}
+
+func (r *memoryRepository) DeleteBefore(context.Context, time.Time) error {
+	return nil
+}
+
+func TestGuardMiddleware(t *testing.T) {
+	calls := 0
+	guard := NewGuard(&memoryRepository{records: make(map[string]Record)}, time.Hour)
+
+	e := echo.New()
+	e.POST("/clicks", func(c echo.Context) error {
+		calls++
+		if calls > 2 {
+			return echo.NewHTTPError(http.StatusInternalServerError)
+		}
+		return c.JSON(http.StatusCreated, map[string]int{"id": calls})
+	}, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(project.ContextKey, uint(1))
+			return next(c)
+		}
+	}, guard.Middleware())
+
+	request := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/clicks", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if key != "" {
+			req.Header.Set(HeaderIdempotencyKey, key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("key-1", `{"url":"test.url1"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":1}`+"\n", rec.Body.String())
+
+	// repetition is replayed without calling the handler
+	rec = request("key-1", `{"url":"test.url1"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, `{"id":1}`+"\n", rec.Body.String())
+	assert.Equal(t, "true", rec.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, 1, calls)
+
+	// the same key can't be used for a different request
+	rec = request("key-1", `{"url":"test.url2"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// requests without a key are not affected
+	rec = request("", `{"url":"test.url1"}`)
+	assert.Equal(t, `{"id":2}`+"\n", rec.Body.String())
+
+	// server errors are not stored, so the request can be retried
+	assert.Equal(t, http.StatusInternalServerError, request("key-2", `{"url":"test.url1"}`).Code)
+	request("key-2", `{"url":"test.url1"}`)
+	assert.Equal(t, 4, calls)
+
+	// bodies are read only up to a limit
+	rec = request("key-3", `{"url":"`+strings.Repeat("a", maxBodySize)+`"}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, 4, calls)
+}
@@ -0,0 +1,35 @@
+// idempotency package makes retried HTTP requests safe.
+// Requests carrying an Idempotency-Key header are executed once, and every
+// repetition within a time window receives the response of the first execution.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// HeaderIdempotencyKey is the request header holding the client generated key.
+const HeaderIdempotencyKey = "Idempotency-Key"
+
+// ErrNotFound is returned when there is no Record for a given key.
+var ErrNotFound = errors.New("not found")
+
+// Record represents a stored response to an idempotent request.
+// Fingerprint identifies the request, so a key can't be reused for a different request.
+type Record struct {
+	ProjectID   uint
+	Key         string
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+}
+
+// Repository defines a storage API for Record entity.
+type Repository interface {
+	Find(ctx context.Context, projectID uint, key string) (Record, error)
+	Save(context.Context, Record) error
+	DeleteBefore(context.Context, time.Time) error
+}
@@ -0,0 +1,95 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordDAO represents a single database entry.
+type RecordDAO struct {
+	ID          uint      `gorm:"primarykey"`
+	CreatedAt   time.Time `gorm:"index"`
+	ProjectID   uint      `gorm:"uniqueIndex:idx_idempotency_keys_project_key"`
+	Key         string    `gorm:"uniqueIndex:idx_idempotency_keys_project_key"`
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// TableName overrides the table name used by RecordDAO to 'idempotency_keys'
+func (RecordDAO) TableName() string {
+	return "idempotency_keys"
+}
+
+// ToDomain maps database model into domain model.
+func (r *RecordDAO) ToDomain() Record {
+	return Record{
+		ProjectID:   r.ProjectID,
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Status:      r.Status,
+		ContentType: r.ContentType,
+		Body:        r.Body,
+		CreatedAt:   r.CreatedAt,
+	}
+}
+
+// NewRecordDAO maps Record entity model into database model.
+func NewRecordDAO(r Record) RecordDAO {
+	return RecordDAO{
+		CreatedAt:   r.CreatedAt,
+		ProjectID:   r.ProjectID,
+		Key:         r.Key,
+		Fingerprint: r.Fingerprint,
+		Status:      r.Status,
+		ContentType: r.ContentType,
+		Body:        r.Body,
+	}
+}
+
+// SQLiteRepository is a SQLite implementation of Record repository.
+type SQLiteRepository struct {
+	db *gorm.DB
+}
+
+// Find returns the Record stored under a given key. ErrNotFound is returned if there is none.
+func (r *SQLiteRepository) Find(ctx context.Context, projectID uint, key string) (Record, error) {
+	var dao RecordDAO
+
+	err := r.db.WithContext(ctx).Where("project_id = ? AND key = ?", projectID, key).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Record{}, ErrNotFound
+	}
+	if err != nil {
+		return Record{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// Save persists Record entity, replacing the one stored under the same key.
+func (r *SQLiteRepository) Save(ctx context.Context, record Record) error {
+	dao := NewRecordDAO(record)
+
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "project_id"}, {Name: "key"}},
+		UpdateAll: true,
+	}).Create(&dao).Error
+}
+
+// DeleteBefore removes Records created before a given time.
+func (r *SQLiteRepository) DeleteBefore(ctx context.Context, t time.Time) error {
+	return r.db.WithContext(ctx).Where("created_at < ?", t).Delete(&RecordDAO{}).Error
+}
+
+// NewSQLiteRepository is a SQLiteRepository constructor.
+func NewSQLiteRepository(db *gorm.DB) *SQLiteRepository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
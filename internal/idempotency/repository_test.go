@@ -0,0 +1,61 @@
+package idempotency
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSaveAndFind(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+	createdAt, _ := time.Parse(time.DateOnly, "2024-01-02")
+
+	record := Record{
+		ProjectID:   1,
+		Key:         "key-1",
+		Fingerprint: "fingerprint",
+		Status:      201,
+		ContentType: "application/json",
+		Body:        []byte(`{"id":1}`),
+		CreatedAt:   createdAt,
+	}
+	assert.NoError(t, sqliteRepo.Save(context.Background(), record))
+
+	found, err := sqliteRepo.Find(context.Background(), 1, "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, record, found)
+
+	// keys are scoped to a project
+	_, err = sqliteRepo.Find(context.Background(), 2, "key-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, sqliteRepo.DeleteBefore(context.Background(), createdAt.Add(time.Second)))
+	_, err = sqliteRepo.Find(context.Background(), 1, "key-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&RecordDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,92 @@
+package importer
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// ProgressDTO represents HTTP response model.
+type ProgressDTO struct {
+	Read       int64  `json:"read"`
+	Imported   int64  `json:"imported"`
+	Duplicates int64  `json:"duplicates"`
+	Error      string `json:"error,omitempty"`
+}
+
+// NewProgressDTO is a ProgressDTO constructor.
+func NewProgressDTO(p Progress, err error) ProgressDTO {
+	dto := ProgressDTO{
+		Read:       p.Read,
+		Imported:   p.Imported,
+		Duplicates: p.Duplicates,
+	}
+	if err != nil {
+		dto.Error = err.Error()
+	}
+	return dto
+}
+
+// Handler defines all API methods for Import.
+type Handler struct {
+	importer *Importer
+}
+
+// Import implements handler for Import HTTP request.
+// Request body is streamed into the repositories of the authenticated project.
+// Format is taken from the "format" query parameter, or derived from Content-Type when it's missing.
+// If the import fails midway, the response contains the number of records that were read
+// and persisted, which can be sent back as "offset" query parameter to resume the import.
+func (h *Handler) Import(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = formatFromContentType(c.Request().Header.Get(echo.HeaderContentType))
+	}
+
+	var offset int64
+	if o := c.QueryParam("offset"); o != "" {
+		var err error
+		if offset, err = strconv.ParseInt(o, 10, 64); err != nil || offset < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "offset must be a non-negative integer")
+		}
+	}
+
+	reader, err := NewReader(format, c.Request().Body)
+	if errors.Is(err, ErrUnsupportedFormat) {
+		return echo.NewHTTPError(http.StatusUnsupportedMediaType, err.Error())
+	}
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	progress, err := h.importer.Import(c.Request().Context(), project.ID(c), reader, offset, nil)
+	if err != nil {
+		return c.JSON(http.StatusUnprocessableEntity, NewProgressDTO(progress, err))
+	}
+
+	return c.JSON(http.StatusOK, NewProgressDTO(progress, nil))
+}
+
+func formatFromContentType(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	switch mediaType {
+	case "text/csv":
+		return FormatCSV
+	case "application/x-ndjson":
+		return FormatNDJSON
+	default:
+		return ""
+	}
+}
+
+// NewHandler is a Handler constructor.
+func NewHandler(importer *Importer) Handler {
+	return Handler{
+		importer: importer,
+	}
+}
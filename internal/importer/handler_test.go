@@ -0,0 +1,53 @@
+package importer
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+func TestHandlerImport(t *testing.T) {
+	e := echo.New()
+	body := "type,external_id,url,created_at\nclick,c1,test.url1,2024-01-02T10:00:00Z\n"
+	req := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, "text/csv")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+
+	createdAt, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+
+	clickRepository := &ClickRepositoryMock{}
+	clickRepository.
+		On("CreateBatch", mock.Anything, click.ClickCollection{{ProjectID: 1, ExternalID: "c1", URL: "test.url1", CreatedAt: createdAt}}).
+		Return(int64(1), nil).Once()
+
+	h := NewHandler(NewImporter(clickRepository, &ViewRepositoryMock{}, 0))
+
+	if assert.NoError(t, h.Import(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"read":1,"imported":1,"duplicates":0}`+"\n", rec.Body.String())
+	}
+}
+
+func TestHandlerImportUnsupportedFormat(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/import?format=xml", strings.NewReader("<xml/>"))
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	h := NewHandler(NewImporter(&ClickRepositoryMock{}, &ViewRepositoryMock{}, 0))
+
+	err := h.Import(c)
+	if assert.Error(t, err) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, err.(*echo.HTTPError).Code)
+	}
+}
@@ -0,0 +1,196 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// DefaultBatchSize is used when Importer is created with non-positive batch size.
+const DefaultBatchSize = 500
+
+// Importer streams Records into click and view repositories in batches.
+type Importer struct {
+	clickRepository click.Repository
+	viewRepository  view.Repository
+	batchSize       int
+}
+
+// run holds consecutive Records of the same type, which are persisted together.
+type run struct {
+	clicks click.ClickCollection
+	views  view.ViewCollection
+}
+
+func (r run) len() int {
+	return len(r.clicks) + len(r.views)
+}
+
+// Import reads all Records from r and persists them into a given project.
+// The first offset Records are skipped, which allows resuming an interrupted import.
+// After every persisted batch report is called with the current Progress;
+// Progress.Read reported at that point is safe to be used as the next offset.
+// Clicks and views are stored in separate tables, so a batch is persisted as runs of consecutive
+// Records of the same type, in the order they were read. If persisting fails, the returned Progress
+// counts the runs persisted before, and its Read is still safe to be used as the next offset.
+func (i *Importer) Import(ctx context.Context, projectID uint, r Reader, offset int64, report func(Progress)) (Progress, error) {
+	var (
+		progress Progress
+		runs     []run
+		pending  int
+	)
+
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+
+		for len(runs) > 0 {
+			var inserted int64
+			var err error
+			if len(runs[0].clicks) > 0 {
+				inserted, err = i.clickRepository.CreateBatch(ctx, runs[0].clicks)
+			} else {
+				inserted, err = i.viewRepository.CreateBatch(ctx, runs[0].views)
+			}
+			if err != nil {
+				return err
+			}
+
+			progress.Imported += inserted
+			progress.Duplicates += int64(runs[0].len()) - inserted
+			pending -= runs[0].len()
+			runs = runs[1:]
+		}
+
+		if report != nil {
+			report(progress)
+		}
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return progress, err
+		}
+
+		progress.Read++
+		if progress.Read <= offset {
+			continue
+		}
+
+		last := len(runs) - 1
+		switch record.Type {
+		case TypeClick:
+			if last < 0 || len(runs[last].views) > 0 {
+				runs, last = append(runs, run{}), last+1
+			}
+			runs[last].clicks = append(runs[last].clicks, click.Click{
+				ProjectID:  projectID,
+				ExternalID: record.ExternalID,
+				VisitorID:  record.VisitorID,
+				URL:        record.URL,
+				CreatedAt:  record.CreatedAt,
+			})
+		case TypeView:
+			if last < 0 || len(runs[last].clicks) > 0 {
+				runs, last = append(runs, run{}), last+1
+			}
+			runs[last].views = append(runs[last].views, view.View{
+				ProjectID:  projectID,
+				ExternalID: record.ExternalID,
+				VisitorID:  record.VisitorID,
+				URL:        record.URL,
+				CreatedAt:  record.CreatedAt,
+			})
+		default:
+			continue
+		}
+		pending++
+
+		if pending >= i.batchSize {
+			if err := flush(); err != nil {
+				// records which weren't persisted must be read again on resume
+				progress.Read -= int64(pending)
+				return progress, err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		progress.Read -= int64(pending)
+		return progress, err
+	}
+
+	return progress, nil
+}
+
+// NewImporter is an Importer constructor.
+func NewImporter(clickRepository click.Repository, viewRepository view.Repository, batchSize int) *Importer {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	return &Importer{
+		clickRepository: clickRepository,
+		viewRepository:  viewRepository,
+		batchSize:       batchSize,
+	}
+}
+
+// Checkpoint persists import position in a file so an interrupted import can be resumed.
+type Checkpoint struct {
+	path string
+}
+
+// Load returns the stored position. Missing checkpoint file means import hasn't started yet.
+func (c Checkpoint) Load() (int64, error) {
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+}
+
+// Save atomically stores the position.
+func (c Checkpoint) Save(position int64) error {
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(position, 10)), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, c.path)
+}
+
+// Clear removes the stored position once the import is complete.
+func (c Checkpoint) Clear() error {
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// NewCheckpoint is a Checkpoint constructor.
+func NewCheckpoint(path string) Checkpoint {
+	return Checkpoint{
+		path: path,
+	}
+}
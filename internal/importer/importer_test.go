@@ -0,0 +1,276 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+type ClickRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ClickRepositoryMock) Create(ctx context.Context, c click.Click) (click.Click, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(click.Click), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) CreateBatch(ctx context.Context, clicks click.ClickCollection) (int64, error) {
+	args := m.Called(ctx, clicks)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Filter(ctx context.Context, filter click.Filter) (click.ClickCollection, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(click.ClickCollection), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Count(ctx context.Context, filter click.Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Aggregate(ctx context.Context, filter click.Filter, groupBy click.GroupBy) (click.GroupCollection, error) {
+	args := m.Called(ctx, filter, groupBy)
+	return args.Get(0).(click.GroupCollection), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Get(ctx context.Context, projectID, id uint) (click.Click, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(click.Click), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Update(ctx context.Context, c click.Click) (click.Click, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(click.Click), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Delete(ctx context.Context, projectID, id uint) error {
+	args := m.Called(ctx, projectID, id)
+	return args.Error(0)
+}
+
+func (m *ClickRepositoryMock) Restore(ctx context.Context, projectID, id uint) (click.Click, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(click.Click), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) DeleteMatching(ctx context.Context, filter click.Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ClickRepositoryMock) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, projectID, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type ViewRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ViewRepositoryMock) Create(ctx context.Context, v view.View) (view.View, error) {
+	args := m.Called(ctx, v)
+	return args.Get(0).(view.View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) CreateBatch(ctx context.Context, views view.ViewCollection) (int64, error) {
+	args := m.Called(ctx, views)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Filter(ctx context.Context, filter view.Filter) (view.ViewCollection, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(view.ViewCollection), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Count(ctx context.Context, filter view.Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Aggregate(ctx context.Context, filter view.Filter, groupBy view.GroupBy) (view.GroupCollection, error) {
+	args := m.Called(ctx, filter, groupBy)
+	return args.Get(0).(view.GroupCollection), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Get(ctx context.Context, projectID, id uint) (view.View, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(view.View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Update(ctx context.Context, v view.View) (view.View, error) {
+	args := m.Called(ctx, v)
+	return args.Get(0).(view.View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Delete(ctx context.Context, projectID, id uint) error {
+	args := m.Called(ctx, projectID, id)
+	return args.Error(0)
+}
+
+func (m *ViewRepositoryMock) Restore(ctx context.Context, projectID, id uint) (view.View, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(view.View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) DeleteMatching(ctx context.Context, filter view.Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, projectID, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func TestReaders(t *testing.T) {
+	time1, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	time2, _ := time.Parse(time.DateTime, "2024-01-03 11:00:00")
+	expected := []Record{
+		{Type: TypeClick, ExternalID: "a1", VisitorID: "v1", URL: "test.url1", CreatedAt: time1},
+		{Type: TypeView, URL: "test.url2", CreatedAt: time2},
+	}
+
+	tests := []struct {
+		testName string
+		format   string
+		input    string
+		err      bool
+	}{
+		{
+			testName: "CSV - success",
+			format:   FormatCSV,
+			input: "url,type,external_id,visitor_id,created_at\n" +
+				"test.url1,click,a1,v1,2024-01-02T10:00:00Z\n" +
+				"test.url2,view,,,2024-01-03 11:00:00\n",
+		},
+		{
+			testName: "NDJSON - success",
+			format:   FormatNDJSON,
+			input: `{"type":"click","externalId":"a1","visitorId":"v1","url":"test.url1","createdAt":"2024-01-02T10:00:00Z"}` + "\n\n" +
+				`{"type":"view","url":"test.url2","createdAt":"2024-01-03 11:00:00"}` + "\n",
+		},
+		{
+			testName: "CSV - unknown type",
+			format:   FormatCSV,
+			input:    "type,url,created_at\nhover,test.url1,2024-01-02T10:00:00Z\n",
+			err:      true,
+		},
+		{
+			testName: "NDJSON - invalid time",
+			format:   FormatNDJSON,
+			input:    `{"type":"click","url":"test.url1","createdAt":"yesterday"}`,
+			err:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			r, err := NewReader(test.format, strings.NewReader(test.input))
+			assert.NoError(t, err)
+
+			var records []Record
+			for {
+				record, err := r.Read()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					assert.True(t, test.err, err.Error())
+					return
+				}
+				records = append(records, record)
+			}
+
+			assert.False(t, test.err)
+			assert.Equal(t, expected, records)
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	input := `{"type":"click","externalId":"c1","url":"test.url1","createdAt":"2024-01-02T10:00:00Z"}
+{"type":"view","externalId":"v1","url":"test.url1","createdAt":"2024-01-02T10:00:00Z"}
+{"type":"click","externalId":"c2","url":"test.url2","createdAt":"2024-01-02T10:00:00Z"}
+`
+	createdAt, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+
+	clickRepository := &ClickRepositoryMock{}
+	clickRepository.
+		On("CreateBatch", mock.Anything, click.ClickCollection{{ProjectID: 1, ExternalID: "c2", URL: "test.url2", CreatedAt: createdAt}}).
+		Return(int64(0), nil).Once()
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.
+		On("CreateBatch", mock.Anything, view.ViewCollection{{ProjectID: 1, ExternalID: "v1", URL: "test.url1", CreatedAt: createdAt}}).
+		Return(int64(1), nil).Once()
+
+	var reported []Progress
+	i := NewImporter(clickRepository, viewRepository, 2)
+	progress, err := i.Import(context.Background(), 1, NewNDJSONReader(strings.NewReader(input)), 1, func(p Progress) {
+		reported = append(reported, p)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Progress{Read: 3, Imported: 1, Duplicates: 1}, progress)
+	assert.Equal(t, []Progress{progress}, reported)
+	clickRepository.AssertExpectations(t)
+	viewRepository.AssertExpectations(t)
+}
+
+func TestImportPartialFailure(t *testing.T) {
+	input := `{"type":"click","url":"test.url1","createdAt":"2024-01-02T10:00:00Z"}
+{"type":"click","url":"test.url2","createdAt":"2024-01-02T10:00:00Z"}
+{"type":"view","url":"test.url1","createdAt":"2024-01-02T10:00:00Z"}
+{"type":"click","url":"test.url3","createdAt":"2024-01-02T10:00:00Z"}
+`
+	createdAt, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	clicks := click.ClickCollection{
+		{ProjectID: 1, URL: "test.url1", CreatedAt: createdAt},
+		{ProjectID: 1, URL: "test.url2", CreatedAt: createdAt},
+	}
+	views := view.ViewCollection{{ProjectID: 1, URL: "test.url1", CreatedAt: createdAt}}
+	lastClicks := click.ClickCollection{{ProjectID: 1, URL: "test.url3", CreatedAt: createdAt}}
+
+	clickRepository := &ClickRepositoryMock{}
+	clickRepository.On("CreateBatch", mock.Anything, clicks).Return(int64(2), nil).Once()
+	clickRepository.On("CreateBatch", mock.Anything, lastClicks).Return(int64(1), nil).Once()
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.On("CreateBatch", mock.Anything, views).Return(int64(0), errors.New("disk is full")).Once()
+	viewRepository.On("CreateBatch", mock.Anything, views).Return(int64(1), nil).Once()
+
+	i := NewImporter(clickRepository, viewRepository, 10)
+	progress, err := i.Import(context.Background(), 1, NewNDJSONReader(strings.NewReader(input)), 0, nil)
+	assert.Error(t, err)
+	assert.Equal(t, Progress{Read: 2, Imported: 2}, progress, "persisted clicks are counted and not read again")
+
+	// clicks without external IDs aren't deduplicated, so resuming must not insert them again
+	progress, err = i.Import(context.Background(), 1, NewNDJSONReader(strings.NewReader(input)), progress.Read, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Progress{Read: 4, Imported: 2}, progress)
+	clickRepository.AssertExpectations(t)
+	viewRepository.AssertExpectations(t)
+}
+
+func TestCheckpoint(t *testing.T) {
+	checkpoint := NewCheckpoint(filepath.Join(t.TempDir(), "import.checkpoint"))
+
+	position, err := checkpoint.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), position)
+
+	assert.NoError(t, checkpoint.Save(42))
+
+	position, err = checkpoint.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, int64(42), position)
+}
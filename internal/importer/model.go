@@ -0,0 +1,42 @@
+// importer package loads historical clicks and views from external sources.
+// It supports CSV and NDJSON files, batched inserts, deduplication by
+// external ID and resumable checkpoints.
+package importer
+
+import (
+	"time"
+)
+
+// Supported event types.
+const (
+	TypeClick = "click"
+	TypeView  = "view"
+)
+
+// Supported import formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Record represents a single historical event read from an import source.
+type Record struct {
+	Type       string
+	ExternalID string
+	VisitorID  string
+	URL        string
+	CreatedAt  time.Time
+}
+
+// Reader reads Records one by one. It returns io.EOF when there are no more Records.
+type Reader interface {
+	Read() (Record, error)
+}
+
+// Progress holds import counters.
+// Read includes records skipped because of a resume offset.
+type Progress struct {
+	Read       int64
+	Imported   int64
+	Duplicates int64
+}
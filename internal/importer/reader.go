@@ -0,0 +1,174 @@
+package importer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedFormat is returned for unknown import formats.
+var ErrUnsupportedFormat = errors.New("unsupported import format")
+
+// NewReader is a Reader constructor for a given format.
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(r)
+	case FormatNDJSON:
+		return NewNDJSONReader(r), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+}
+
+// CSVReader reads Records from CSV with a header row.
+// Recognised columns are type, external_id, visitor_id, url and created_at, in any order.
+type CSVReader struct {
+	r       *csv.Reader
+	columns map[string]int
+	line    int
+}
+
+// NewCSVReader is a CSVReader constructor. It consumes the header row.
+func NewCSVReader(r io.Reader) (*CSVReader, error) {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.ToLower(name))] = i
+	}
+	for _, required := range []string{"type", "url", "created_at"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing %q column", required)
+		}
+	}
+
+	return &CSVReader{r: cr, columns: columns, line: 1}, nil
+}
+
+// Read implements Reader interface.
+func (r *CSVReader) Read() (Record, error) {
+	row, err := r.r.Read()
+	if err != nil {
+		return Record{}, err
+	}
+	r.line++
+
+	createdAt, err := parseTime(r.column(row, "created_at"))
+	if err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+	}
+
+	record := Record{
+		Type:       r.column(row, "type"),
+		ExternalID: r.column(row, "external_id"),
+		VisitorID:  r.column(row, "visitor_id"),
+		URL:        r.column(row, "url"),
+		CreatedAt:  createdAt,
+	}
+	if err := record.validate(); err != nil {
+		return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+	}
+
+	return record, nil
+}
+
+func (r *CSVReader) column(row []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+// NDJSONReader reads Records from newline delimited JSON.
+type NDJSONReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+// NewNDJSONReader is a NDJSONReader constructor.
+func NewNDJSONReader(r io.Reader) *NDJSONReader {
+	return &NDJSONReader{s: bufio.NewScanner(r)}
+}
+
+type ndjsonRecord struct {
+	Type       string `json:"type"`
+	ExternalID string `json:"externalId"`
+	VisitorID  string `json:"visitorId"`
+	URL        string `json:"url"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// Read implements Reader interface. Blank lines are ignored.
+func (r *NDJSONReader) Read() (Record, error) {
+	for r.s.Scan() {
+		r.line++
+
+		line := strings.TrimSpace(r.s.Text())
+		if line == "" {
+			continue
+		}
+
+		var raw ndjsonRecord
+		if err := json.Unmarshal([]byte(line), &raw); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		createdAt, err := parseTime(raw.CreatedAt)
+		if err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		record := Record{
+			Type:       raw.Type,
+			ExternalID: raw.ExternalID,
+			VisitorID:  raw.VisitorID,
+			URL:        raw.URL,
+			CreatedAt:  createdAt,
+		}
+		if err := record.validate(); err != nil {
+			return Record{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		return record, nil
+	}
+
+	if err := r.s.Err(); err != nil {
+		return Record{}, err
+	}
+	return Record{}, io.EOF
+}
+
+func (r Record) validate() error {
+	if r.Type != TypeClick && r.Type != TypeView {
+		return fmt.Errorf("unknown event type %q", r.Type)
+	}
+	if r.URL == "" {
+		return errors.New("url is required")
+	}
+	return nil
+}
+
+// parseTime accepts RFC 3339 timestamps as well as the format used by earlier API responses.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateTime, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid createdAt %q", value)
+	}
+	return t, nil
+}
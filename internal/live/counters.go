@@ -0,0 +1,188 @@
+// live package keeps rolling counts of recent clicks and views per URL in memory
+// and pushes them to WebSocket subscribers.
+package live
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMaxURLs bounds the number of URLs counted at the same time.
+// Each URL takes roughly 2KB of memory.
+const DefaultMaxURLs = 10_000
+
+// Kind of counted events.
+type Kind int
+
+const (
+	KindView Kind = iota
+	KindClick
+)
+
+// Window holds event counts over the recent periods.
+type Window struct {
+	LastMinute uint64
+	LastHour   uint64
+}
+
+// Counts holds rolling counts of a single URL.
+type Counts struct {
+	Views  Window
+	Clicks Window
+}
+
+// bucket counts events of a single time slot, identified by its start divided by the slot width.
+type bucket struct {
+	slot  int64
+	count uint64
+}
+
+// ring is a rolling window split into fixed width slots.
+type ring struct {
+	width   int64
+	buckets []bucket
+}
+
+func newRing(width time.Duration, slots int) ring {
+	return ring{
+		width:   int64(width / time.Second),
+		buckets: make([]bucket, slots),
+	}
+}
+
+func (r ring) add(at time.Time) {
+	slot := at.Unix() / r.width
+	b := &r.buckets[slot%int64(len(r.buckets))]
+	if b.slot > slot {
+		return
+	}
+	if b.slot < slot {
+		b.slot = slot
+		b.count = 0
+	}
+	b.count++
+}
+
+func (r ring) sum(now time.Time) uint64 {
+	current := now.Unix() / r.width
+	var n uint64
+	for _, b := range r.buckets {
+		if b.slot <= current && current-b.slot < int64(len(r.buckets)) {
+			n += b.count
+		}
+	}
+	return n
+}
+
+// window counts the last minute in 10 second slots and the last hour in 1 minute slots.
+type window struct {
+	minute ring
+	hour   ring
+}
+
+func newWindow() window {
+	return window{
+		minute: newRing(10*time.Second, 6),
+		hour:   newRing(time.Minute, 60),
+	}
+}
+
+func (w window) add(at time.Time) {
+	w.minute.add(at)
+	w.hour.add(at)
+}
+
+func (w window) counts(now time.Time) Window {
+	return Window{
+		LastMinute: w.minute.sum(now),
+		LastHour:   w.hour.sum(now),
+	}
+}
+
+type key struct {
+	projectID uint
+	url       string
+}
+
+type entry struct {
+	key    key
+	views  window
+	clicks window
+}
+
+// Counters maintains rolling counts of views and clicks per project and URL.
+// Counts are approximate: the current slot is always included in full, so the last
+// minute may span up to 70 seconds and the last hour up to 61 minutes.
+// When the number of URLs reaches the limit, the least recently updated one is dropped.
+type Counters struct {
+	mu      sync.Mutex
+	maxURLs int
+	entries map[key]*list.Element
+	recency *list.List
+}
+
+// Add counts an event which happened at a given time.
+func (c *Counters) Add(projectID uint, url string, kind Kind, at time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	k := key{projectID: projectID, url: url}
+	element, ok := c.entries[k]
+	if ok {
+		c.recency.MoveToFront(element)
+	} else {
+		if c.recency.Len() >= c.maxURLs {
+			oldest := c.recency.Back()
+			c.recency.Remove(oldest)
+			delete(c.entries, oldest.Value.(*entry).key)
+		}
+		element = c.recency.PushFront(&entry{key: k, views: newWindow(), clicks: newWindow()})
+		c.entries[k] = element
+	}
+
+	e := element.Value.(*entry)
+	switch kind {
+	case KindView:
+		e.views.add(at)
+	case KindClick:
+		e.clicks.add(at)
+	}
+}
+
+// Get returns counts of a URL at a given time. URLs without recent events have zero counts.
+func (c *Counters) Get(projectID uint, url string, now time.Time) Counts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key{projectID: projectID, url: url}]
+	if !ok {
+		return Counts{}
+	}
+
+	e := element.Value.(*entry)
+	return Counts{
+		Views:  e.views.counts(now),
+		Clicks: e.clicks.counts(now),
+	}
+}
+
+// Len returns the number of counted URLs.
+func (c *Counters) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.recency.Len()
+}
+
+// NewCounters is a Counters constructor.
+func NewCounters(maxURLs int) *Counters {
+	if maxURLs < 1 {
+		maxURLs = 1
+	}
+	return &Counters{
+		maxURLs: maxURLs,
+		entries: make(map[key]*list.Element),
+		recency: list.New(),
+	}
+}
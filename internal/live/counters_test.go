@@ -0,0 +1,82 @@
+package live
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounters(t *testing.T) {
+	start, _ := time.Parse(time.DateTime, "2024-01-02 03:04:00")
+	counters := NewCounters(10)
+
+	counters.Add(1, "test.url1", KindView, start)
+	counters.Add(1, "test.url1", KindView, start.Add(30*time.Second))
+	counters.Add(1, "test.url1", KindClick, start.Add(30*time.Second))
+	counters.Add(2, "test.url1", KindView, start)
+
+	tests := map[string]struct {
+		projectID uint
+		url       string
+		now       time.Time
+		expected  Counts
+	}{
+		"within a minute": {
+			projectID: 1,
+			url:       "test.url1",
+			now:       start.Add(40 * time.Second),
+			expected:  Counts{Views: Window{LastMinute: 2, LastHour: 2}, Clicks: Window{LastMinute: 1, LastHour: 1}},
+		},
+		"first view older than a minute": {
+			projectID: 1,
+			url:       "test.url1",
+			now:       start.Add(80 * time.Second),
+			expected:  Counts{Views: Window{LastMinute: 1, LastHour: 2}, Clicks: Window{LastMinute: 1, LastHour: 1}},
+		},
+		"all older than an hour": {
+			projectID: 1,
+			url:       "test.url1",
+			now:       start.Add(2 * time.Hour),
+			expected:  Counts{},
+		},
+		"other project": {
+			projectID: 2,
+			url:       "test.url1",
+			now:       start,
+			expected:  Counts{Views: Window{LastMinute: 1, LastHour: 1}},
+		},
+		"unknown url": {
+			projectID: 1,
+			url:       "test.url2",
+			now:       start,
+			expected:  Counts{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, counters.Get(test.projectID, test.url, test.now))
+		})
+	}
+
+	// events in a slot which has been reused for a later time are not counted
+	counters.Add(1, "test.url1", KindView, start.Add(2*time.Hour))
+	counters.Add(1, "test.url1", KindView, start)
+	assert.Equal(t, uint64(1), counters.Get(1, "test.url1", start.Add(2*time.Hour)).Views.LastHour)
+}
+
+func TestCountersLimit(t *testing.T) {
+	now := time.Now()
+	counters := NewCounters(2)
+
+	counters.Add(1, "test.url1", KindView, now)
+	counters.Add(1, "test.url2", KindView, now)
+	counters.Add(1, "test.url1", KindView, now)
+	counters.Add(1, "test.url3", KindView, now)
+
+	assert.Equal(t, 2, counters.Len())
+	assert.Equal(t, uint64(2), counters.Get(1, "test.url1", now).Views.LastMinute)
+	assert.Equal(t, Counts{}, counters.Get(1, "test.url2", now), "least recently updated URL is dropped")
+	assert.Equal(t, uint64(1), counters.Get(1, "test.url3", now).Views.LastMinute)
+}
@@ -0,0 +1,175 @@
+package live
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+const (
+	// DefaultInterval is how often counts are pushed to subscribers.
+	DefaultInterval = 5 * time.Second
+	// MaxSubscribedURLs limits the number of URLs a single connection can subscribe to.
+	MaxSubscribedURLs = 100
+
+	writeTimeout   = 10 * time.Second
+	maxMessageSize = 64 << 10
+)
+
+// SubscribeDTO represents WebSocket request model.
+// Every message replaces the set of URLs the connection is subscribed to.
+type SubscribeDTO struct {
+	URLs []string `json:"urls"`
+}
+
+// WindowDTO represents WebSocket response model.
+type WindowDTO struct {
+	LastMinute uint64 `json:"lastMinute"`
+	LastHour   uint64 `json:"lastHour"`
+}
+
+// NewWindowDTO is a WindowDTO constructor.
+func NewWindowDTO(w Window) WindowDTO {
+	return WindowDTO{
+		LastMinute: w.LastMinute,
+		LastHour:   w.LastHour,
+	}
+}
+
+// CountsDTO represents WebSocket response model.
+type CountsDTO struct {
+	Views  WindowDTO `json:"views"`
+	Clicks WindowDTO `json:"clicks"`
+}
+
+// NewCountsDTO is a CountsDTO constructor.
+func NewCountsDTO(c Counts) CountsDTO {
+	return CountsDTO{
+		Views:  NewWindowDTO(c.Views),
+		Clicks: NewWindowDTO(c.Clicks),
+	}
+}
+
+// UpdateDTO represents WebSocket response model, pushed periodically with counts
+// of all subscribed URLs. Error is set instead when a subscription is rejected.
+type UpdateDTO struct {
+	Counts map[string]CountsDTO `json:"counts,omitempty"`
+	Time   string               `json:"time,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// Handler defines WebSocket API for rolling counts.
+type Handler struct {
+	counters *Counters
+	interval time.Duration
+	upgrader websocket.Upgrader
+}
+
+// Subscribe implements handler for Subscribe WebSocket connection.
+// Initial URLs can be provided as url query parameters, and changed later with SubscribeDTO
+// messages. Counts of subscribed URLs are pushed right after subscribing and then periodically.
+func (h *Handler) Subscribe(c echo.Context) error {
+	projectID := project.ID(c)
+	urls := c.QueryParams()["url"]
+	if len(urls) > MaxSubscribedURLs {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("at most %d URLs can be subscribed to", MaxSubscribedURLs))
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Response(), c.Request(), nil)
+	if err != nil {
+		// the upgrader has already responded with an error
+		return nil
+	}
+	defer conn.Close()
+
+	done := make(chan struct{})
+	defer close(done)
+	subscriptions := make(chan SubscribeDTO)
+	go read(conn, subscriptions, done)
+
+	push := func(update UpdateDTO) error {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return conn.WriteJSON(update)
+	}
+
+	if len(urls) > 0 {
+		if err := push(h.update(projectID, urls)); err != nil {
+			return nil
+		}
+	}
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		var update UpdateDTO
+		select {
+		case subscription, ok := <-subscriptions:
+			if !ok {
+				return nil
+			}
+			if len(subscription.URLs) > MaxSubscribedURLs {
+				update.Error = fmt.Sprintf("at most %d URLs can be subscribed to", MaxSubscribedURLs)
+				break
+			}
+			urls = subscription.URLs
+			update = h.update(projectID, urls)
+		case <-ticker.C:
+			if len(urls) == 0 {
+				continue
+			}
+			update = h.update(projectID, urls)
+		}
+
+		if err := push(update); err != nil {
+			return nil
+		}
+	}
+}
+
+func (h *Handler) update(projectID uint, urls []string) UpdateDTO {
+	now := time.Now()
+	update := UpdateDTO{
+		Counts: make(map[string]CountsDTO, len(urls)),
+		Time:   now.Format(time.RFC3339),
+	}
+	for _, url := range urls {
+		update.Counts[url] = NewCountsDTO(h.counters.Get(projectID, url, now))
+	}
+	return update
+}
+
+// read forwards subscription messages until the connection fails or done is closed.
+func read(conn *websocket.Conn, subscriptions chan<- SubscribeDTO, done <-chan struct{}) {
+	defer close(subscriptions)
+
+	conn.SetReadLimit(maxMessageSize)
+	for {
+		var subscription SubscribeDTO
+		if err := conn.ReadJSON(&subscription); err != nil {
+			return
+		}
+		select {
+		case subscriptions <- subscription:
+		case <-done:
+			return
+		}
+	}
+}
+
+// NewHandler is a Handler constructor.
+// Connections are accepted from any origin, since they are authenticated with API keys
+// rather than cookies.
+func NewHandler(counters *Counters, interval time.Duration) Handler {
+	return Handler{
+		counters: counters,
+		interval: interval,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(*http.Request) bool { return true },
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package live
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+func TestHandlerSubscribe(t *testing.T) {
+	counters := NewCounters(10)
+	counters.Add(1, "test.url1", KindView, time.Now())
+	counters.Add(1, "test.url2", KindClick, time.Now())
+
+	h := NewHandler(counters, 10*time.Millisecond)
+
+	e := echo.New()
+	e.GET("/live", h.Subscribe, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(project.ContextKey, uint(1))
+			return next(c)
+		}
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/live?url=test.url1", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// counts of initial URLs are pushed right away
+	var update UpdateDTO
+	assert.NoError(t, conn.ReadJSON(&update))
+	assert.Equal(t, map[string]CountsDTO{
+		"test.url1": {Views: WindowDTO{LastMinute: 1, LastHour: 1}},
+	}, update.Counts)
+
+	// and periodically after that
+	counters.Add(1, "test.url1", KindView, time.Now())
+	update = readUntil(t, conn, func(u UpdateDTO) bool { return u.Counts["test.url1"].Views.LastMinute == 2 })
+	assert.Equal(t, uint64(2), update.Counts["test.url1"].Views.LastHour)
+
+	// a subscription replaces URLs
+	assert.NoError(t, conn.WriteJSON(SubscribeDTO{URLs: []string{"test.url2"}}))
+	update = readUntil(t, conn, func(u UpdateDTO) bool { return u.Counts["test.url2"].Clicks.LastMinute > 0 })
+	assert.Equal(t, map[string]CountsDTO{
+		"test.url2": {Clicks: WindowDTO{LastMinute: 1, LastHour: 1}},
+	}, update.Counts)
+
+	// a subscription to too many URLs is rejected
+	assert.NoError(t, conn.WriteJSON(SubscribeDTO{URLs: make([]string, MaxSubscribedURLs+1)}))
+	update = readUntil(t, conn, func(u UpdateDTO) bool { return u.Error != "" })
+	assert.Equal(t, "at most 100 URLs can be subscribed to", update.Error)
+}
+
+// readUntil skips pushed updates until one satisfies a condition.
+func readUntil(t *testing.T, conn *websocket.Conn, done func(UpdateDTO) bool) UpdateDTO {
+	t.Helper()
+
+	for {
+		var update UpdateDTO
+		if !assert.NoError(t, conn.ReadJSON(&update)) || done(update) {
+			return update
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type clickRepositoryStub struct {
+	click.Repository
+}
+
+func (clickRepositoryStub) Create(_ context.Context, c click.Click) (click.Click, error) {
+	c.ID = 1
+	return c, nil
+}
+
+func (clickRepositoryStub) CreateBatch(_ context.Context, clicks click.ClickCollection) (int64, error) {
+	return int64(len(clicks)), nil
+}
+
+func TestMetrics(t *testing.T) {
+	m := New(openDB(t))
+	m.RegisterQueue("test", func() int { return 3 })
+
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/clicks/:id", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	e.GET("/metrics", m.Handler())
+
+	repository := NewClickRepository(clickRepositoryStub{}, m)
+	_, err := repository.Create(context.Background(), click.Click{URL: "test.url1"})
+	assert.NoError(t, err)
+	_, err = repository.CreateBatch(context.Background(), click.ClickCollection{{URL: "test.url1"}, {URL: "test.url2"}})
+	assert.NoError(t, err)
+
+	for _, path := range []string{"/clicks/1", "/clicks/2", "/unknown/1"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	body := rec.Body.String()
+	for _, expected := range []string{
+		`clicks_and_views_http_requests_total{method="GET",route="/clicks/:id",status="200"} 2`,
+		`clicks_and_views_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`clicks_and_views_events_ingested_total{type="click"} 3`,
+		`clicks_and_views_repository_query_duration_seconds_count{operation="create",repository="click"} 1`,
+		`clicks_and_views_repository_query_duration_seconds_count{operation="create_batch",repository="click"} 1`,
+		`clicks_and_views_queue_depth{queue="test"} 3`,
+		`go_sql_open_connections{db_name="main"}`,
+	} {
+		assert.True(t, strings.Contains(body, expected), expected)
+	}
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+	sqlDB, err := gormDB.DB()
+	assert.NoError(t, err)
+
+	return sqlDB
+}
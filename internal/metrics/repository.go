@@ -0,0 +1,207 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// ClickRepository decorates click.Repository with query durations and ingested events counts.
+type ClickRepository struct {
+	click.Repository
+	metrics *Metrics
+}
+
+// Create implements click.Repository interface.
+func (r *ClickRepository) Create(ctx context.Context, c click.Click) (click.Click, error) {
+	defer r.metrics.ObserveQuery("click", "create", time.Now())
+
+	created, err := r.Repository.Create(ctx, c)
+	if err == nil {
+		r.metrics.EventsIngested("click", 1)
+	}
+
+	return created, err
+}
+
+// CreateBatch implements click.Repository interface.
+func (r *ClickRepository) CreateBatch(ctx context.Context, clicks click.ClickCollection) (int64, error) {
+	defer r.metrics.ObserveQuery("click", "create_batch", time.Now())
+
+	inserted, err := r.Repository.CreateBatch(ctx, clicks)
+	r.metrics.EventsIngested("click", int(inserted))
+
+	return inserted, err
+}
+
+// Filter implements click.Repository interface.
+func (r *ClickRepository) Filter(ctx context.Context, filter click.Filter) (click.ClickCollection, error) {
+	defer r.metrics.ObserveQuery("click", "filter", time.Now())
+
+	return r.Repository.Filter(ctx, filter)
+}
+
+// Count implements click.Repository interface.
+func (r *ClickRepository) Count(ctx context.Context, filter click.Filter) (int64, error) {
+	defer r.metrics.ObserveQuery("click", "count", time.Now())
+
+	return r.Repository.Count(ctx, filter)
+}
+
+// Aggregate implements click.Repository interface.
+func (r *ClickRepository) Aggregate(ctx context.Context, filter click.Filter, groupBy click.GroupBy) (click.GroupCollection, error) {
+	defer r.metrics.ObserveQuery("click", "aggregate", time.Now())
+
+	return r.Repository.Aggregate(ctx, filter, groupBy)
+}
+
+// Get implements click.Repository interface.
+func (r *ClickRepository) Get(ctx context.Context, projectID, id uint) (click.Click, error) {
+	defer r.metrics.ObserveQuery("click", "get", time.Now())
+
+	return r.Repository.Get(ctx, projectID, id)
+}
+
+// Update implements click.Repository interface.
+func (r *ClickRepository) Update(ctx context.Context, c click.Click) (click.Click, error) {
+	defer r.metrics.ObserveQuery("click", "update", time.Now())
+
+	return r.Repository.Update(ctx, c)
+}
+
+// Delete implements click.Repository interface.
+func (r *ClickRepository) Delete(ctx context.Context, projectID, id uint) error {
+	defer r.metrics.ObserveQuery("click", "delete", time.Now())
+
+	return r.Repository.Delete(ctx, projectID, id)
+}
+
+// Restore implements click.Repository interface.
+func (r *ClickRepository) Restore(ctx context.Context, projectID, id uint) (click.Click, error) {
+	defer r.metrics.ObserveQuery("click", "restore", time.Now())
+
+	return r.Repository.Restore(ctx, projectID, id)
+}
+
+// DeleteMatching implements click.Repository interface.
+func (r *ClickRepository) DeleteMatching(ctx context.Context, filter click.Filter) (int64, error) {
+	defer r.metrics.ObserveQuery("click", "delete_matching", time.Now())
+
+	return r.Repository.DeleteMatching(ctx, filter)
+}
+
+// Purge implements click.Repository interface.
+func (r *ClickRepository) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	defer r.metrics.ObserveQuery("click", "purge", time.Now())
+
+	return r.Repository.Purge(ctx, projectID, deletedBefore)
+}
+
+// NewClickRepository is a ClickRepository constructor.
+func NewClickRepository(repository click.Repository, metrics *Metrics) *ClickRepository {
+	return &ClickRepository{
+		Repository: repository,
+		metrics:    metrics,
+	}
+}
+
+// ViewRepository decorates view.Repository with query durations and ingested events counts.
+type ViewRepository struct {
+	view.Repository
+	metrics *Metrics
+}
+
+// Create implements view.Repository interface.
+func (r *ViewRepository) Create(ctx context.Context, v view.View) (view.View, error) {
+	defer r.metrics.ObserveQuery("view", "create", time.Now())
+
+	created, err := r.Repository.Create(ctx, v)
+	if err == nil {
+		r.metrics.EventsIngested("view", 1)
+	}
+
+	return created, err
+}
+
+// CreateBatch implements view.Repository interface.
+func (r *ViewRepository) CreateBatch(ctx context.Context, views view.ViewCollection) (int64, error) {
+	defer r.metrics.ObserveQuery("view", "create_batch", time.Now())
+
+	inserted, err := r.Repository.CreateBatch(ctx, views)
+	r.metrics.EventsIngested("view", int(inserted))
+
+	return inserted, err
+}
+
+// Filter implements view.Repository interface.
+func (r *ViewRepository) Filter(ctx context.Context, filter view.Filter) (view.ViewCollection, error) {
+	defer r.metrics.ObserveQuery("view", "filter", time.Now())
+
+	return r.Repository.Filter(ctx, filter)
+}
+
+// Count implements view.Repository interface.
+func (r *ViewRepository) Count(ctx context.Context, filter view.Filter) (int64, error) {
+	defer r.metrics.ObserveQuery("view", "count", time.Now())
+
+	return r.Repository.Count(ctx, filter)
+}
+
+// Aggregate implements view.Repository interface.
+func (r *ViewRepository) Aggregate(ctx context.Context, filter view.Filter, groupBy view.GroupBy) (view.GroupCollection, error) {
+	defer r.metrics.ObserveQuery("view", "aggregate", time.Now())
+
+	return r.Repository.Aggregate(ctx, filter, groupBy)
+}
+
+// Get implements view.Repository interface.
+func (r *ViewRepository) Get(ctx context.Context, projectID, id uint) (view.View, error) {
+	defer r.metrics.ObserveQuery("view", "get", time.Now())
+
+	return r.Repository.Get(ctx, projectID, id)
+}
+
+// Update implements view.Repository interface.
+func (r *ViewRepository) Update(ctx context.Context, v view.View) (view.View, error) {
+	defer r.metrics.ObserveQuery("view", "update", time.Now())
+
+	return r.Repository.Update(ctx, v)
+}
+
+// Delete implements view.Repository interface.
+func (r *ViewRepository) Delete(ctx context.Context, projectID, id uint) error {
+	defer r.metrics.ObserveQuery("view", "delete", time.Now())
+
+	return r.Repository.Delete(ctx, projectID, id)
+}
+
+// Restore implements view.Repository interface.
+func (r *ViewRepository) Restore(ctx context.Context, projectID, id uint) (view.View, error) {
+	defer r.metrics.ObserveQuery("view", "restore", time.Now())
+
+	return r.Repository.Restore(ctx, projectID, id)
+}
+
+// DeleteMatching implements view.Repository interface.
+func (r *ViewRepository) DeleteMatching(ctx context.Context, filter view.Filter) (int64, error) {
+	defer r.metrics.ObserveQuery("view", "delete_matching", time.Now())
+
+	return r.Repository.DeleteMatching(ctx, filter)
+}
+
+// Purge implements view.Repository interface.
+func (r *ViewRepository) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	defer r.metrics.ObserveQuery("view", "purge", time.Now())
+
+	return r.Repository.Purge(ctx, projectID, deletedBefore)
+}
+
+// NewViewRepository is a ViewRepository constructor.
+func NewViewRepository(repository view.Repository, metrics *Metrics) *ViewRepository {
+	return &ViewRepository{
+		Repository: repository,
+		metrics:    metrics,
+	}
+}
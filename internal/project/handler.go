@@ -0,0 +1,202 @@
+package project
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ProjectDTO represents HTTP request/response model.
+type ProjectDTO struct {
+	ID        uint   `json:"id,omitempty"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// ToDomain maps DTO model into domain model.
+func (p ProjectDTO) ToDomain() Project {
+	return Project{
+		Name: p.Name,
+	}
+}
+
+// NewProjectDTO is a ProjectDTO constructor.
+func NewProjectDTO(p Project) ProjectDTO {
+	return ProjectDTO{
+		ID:        p.ID,
+		Name:      p.Name,
+		CreatedAt: p.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// ProjectDTOCollection represents ProjectDTO collection.
+type ProjectDTOCollection []ProjectDTO
+
+// NewProjectDTOCollection maps domain models into DTO models.
+func NewProjectDTOCollection(projectCollection ProjectCollection) ProjectDTOCollection {
+	projectDTOCollection := make(ProjectDTOCollection, 0, len(projectCollection))
+
+	for _, project := range projectCollection {
+		projectDTOCollection = append(projectDTOCollection, NewProjectDTO(project))
+	}
+
+	return projectDTOCollection
+}
+
+// KeyDTO represents HTTP request/response model.
+// Key is populated only in the response to key creation.
+type KeyDTO struct {
+	ID        uint   `json:"id,omitempty"`
+	ProjectID uint   `json:"projectId,omitempty"`
+	Scope     Scope  `json:"scope"`
+	Prefix    string `json:"prefix,omitempty"`
+	Key       string `json:"key,omitempty"`
+	CreatedAt string `json:"createdAt,omitempty"`
+}
+
+// NewKeyDTO is a KeyDTO constructor.
+func NewKeyDTO(k Key) KeyDTO {
+	return KeyDTO{
+		ID:        k.ID,
+		ProjectID: k.ProjectID,
+		Scope:     k.Scope,
+		Prefix:    k.Prefix,
+		CreatedAt: k.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// KeyDTOCollection represents KeyDTO collection.
+type KeyDTOCollection []KeyDTO
+
+// NewKeyDTOCollection maps domain models into DTO models.
+func NewKeyDTOCollection(keyCollection KeyCollection) KeyDTOCollection {
+	keyDTOCollection := make(KeyDTOCollection, 0, len(keyCollection))
+
+	for _, key := range keyCollection {
+		keyDTOCollection = append(keyDTOCollection, NewKeyDTO(key))
+	}
+
+	return keyDTOCollection
+}
+
+// Handler defines all admin API methods for Project and Key.
+type Handler struct {
+	projectRepository Repository
+}
+
+// CreateProject implements handler for Create Project HTTP request.
+func (h *Handler) CreateProject(c echo.Context) error {
+	var projectDTO ProjectDTO
+	if err := c.Bind(&projectDTO); err != nil {
+		return err
+	}
+	if projectDTO.Name == "" {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "name is required")
+	}
+
+	project, err := h.projectRepository.CreateProject(c.Request().Context(), projectDTO.ToDomain())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, NewProjectDTO(project))
+}
+
+// ListProjects implements handler for List Projects HTTP request.
+func (h *Handler) ListProjects(c echo.Context) error {
+	projects, err := h.projectRepository.ListProjects(c.Request().Context())
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewProjectDTOCollection(projects))
+}
+
+// CreateKey implements handler for Create Key HTTP request.
+// The generated key is returned only once, in this response.
+func (h *Handler) CreateKey(c echo.Context) error {
+	projectID, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var keyDTO KeyDTO
+	if err := c.Bind(&keyDTO); err != nil {
+		return err
+	}
+	if !keyDTO.Scope.Valid() {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "scope must be one of read, write or manage")
+	}
+
+	if _, err := h.projectRepository.GetProject(c.Request().Context(), projectID); err != nil {
+		return notFound(err)
+	}
+
+	key, plain, err := NewKey(projectID, keyDTO.Scope)
+	if err != nil {
+		return err
+	}
+	key, err = h.projectRepository.CreateKey(c.Request().Context(), key)
+	if err != nil {
+		return err
+	}
+
+	response := NewKeyDTO(key)
+	response.Key = plain
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// ListKeys implements handler for List Keys HTTP request.
+func (h *Handler) ListKeys(c echo.Context) error {
+	projectID, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	keys, err := h.projectRepository.ListKeys(c.Request().Context(), projectID)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewKeyDTOCollection(keys))
+}
+
+// DeleteKey implements handler for Delete Key HTTP request.
+func (h *Handler) DeleteKey(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.projectRepository.DeleteKey(c.Request().Context(), id); err != nil {
+		return notFound(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// NewHandler is a Handler constructor.
+func NewHandler(projectRepository Repository) Handler {
+	return Handler{
+		projectRepository: projectRepository,
+	}
+}
+
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+package project
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type ProjectRepositoryMock struct {
+	mock.Mock
+}
+
+func (m *ProjectRepositoryMock) CreateProject(ctx context.Context, project Project) (Project, error) {
+	args := m.Called(ctx, project)
+	return args.Get(0).(Project), args.Error(1)
+}
+
+func (m *ProjectRepositoryMock) ListProjects(ctx context.Context) (ProjectCollection, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(ProjectCollection), args.Error(1)
+}
+
+func (m *ProjectRepositoryMock) GetProject(ctx context.Context, id uint) (Project, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(Project), args.Error(1)
+}
+
+func (m *ProjectRepositoryMock) CreateKey(ctx context.Context, key Key) (Key, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(Key), args.Error(1)
+}
+
+func (m *ProjectRepositoryMock) ListKeys(ctx context.Context, projectID uint) (KeyCollection, error) {
+	args := m.Called(ctx, projectID)
+	return args.Get(0).(KeyCollection), args.Error(1)
+}
+
+func (m *ProjectRepositoryMock) DeleteKey(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *ProjectRepositoryMock) FindKeyByHash(ctx context.Context, hash string) (Key, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(Key), args.Error(1)
+}
+
+func TestHandlerCreateProject(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/projects", strings.NewReader(`{"name":"test"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	timeNow := time.Now()
+
+	projectRepository := &ProjectRepositoryMock{}
+	projectRepository.
+		On("CreateProject", c.Request().Context(), Project{Name: "test"}).
+		Return(Project{ID: 1, Name: "test", CreatedAt: timeNow}, nil).Once()
+
+	h := &Handler{projectRepository: projectRepository}
+
+	if assert.NoError(t, h.CreateProject(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.JSONEq(t, `{"id":1,"name":"test","createdAt":"`+timeNow.Format(time.RFC3339)+`"}`, rec.Body.String())
+	}
+}
+
+func TestHandlerCreateKey(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/admin/projects/1/keys", strings.NewReader(`{"scope":"read"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	projectRepository := &ProjectRepositoryMock{}
+	projectRepository.
+		On("GetProject", c.Request().Context(), uint(1)).
+		Return(Project{ID: 1}, nil).Once()
+	var stored Key
+	projectRepository.
+		On("CreateKey", c.Request().Context(), mock.MatchedBy(func(k Key) bool {
+			stored = k
+			return k.ProjectID == 1 && k.Scope == ScopeRead
+		})).
+		Return(Key{ID: 7, ProjectID: 1, Scope: ScopeRead, Prefix: "cav_r_1234"}, nil).Once()
+
+	h := &Handler{projectRepository: projectRepository}
+
+	if assert.NoError(t, h.CreateKey(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+
+		var response KeyDTO
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Equal(t, uint(7), response.ID)
+		assert.Equal(t, ScopeRead, response.Scope)
+		assert.True(t, strings.HasPrefix(response.Key, stored.Prefix))
+		assert.Equal(t, HashKey(response.Key), stored.Hash)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	writeKey := Key{ID: 1, ProjectID: 3, Scope: ScopeWrite}
+
+	projectRepository := &ProjectRepositoryMock{}
+	projectRepository.On("FindKeyByHash", mock.Anything, HashKey("write-key")).Return(writeKey, nil)
+	projectRepository.On("FindKeyByHash", mock.Anything, HashKey("broken-key")).Return(Key{}, errors.New("database is locked"))
+	projectRepository.On("FindKeyByHash", mock.Anything, mock.Anything).Return(Key{}, ErrNotFound)
+
+	tests := []struct {
+		testName     string
+		scope        Scope
+		key          string
+		expectedCode int
+	}{
+		{
+			testName:     "valid key",
+			scope:        ScopeWrite,
+			key:          "write-key",
+			expectedCode: http.StatusOK,
+		},
+		{
+			testName:     "key used outside of its scope",
+			scope:        ScopeRead,
+			key:          "write-key",
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			testName:     "key lookup failure",
+			scope:        ScopeWrite,
+			key:          "broken-key",
+			expectedCode: http.StatusInternalServerError,
+		},
+		{
+			testName:     "missing key",
+			scope:        ScopeWrite,
+			key:          "",
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			testName:     "unknown key",
+			scope:        ScopeWrite,
+			key:          "unknown-key",
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			e.GET("/", func(c echo.Context) error {
+				assert.Equal(t, writeKey.ProjectID, ID(c))
+				return c.NoContent(http.StatusOK)
+			}, Auth(projectRepository, test.scope))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+test.key)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expectedCode, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// ContextKey is the echo.Context key under which the authenticated project ID is stored.
+const ContextKey = "projectID"
+
+// errScope is returned by the validator when a valid key is used outside of its scope.
+var errScope = errors.New("key is not allowed to perform this operation")
+
+// errLookup wraps errors of looking up a key, which are failures of the server rather than of authentication.
+var errLookup = errors.New("looking up API key")
+
+// DefaultKeyLookup reads the key from bearer token.
+const DefaultKeyLookup = "header:" + echo.HeaderAuthorization
+
+// Auth returns middleware which authenticates requests with a project API key of a given scope.
+// The key is expected as a bearer token. On success, ID of the key's project is stored
+// in echo.Context and can be retrieved with ID.
+func Auth(repository Repository, scope Scope) echo.MiddlewareFunc {
+	return AuthWithLookup(repository, scope, DefaultKeyLookup)
+}
+
+// AuthWithLookup is Auth which reads the key from places listed in keyLookup,
+// in the format of middleware.KeyAuthConfig.KeyLookup, e.g. "header:Authorization,query:key".
+// It's meant for clients which can't set headers, such as browser's EventSource.
+func AuthWithLookup(repository Repository, scope Scope, keyLookup string) echo.MiddlewareFunc {
+	config := middleware.DefaultKeyAuthConfig
+	config.KeyLookup = keyLookup
+	config.Validator = func(plain string, c echo.Context) (bool, error) {
+		key, err := repository.FindKeyByHash(c.Request().Context(), HashKey(plain))
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("%w: %w", errLookup, err)
+		}
+		if key.Scope != scope {
+			return false, errScope
+		}
+
+		c.Set(ContextKey, key.ProjectID)
+		return true, nil
+	}
+	config.ErrorHandler = Unauthorized
+
+	return middleware.KeyAuthWithConfig(config)
+}
+
+// Unauthorized is an error handler of key authentication middleware, which responds with 401
+// to requests without a valid key, including the ones missing a key, which are rejected with 400 by default.
+// Keys used outside of their scope are rejected with 403, failures to look up a key are returned unchanged.
+func Unauthorized(err error, c echo.Context) error {
+	var missing *middleware.ErrKeyAuthMissing
+	switch {
+	case errors.As(err, &missing):
+		return echo.NewHTTPError(http.StatusUnauthorized, "missing API key").SetInternal(err)
+	case errors.Is(err, errScope):
+		return echo.NewHTTPError(http.StatusForbidden, errScope.Error()).SetInternal(err)
+	case errors.Is(err, errLookup):
+		return err
+	}
+	return echo.NewHTTPError(http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized)).SetInternal(err)
+}
+
+// ID returns ID of the project authenticated by Auth middleware, or zero if there is none.
+func ID(c echo.Context) uint {
+	id, _ := c.Get(ContextKey).(uint)
+	return id
+}
@@ -0,0 +1,94 @@
+// project package provides tenancy for clicks and views.
+// Every project owns its events and a set of API keys used to ingest and query them.
+package project
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// Scope defines what an API key is allowed to do.
+type Scope string
+
+// Available key scopes. Write keys are embedded in tracked pages, so operations which change
+// or remove existing data require a manage key, which is meant to be kept on servers.
+const (
+	ScopeRead   Scope = "read"
+	ScopeWrite  Scope = "write"
+	ScopeManage Scope = "manage"
+)
+
+// Valid reports whether s is one of the known scopes.
+func (s Scope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite || s == ScopeManage
+}
+
+// ErrNotFound is returned when requested entity doesn't exist.
+var ErrNotFound = errors.New("not found")
+
+// Project represents entity model of a single tenant.
+type Project struct {
+	ID        uint
+	Name      string
+	CreatedAt time.Time
+}
+
+// ProjectCollection represents a collection of Project domain entities.
+type ProjectCollection []Project
+
+// Key represents entity model of a single API key.
+// Only the hash of a key is stored, the key itself is known only at creation time.
+type Key struct {
+	ID        uint
+	ProjectID uint
+	Scope     Scope
+	Prefix    string
+	Hash      string
+	CreatedAt time.Time
+}
+
+// KeyCollection represents a collection of Key domain entities.
+type KeyCollection []Key
+
+// keyPrefixLength is the number of leading key characters kept to help identifying keys.
+const keyPrefixLength = 10
+
+// NewKey generates a new random API key for a given project.
+// It returns the Key entity and the plain text key which must be handed over to the user.
+func NewKey(projectID uint, scope Scope) (Key, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Key{}, "", err
+	}
+	plain := "cav_" + string(scope[0]) + "_" + base64.RawURLEncoding.EncodeToString(b)
+
+	return Key{
+		ProjectID: projectID,
+		Scope:     scope,
+		Prefix:    plain[:keyPrefixLength],
+		Hash:      HashKey(plain),
+	}, plain, nil
+}
+
+// HashKey returns the hash under which a plain text key is stored.
+// Keys are long random strings, so a fast hash function is sufficient.
+func HashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// Repository defines a storage API for Project and Key entities.
+type Repository interface {
+	CreateProject(context.Context, Project) (Project, error)
+	ListProjects(context.Context) (ProjectCollection, error)
+	GetProject(context.Context, uint) (Project, error)
+	CreateKey(context.Context, Key) (Key, error)
+	ListKeys(context.Context, uint) (KeyCollection, error)
+	DeleteKey(context.Context, uint) error
+	FindKeyByHash(context.Context, string) (Key, error)
+}
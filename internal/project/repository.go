@@ -0,0 +1,205 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProjectDAO represents a single database entry.
+type ProjectDAO struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	Name      string
+}
+
+// TableName overrides the table name used by ProjectDAO to 'projects'
+func (ProjectDAO) TableName() string {
+	return "projects"
+}
+
+// ToDomain maps database model into domain model.
+func (p *ProjectDAO) ToDomain() Project {
+	return Project{
+		ID:        p.ID,
+		Name:      p.Name,
+		CreatedAt: p.CreatedAt,
+	}
+}
+
+// ProjectDAOCollection represents a collection of Project database model.
+type ProjectDAOCollection []ProjectDAO
+
+// ToDomain maps DAO models into domain models.
+func (pp ProjectDAOCollection) ToDomain() ProjectCollection {
+	r := make(ProjectCollection, 0, len(pp))
+
+	for _, p := range pp {
+		r = append(r, p.ToDomain())
+	}
+
+	return r
+}
+
+// NewProjectDAO maps Project entity model into database model.
+func NewProjectDAO(p Project) ProjectDAO {
+	return ProjectDAO{
+		ID:        p.ID,
+		CreatedAt: p.CreatedAt,
+		Name:      p.Name,
+	}
+}
+
+// KeyDAO represents a single database entry.
+type KeyDAO struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	ProjectID uint `gorm:"index"`
+	Scope     string
+	Prefix    string
+	Hash      string `gorm:"uniqueIndex"`
+}
+
+// TableName overrides the table name used by KeyDAO to 'api_keys'
+func (KeyDAO) TableName() string {
+	return "api_keys"
+}
+
+// ToDomain maps database model into domain model.
+func (k *KeyDAO) ToDomain() Key {
+	return Key{
+		ID:        k.ID,
+		ProjectID: k.ProjectID,
+		Scope:     Scope(k.Scope),
+		Prefix:    k.Prefix,
+		Hash:      k.Hash,
+		CreatedAt: k.CreatedAt,
+	}
+}
+
+// KeyDAOCollection represents a collection of Key database model.
+type KeyDAOCollection []KeyDAO
+
+// ToDomain maps DAO models into domain models.
+func (kk KeyDAOCollection) ToDomain() KeyCollection {
+	r := make(KeyCollection, 0, len(kk))
+
+	for _, k := range kk {
+		r = append(r, k.ToDomain())
+	}
+
+	return r
+}
+
+// NewKeyDAO maps Key entity model into database model.
+func NewKeyDAO(k Key) KeyDAO {
+	return KeyDAO{
+		ID:        k.ID,
+		CreatedAt: k.CreatedAt,
+		ProjectID: k.ProjectID,
+		Scope:     string(k.Scope),
+		Prefix:    k.Prefix,
+		Hash:      k.Hash,
+	}
+}
+
+// SQLiteRepository is a SQLite implementation of Project repository.
+type SQLiteRepository struct {
+	db *gorm.DB
+}
+
+// CreateProject persists Project entity.
+func (r *SQLiteRepository) CreateProject(ctx context.Context, project Project) (Project, error) {
+	dao := NewProjectDAO(project)
+
+	if err := r.db.WithContext(ctx).Create(&dao).Error; err != nil {
+		return Project{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// ListProjects returns all Projects.
+func (r *SQLiteRepository) ListProjects(ctx context.Context) (ProjectCollection, error) {
+	var projects ProjectDAOCollection
+
+	if err := r.db.WithContext(ctx).Order("id").Find(&projects).Error; err != nil {
+		return ProjectCollection{}, err
+	}
+
+	return projects.ToDomain(), nil
+}
+
+// GetProject returns a single Project. ErrNotFound is returned if it doesn't exist.
+func (r *SQLiteRepository) GetProject(ctx context.Context, id uint) (Project, error) {
+	var dao ProjectDAO
+
+	err := r.db.WithContext(ctx).First(&dao, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Project{}, ErrNotFound
+	}
+	if err != nil {
+		return Project{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// CreateKey persists Key entity.
+func (r *SQLiteRepository) CreateKey(ctx context.Context, key Key) (Key, error) {
+	dao := NewKeyDAO(key)
+
+	if err := r.db.WithContext(ctx).Create(&dao).Error; err != nil {
+		return Key{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// ListKeys returns all Keys of a given Project.
+func (r *SQLiteRepository) ListKeys(ctx context.Context, projectID uint) (KeyCollection, error) {
+	var keys KeyDAOCollection
+
+	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id").Find(&keys).Error; err != nil {
+		return KeyCollection{}, err
+	}
+
+	return keys.ToDomain(), nil
+}
+
+// DeleteKey revokes a Key. ErrNotFound is returned if it doesn't exist.
+func (r *SQLiteRepository) DeleteKey(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Delete(&KeyDAO{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// FindKeyByHash returns the Key stored under a given hash. ErrNotFound is returned if it doesn't exist.
+func (r *SQLiteRepository) FindKeyByHash(ctx context.Context, hash string) (Key, error) {
+	var dao KeyDAO
+
+	err := r.db.WithContext(ctx).Where("hash = ?", hash).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Key{}, ErrNotFound
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// NewSQLiteRepository is a SQLiteRepository constructor.
+func NewSQLiteRepository(db *gorm.DB) *SQLiteRepository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,81 @@
+package project
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCreateProject(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	project, err := sqliteRepo.CreateProject(context.Background(), Project{Name: "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), project.ID)
+
+	projects, err := sqliteRepo.ListProjects(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, projects, 1) {
+		assert.Equal(t, project.ID, projects[0].ID)
+		assert.Equal(t, "test", projects[0].Name)
+	}
+
+	_, err = sqliteRepo.GetProject(context.Background(), 2)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestKeys(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	sqliteRepo := SQLiteRepository{db: gormDB}
+
+	key, plain, err := NewKey(1, ScopeWrite)
+	assert.NoError(t, err)
+	assert.NotContains(t, key.Hash, plain)
+
+	key, err = sqliteRepo.CreateKey(context.Background(), key)
+	assert.NoError(t, err)
+
+	found, err := sqliteRepo.FindKeyByHash(context.Background(), HashKey(plain))
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, ScopeWrite, found.Scope)
+
+	keys, err := sqliteRepo.ListKeys(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	assert.NoError(t, sqliteRepo.DeleteKey(context.Background(), key.ID))
+	assert.ErrorIs(t, sqliteRepo.DeleteKey(context.Background(), key.ID), ErrNotFound)
+
+	_, err = sqliteRepo.FindKeyByHash(context.Background(), HashKey(plain))
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&ProjectDAO{}, &KeyDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"gorm.io/gorm"
+)
+
+// Type is the type of a field, which determines operators and values it can be compared with.
+type Type int
+
+// Supported field types.
+const (
+	// String fields support =, !=, ~ (contains) and !~ (doesn't contain), compared with quoted strings.
+	String Type = iota
+	// Int fields support =, !=, <, <=, > and >=, compared with whole numbers.
+	Int
+	// Time fields support =, !=, <, <=, > and >=, compared with times accepted by timeparam.Parse.
+	Time
+)
+
+// ordered maps operators of types with ordered values to their SQL form.
+var ordered = map[string]string{
+	OpEqual:          "%s = ?",
+	OpNotEqual:       "%s <> ?",
+	OpLess:           "%s < ?",
+	OpLessOrEqual:    "%s <= ?",
+	OpGreater:        "%s > ?",
+	OpGreaterOrEqual: "%s >= ?",
+}
+
+// operators maps operators supported by each Type to their SQL form.
+var operators = map[Type]map[string]string{
+	String: {
+		OpEqual:       "%s = ?",
+		OpNotEqual:    "%s <> ?",
+		OpContains:    "instr(%s, ?) > 0",
+		OpNotContains: "instr(%s, ?) = 0",
+	},
+	Int:  ordered,
+	Time: ordered,
+}
+
+// Field maps a field of a query to an SQL expression of a given Type.
+type Field struct {
+	SQL  string
+	Type Type
+}
+
+// Fields are the fields a query can refer to, by their names.
+type Fields map[string]Field
+
+// names returns sorted names of the fields.
+func (f Fields) names() string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// Condition is a parameterized SQL condition compiled from a query.
+// Zero Condition holds for all events.
+type Condition struct {
+	SQL  string
+	Args []any
+}
+
+// IsZero reports whether the Condition is empty.
+func (c Condition) IsZero() bool {
+	return c.SQL == ""
+}
+
+// Apply adds a Condition to a query.
+func Apply(tx *gorm.DB, c Condition) *gorm.DB {
+	if c.IsZero() {
+		return tx
+	}
+	return tx.Where(c.SQL, c.Args...)
+}
+
+// Compile compiles an expression into a Condition on given fields. Values are always passed as arguments,
+// relative times are resolved against now. Unknown fields, unsupported operators and invalid values
+// are reported as *Error.
+func Compile(expr Expr, fields Fields, now time.Time) (Condition, error) {
+	var b strings.Builder
+	var args []any
+	if err := compileGroup(&b, &args, expr, fields, now); err != nil {
+		return Condition{}, err
+	}
+
+	return Condition{SQL: b.String(), Args: args}, nil
+}
+
+// ParseAndCompile parses a query and compiles it into a Condition on given fields. Empty query is a zero Condition.
+func ParseAndCompile(q string, fields Fields, now time.Time) (Condition, error) {
+	if strings.TrimSpace(q) == "" {
+		return Condition{}, nil
+	}
+
+	expr, err := Parse(q)
+	if err != nil {
+		return Condition{}, err
+	}
+
+	return Compile(expr, fields, now)
+}
+
+func compile(b *strings.Builder, args *[]any, expr Expr, fields Fields, now time.Time) error {
+	switch e := expr.(type) {
+	case And:
+		return compileBinary(b, args, "AND", e.Left, e.Right, fields, now)
+	case Or:
+		return compileBinary(b, args, "OR", e.Left, e.Right, fields, now)
+	case Not:
+		b.WriteString("NOT (")
+		if err := compile(b, args, e.X, fields, now); err != nil {
+			return err
+		}
+		b.WriteString(")")
+		return nil
+	case Comparison:
+		field, ok := fields[e.Field]
+		if !ok {
+			return &Error{Column: e.Col, Message: fmt.Sprintf("unknown field %q, expected one of %s", e.Field, fields.names())}
+		}
+		format, ok := operators[field.Type][e.Op]
+		if !ok {
+			return &Error{Column: e.Col, Message: fmt.Sprintf("operator %q can't be used with field %q", e.Op, e.Field)}
+		}
+		arg, err := value(e.Value, field.Type, now)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, format, field.SQL)
+		*args = append(*args, arg)
+		return nil
+	default:
+		return fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func compileBinary(b *strings.Builder, args *[]any, op string, left, right Expr, fields Fields, now time.Time) error {
+	if err := compileGroup(b, args, left, fields, now); err != nil {
+		return err
+	}
+	b.WriteString(" " + op + " ")
+	return compileGroup(b, args, right, fields, now)
+}
+
+// compileGroup compiles an expression, in parentheses when it combines others, so it is combined
+// with further ones as a whole.
+func compileGroup(b *strings.Builder, args *[]any, expr Expr, fields Fields, now time.Time) error {
+	switch expr.(type) {
+	case And, Or:
+	default:
+		return compile(b, args, expr, fields, now)
+	}
+
+	b.WriteString("(")
+	if err := compile(b, args, expr, fields, now); err != nil {
+		return err
+	}
+	b.WriteString(")")
+	return nil
+}
+
+// value converts a literal into an argument of a given Type.
+func value(v Value, t Type, now time.Time) (any, error) {
+	switch t {
+	case Int:
+		n, err := strconv.ParseInt(v.Text, 10, 64)
+		if err != nil {
+			return nil, &Error{Column: v.Col, Message: fmt.Sprintf("expected a whole number, found %q", v.Text)}
+		}
+		return n, nil
+	case Time:
+		at, err := timeparam.Parse(v.Text, now)
+		if err != nil {
+			return nil, &Error{Column: v.Col, Message: err.Error()}
+		}
+		return at, nil
+	default:
+		if !v.Quoted {
+			return nil, &Error{Column: v.Col, Message: fmt.Sprintf("expected a quoted string, found %q", v.Text)}
+		}
+		return v.Text, nil
+	}
+}
@@ -0,0 +1,114 @@
+package query
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var testFields = Fields{
+	"id":        {SQL: "id", Type: Int},
+	"url":       {SQL: "url", Type: String},
+	"createdAt": {SQL: "created_at", Type: Time},
+}
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := map[string]struct {
+		query     string
+		condition Condition
+		err       string
+	}{
+		"empty": {query: "", condition: Condition{}},
+		"comparison": {
+			query:     `url = "/a"`,
+			condition: Condition{SQL: "url = ?", Args: []any{"/a"}},
+		},
+		"connectives": {
+			query: `url ~ "/blog/" and (id >= 10 or not createdAt > now-1d)`,
+			condition: Condition{
+				SQL:  "(instr(url, ?) > 0 AND (id >= ? OR NOT (created_at > ?)))",
+				Args: []any{"/blog/", int64(10), now.Add(-24 * time.Hour)},
+			},
+		},
+		"nested not": {
+			query:     `not (url !~ "a" or url != "b")`,
+			condition: Condition{SQL: "NOT (instr(url, ?) = 0 OR url <> ?)", Args: []any{"a", "b"}},
+		},
+		"absolute time": {
+			query:     `createdAt < "2024-01-02T04:04:05+01:00"`,
+			condition: Condition{SQL: "created_at < ?", Args: []any{now}},
+		},
+		"unknown field":       {query: `device = "mobile"`, err: `invalid query at column 1: unknown field "device", expected one of createdAt, id, url`},
+		"unsupported op":      {query: `id ~ "1"`, err: `invalid query at column 1: operator "~" can't be used with field "id"`},
+		"unquoted string":     {query: `url = blog`, err: `invalid query at column 7: expected a quoted string, found "blog"`},
+		"not a number":        {query: `id > ten`, err: `invalid query at column 6: expected a whole number, found "ten"`},
+		"not a time":          {query: `id = 1 and createdAt > yesterday`, err: `invalid query at column 24: invalid time "yesterday", expected RFC 3339, Unix epoch seconds or now-<n><s|m|h|d|w>`},
+		"syntax error passed": {query: `url =`, err: `invalid query at column 6: expected value after "=", found end of query`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			condition, err := ParseAndCompile(test.query, testFields, now)
+			if test.err != "" {
+				assert.EqualError(t, err, test.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.condition, condition)
+		})
+	}
+}
+
+type eventDAO struct {
+	ID        uint `gorm:"primarykey"`
+	URL       string
+	CreatedAt time.Time
+}
+
+func TestApply(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	defer func() {
+		os.Remove("gorm.db")
+	}()
+	assert.NoError(t, gormDB.AutoMigrate(&eventDAO{}))
+
+	now := time.Now().UTC()
+	gormDB.Create([]eventDAO{
+		{URL: "/blog/a", CreatedAt: now.Add(-time.Hour)},
+		{URL: "/blog/b", CreatedAt: now.Add(-48 * time.Hour)},
+		{URL: "/pricing", CreatedAt: now.Add(-time.Hour)},
+		{URL: `" OR 1=1 --`, CreatedAt: now.Add(-time.Hour)},
+	})
+	assert.NoError(t, gormDB.Error)
+
+	tests := map[string]struct {
+		query    string
+		expected []uint
+	}{
+		"no query":        {"", []uint{1, 3, 4}},
+		"or":              {`url = "/pricing" or id = 1`, []uint{1, 3}},
+		"and with others": {`url ~ "/blog/" or url = "/pricing"`, []uint{1, 3}},
+		"relative time":   {`createdAt > now-1d and not url ~ "/blog/"`, []uint{3, 4}},
+		"quotes are data": {`url = "\" OR 1=1 --"`, []uint{4}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			condition, err := ParseAndCompile(test.query, testFields, now)
+			assert.NoError(t, err)
+
+			// conditions have to hold along with the ones of the query they are applied to
+			var ids []uint
+			err = Apply(gormDB.Model(&eventDAO{}).Where("id <> ?", 2), condition).Order("id").Pluck("id", &ids).Error
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
@@ -0,0 +1,367 @@
+// query package implements a small language of boolean expressions filtering events, such as
+//
+//	url ~ "/blog/" and (visitorId = "3f2b9c1e" or createdAt > now-1d)
+//
+// Expressions are parsed into an AST and compiled into parameterized SQL conditions.
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits of parsed queries, keeping both parsing and compiled SQL cheap.
+const (
+	MaxLength      = 2048
+	MaxComparisons = 50
+	MaxDepth       = 16
+)
+
+// Operators comparing a field with a value.
+const (
+	OpEqual          = "="
+	OpNotEqual       = "!="
+	OpContains       = "~"
+	OpNotContains    = "!~"
+	OpLess           = "<"
+	OpLessOrEqual    = "<="
+	OpGreater        = ">"
+	OpGreaterOrEqual = ">="
+)
+
+// Error is a syntax or type error of a query, at a column counted in characters from 1.
+type Error struct {
+	Column  int
+	Message string
+}
+
+// Error implements error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid query at column %d: %s", e.Column, e.Message)
+}
+
+// Expr is a node of a parsed query: And, Or, Not or Comparison.
+type Expr interface {
+	// Column returns the column of the query the expression starts at.
+	Column() int
+}
+
+// And holds when both of its operands hold.
+type And struct {
+	Left, Right Expr
+}
+
+// Column implements Expr interface.
+func (e And) Column() int { return e.Left.Column() }
+
+// Or holds when any of its operands holds.
+type Or struct {
+	Left, Right Expr
+}
+
+// Column implements Expr interface.
+func (e Or) Column() int { return e.Left.Column() }
+
+// Not holds when its operand doesn't.
+type Not struct {
+	X   Expr
+	Col int
+}
+
+// Column implements Expr interface.
+func (e Not) Column() int { return e.Col }
+
+// Comparison compares a field of an event with a value.
+type Comparison struct {
+	Field string
+	Op    string
+	Value Value
+	Col   int
+}
+
+// Column implements Expr interface.
+func (e Comparison) Column() int { return e.Col }
+
+// Value is a literal of a query, either a quoted string or a bare word such as 42 or now-1d.
+type Value struct {
+	Text   string
+	Quoted bool
+	Col    int
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenWord
+	tokenString
+	tokenOperator
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	col  int
+}
+
+// String describes the token in error messages.
+func (t token) String() string {
+	switch t.kind {
+	case tokenEnd:
+		return "end of query"
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// keyword reports whether the token is a given keyword, which are case insensitive.
+func (t token) keyword(keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.text, keyword)
+}
+
+// isKeyword reports whether the token is any keyword, so it can't be a field or a value.
+func (t token) isKeyword() bool {
+	return t.keyword("and") || t.keyword("or") || t.keyword("not")
+}
+
+// isWordRune reports whether r can be a part of a bare word, which covers field names,
+// numbers and times such as 2024-01-02T03:04:05+01:00 or now-1d.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-+:.", r)
+}
+
+// lex splits a query into tokens, ending with tokenEnd.
+func lex(q string) ([]token, error) {
+	var tokens []token
+	col := 1
+	for i := 0; i < len(q); {
+		r, size := utf8.DecodeRuneInString(q[i:])
+		start := col
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			col++
+		case r == '(' || r == ')':
+			kind := tokenOpen
+			if r == ')' {
+				kind = tokenClose
+			}
+			tokens = append(tokens, token{kind: kind, text: string(r), col: start})
+			i += size
+			col++
+		case r == '"':
+			var b strings.Builder
+			i += size
+			col++
+			closed := false
+			for i < len(q) {
+				r, size = utf8.DecodeRuneInString(q[i:])
+				i += size
+				col++
+				if r == '"' {
+					closed = true
+					break
+				}
+				if r == '\\' {
+					if i == len(q) {
+						break
+					}
+					r, size = utf8.DecodeRuneInString(q[i:])
+					i += size
+					col++
+				}
+				b.WriteRune(r)
+			}
+			if !closed {
+				return nil, &Error{Column: start, Message: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokenString, text: b.String(), col: start})
+		case strings.ContainsRune("=!~<>", r):
+			op := string(r)
+			if i+1 < len(q) && q[i+1] == '=' && r != '=' && r != '~' {
+				op += "="
+			} else if r == '!' && i+1 < len(q) && q[i+1] == '~' {
+				op = OpNotContains
+			}
+			if op == "!" {
+				return nil, &Error{Column: start, Message: `unexpected "!", expected "!=" or "!~"`}
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, col: start})
+			i += len(op)
+			col += len(op)
+		case isWordRune(r):
+			j := i
+			for j < len(q) {
+				r, size := utf8.DecodeRuneInString(q[j:])
+				if !isWordRune(r) {
+					break
+				}
+				j += size
+				col++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: q[i:j], col: start})
+			i = j
+		default:
+			return nil, &Error{Column: start, Message: fmt.Sprintf("unexpected character %q", r)}
+		}
+	}
+
+	return append(tokens, token{kind: tokenEnd, col: col}), nil
+}
+
+// parser is a recursive descent parser of the grammar
+//
+//	or         = and { "or" and }
+//	and        = not { "and" not }
+//	not        = "not" not | "(" or ")" | comparison
+//	comparison = field operator value
+type parser struct {
+	tokens      []token
+	next        int
+	depth       int
+	comparisons int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEnd {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) nest(t token) error {
+	p.depth++
+	if p.depth > MaxDepth {
+		return &Error{Column: t.col, Message: fmt.Sprintf("expressions can be nested at most %d levels deep", MaxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("or") {
+		p.advance()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().keyword("and") {
+		p.advance()
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) not() (Expr, error) {
+	t := p.peek()
+	switch {
+	case t.keyword("not"):
+		p.advance()
+		if err := p.nest(t); err != nil {
+			return nil, err
+		}
+		x, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		p.depth--
+		return Not{X: x, Col: t.col}, nil
+	case t.kind == tokenOpen:
+		p.advance()
+		if err := p.nest(t); err != nil {
+			return nil, err
+		}
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokenClose {
+			return nil, &Error{Column: closing.col, Message: fmt.Sprintf(`expected ")" closing "(" at column %d, found %s`, t.col, closing)}
+		}
+		p.depth--
+		return x, nil
+	default:
+		return p.comparison()
+	}
+}
+
+func (p *parser) comparison() (Expr, error) {
+	field := p.advance()
+	if field.kind != tokenWord || field.isKeyword() {
+		return nil, &Error{Column: field.col, Message: fmt.Sprintf("expected field name, found %s", field)}
+	}
+	op := p.advance()
+	if op.kind != tokenOperator {
+		return nil, &Error{Column: op.col, Message: fmt.Sprintf("expected operator after %q, found %s", field.text, op)}
+	}
+	value := p.advance()
+	if (value.kind != tokenWord && value.kind != tokenString) || value.isKeyword() {
+		return nil, &Error{Column: value.col, Message: fmt.Sprintf("expected value after %q, found %s", op.text, value)}
+	}
+
+	p.comparisons++
+	if p.comparisons > MaxComparisons {
+		return nil, &Error{Column: field.col, Message: fmt.Sprintf("at most %d comparisons are allowed", MaxComparisons)}
+	}
+
+	return Comparison{
+		Field: field.text,
+		Op:    op.text,
+		Value: Value{Text: value.text, Quoted: value.kind == tokenString, Col: value.col},
+		Col:   field.col,
+	}, nil
+}
+
+// Parse parses a query into an expression. Errors are of type *Error.
+func Parse(q string) (Expr, error) {
+	if len(q) > MaxLength {
+		return nil, &Error{Column: 1, Message: fmt.Sprintf("query must not be longer than %d bytes", MaxLength)}
+	}
+
+	tokens, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEnd {
+		return nil, &Error{Column: 1, Message: "query is empty"}
+	}
+
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEnd {
+		return nil, &Error{Column: t.col, Message: fmt.Sprintf(`expected "and", "or" or end of query, found %s`, t)}
+	}
+
+	return expr, nil
+}
@@ -0,0 +1,71 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	expr, err := Parse(`url ~ "/blog/" and (visitorId = "v\"1" OR not createdAt>now-1d)`)
+	assert.NoError(t, err)
+	assert.Equal(t, And{
+		Left: Comparison{Field: "url", Op: OpContains, Value: Value{Text: "/blog/", Quoted: true, Col: 7}, Col: 1},
+		Right: Or{
+			Left: Comparison{Field: "visitorId", Op: OpEqual, Value: Value{Text: `v"1`, Quoted: true, Col: 33}, Col: 21},
+			Right: Not{
+				X:   Comparison{Field: "createdAt", Op: OpGreater, Value: Value{Text: "now-1d", Col: 57}, Col: 47},
+				Col: 43,
+			},
+		},
+	}, expr)
+}
+
+func TestParsePrecedence(t *testing.T) {
+	expr, err := Parse(`id = 1 or id = 2 and id = 3`)
+	assert.NoError(t, err)
+	or, ok := expr.(Or)
+	if assert.True(t, ok, "and binds tighter than or") {
+		assert.IsType(t, And{}, or.Right)
+	}
+
+	expr, err = Parse(`id = 1 or id = 2 or id = 3`)
+	assert.NoError(t, err)
+	or, ok = expr.(Or)
+	if assert.True(t, ok) {
+		assert.IsType(t, Or{}, or.Left, "operators are left associative")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]struct {
+		query string
+		err   string
+	}{
+		"empty":              {"  ", "invalid query at column 1: query is empty"},
+		"missing value":      {`url =`, `invalid query at column 6: expected value after "=", found end of query`},
+		"missing operator":   {`url "/blog/"`, `invalid query at column 5: expected operator after "url", found string "/blog/"`},
+		"keyword as field":   {`and url = "/"`, `invalid query at column 1: expected field name, found "and"`},
+		"keyword as value":   {`url = or`, `invalid query at column 7: expected value after "=", found "or"`},
+		"missing connective": {`url = "/" id = 1`, `invalid query at column 11: expected "and", "or" or end of query, found "id"`},
+		"unclosed":           {`(url = "/"`, `invalid query at column 11: expected ")" closing "(" at column 1, found end of query`},
+		"unopened":           {`url = "/")`, `invalid query at column 10: expected "and", "or" or end of query, found ")"`},
+		"unterminated":       {`url = "/blog`, `invalid query at column 7: unterminated string`},
+		"bang":               {`url ! "/"`, `invalid query at column 5: unexpected "!", expected "!=" or "!~"`},
+		"character":          {`url = '/'`, `invalid query at column 7: unexpected character '\''`},
+		"too long":           {`url = "` + strings.Repeat("a", MaxLength) + `"`, "invalid query at column 1: query must not be longer than 2048 bytes"},
+		"too deep":           {strings.Repeat("(", MaxDepth+1) + `id = 1` + strings.Repeat(")", MaxDepth+1), "invalid query at column 17: expressions can be nested at most 16 levels deep"},
+		"too many":           {strings.Repeat("id = 1 or ", MaxComparisons) + "id = 1", "invalid query at column 501: at most 50 comparisons are allowed"},
+		"unicode columns":    {`url = "ž" ž`, `invalid query at column 11: expected "and", "or" or end of query, found "ž"`},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse(test.query)
+			var queryErr *Error
+			assert.ErrorAs(t, err, &queryErr)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RouteConfig holds limits of a single route. Nil limit means no limit.
+// KeyOverrides replace PerKey limit for particular API keys, identified by their prefix.
+type RouteConfig struct {
+	PerKey       *Limit           `json:"perKey,omitempty"`
+	PerIP        *Limit           `json:"perIP,omitempty"`
+	KeyOverrides map[string]Limit `json:"keyOverrides,omitempty"`
+}
+
+// keyLimit returns the limit applied to a given API key. The longest matching override wins.
+func (rc RouteConfig) keyLimit(key string) *Limit {
+	limit, matched := rc.PerKey, ""
+	for prefix, override := range rc.KeyOverrides {
+		if strings.HasPrefix(key, prefix) && len(prefix) > len(matched) {
+			override := override
+			limit, matched = &override, prefix
+		}
+	}
+	return limit
+}
+
+// Config holds limits of all routes, keyed by method and path, e.g. "POST /clicks".
+type Config struct {
+	Routes map[string]RouteConfig `json:"routes"`
+}
+
+// Validate checks that all configured limits can be enforced.
+func (c Config) Validate() error {
+	for route, rc := range c.Routes {
+		for name, limit := range map[string]*Limit{"perKey": rc.PerKey, "perIP": rc.PerIP} {
+			if limit != nil && !limit.Valid() {
+				return fmt.Errorf("%s: %s limit must have positive rate and burst", route, name)
+			}
+		}
+		for prefix, limit := range rc.KeyOverrides {
+			if prefix == "" || !limit.Valid() {
+				return fmt.Errorf("%s: key override %q must have non-empty prefix, positive rate and burst", route, prefix)
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultConfig is used when there is no configuration file.
+var DefaultConfig = Config{
+	Routes: map[string]RouteConfig{
+		"POST /clicks": {PerKey: &Limit{Rate: 100, Burst: 200}, PerIP: &Limit{Rate: 20, Burst: 40}},
+		"POST /views":  {PerKey: &Limit{Rate: 100, Burst: 200}, PerIP: &Limit{Rate: 20, Burst: 40}},
+		"GET /v.gif":   {PerKey: &Limit{Rate: 100, Burst: 200}, PerIP: &Limit{Rate: 20, Burst: 40}},
+		"GET /r":       {PerKey: &Limit{Rate: 100, Burst: 200}, PerIP: &Limit{Rate: 20, Burst: 40}},
+	},
+}
+
+// LoadConfig reads JSON configuration from a file.
+func LoadConfig(path string) (Config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	if err := json.Unmarshal(b, &config); err != nil {
+		return Config{}, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	return config, config.Validate()
+}
+
+// Limiter enforces configured limits on HTTP routes.
+// Configuration can be replaced at any time without losing bucket state.
+type Limiter struct {
+	store  Store
+	config atomic.Pointer[Config]
+	now    func() time.Time
+}
+
+// SetConfig replaces the active configuration.
+func (l *Limiter) SetConfig(config Config) {
+	l.config.Store(&config)
+}
+
+// Reload replaces the active configuration with the one read from a file.
+// The active configuration is kept if the file is invalid.
+func (l *Limiter) Reload(path string) error {
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	l.SetConfig(config)
+	return nil
+}
+
+// chargeKey is the echo.Context key of the function used by Charge.
+const chargeKey = "ratelimit.charge"
+
+// Middleware returns middleware which limits requests of the routes it is applied to.
+// Key limit is applied to the API key regardless of whether it's valid,
+// so the middleware can run before authentication. Rate limit headers describe
+// the most restrictive of the applied limits.
+func (l *Limiter) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Request().Method + " " + c.Path()
+			rc, ok := l.config.Load().Routes[route]
+			if !ok {
+				return next(c)
+			}
+
+			key := apiKey(c)
+			if err := l.take(c, route, rc, key, 1); err != nil {
+				return err
+			}
+			c.Set(chargeKey, func(n int) error {
+				return l.take(c, route, rc, key, n)
+			})
+
+			return next(c)
+		}
+	}
+}
+
+// take takes n tokens from the buckets a request is limited by and sets rate limit headers.
+// It returns an error responding with 429 if any of the buckets doesn't have enough tokens.
+func (l *Limiter) take(c echo.Context, route string, rc RouteConfig, key string, n int) error {
+	now := l.now()
+	var results []Result
+	if key != "" {
+		if limit := rc.keyLimit(key); limit != nil {
+			results = append(results, l.store.Take(route+"|key|"+hash(key), *limit, n, now))
+		}
+	}
+	if rc.PerIP != nil {
+		results = append(results, l.store.Take(route+"|ip|"+c.RealIP(), *rc.PerIP, n, now))
+	}
+	if len(results) == 0 {
+		return nil
+	}
+	result := mostRestrictive(results)
+
+	h := c.Response().Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+	if !result.Allowed {
+		h.Set(echo.HeaderRetryAfter, strconv.Itoa(ceilSeconds(result.RetryAfter)))
+		return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded")
+	}
+
+	return nil
+}
+
+// Charge charges n more requests to the limits applied to the current request by Limiter middleware,
+// such as the events of a batch following the first one. It returns an error responding with 429
+// if a limit is exceeded. Nothing is charged on routes which are not limited.
+func Charge(c echo.Context, n int) error {
+	charge, ok := c.Get(chargeKey).(func(int) error)
+	if !ok || n <= 0 {
+		return nil
+	}
+	return charge(n)
+}
+
+// NewLimiter is a Limiter constructor.
+func NewLimiter(store Store, config Config) *Limiter {
+	l := &Limiter{
+		store: store,
+		now:   time.Now,
+	}
+	l.SetConfig(config)
+
+	return l
+}
+
+// mostRestrictive returns the denied Result with the longest wait,
+// or the allowed one with the fewest remaining tokens if none was denied.
+func mostRestrictive(results []Result) Result {
+	result := results[0]
+	for _, r := range results[1:] {
+		switch {
+		case result.Allowed && !r.Allowed:
+			result = r
+		case !result.Allowed && !r.Allowed && r.RetryAfter > result.RetryAfter:
+			result = r
+		case result.Allowed && r.Allowed && r.Remaining < result.Remaining:
+			result = r
+		}
+	}
+	return result
+}
+
+// apiKey returns the API key of a request, looked up the same way as by tracking endpoints:
+// the bearer token first, then "key" query parameter.
+func apiKey(c echo.Context) string {
+	auth := c.Request().Header.Get(echo.HeaderAuthorization)
+	if len(auth) > len("Bearer ") && strings.EqualFold(auth[:len("Bearer ")], "Bearer ") {
+		return auth[len("Bearer "):]
+	}
+	return c.QueryParam("key")
+}
+
+// hash is used to identify key buckets, so raw keys aren't kept in memory.
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,126 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterMiddleware(t *testing.T) {
+	now := time.Now()
+	limiter := NewLimiter(NewMemoryStore(), Config{
+		Routes: map[string]RouteConfig{
+			"POST /clicks": {
+				PerKey:       &Limit{Rate: 1, Burst: 1},
+				PerIP:        &Limit{Rate: 1, Burst: 3},
+				KeyOverrides: map[string]Limit{"cav_w_big": {Rate: 1, Burst: 2}},
+			},
+		},
+	})
+	limiter.now = func() time.Time { return now }
+
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusCreated) }
+	e.POST("/clicks", ok, limiter.Middleware())
+	e.GET("/clicks", ok, limiter.Middleware())
+
+	request := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/clicks", nil)
+		if key != "" {
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(http.MethodPost, "cav_w_small")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "0", rec.Header().Get("X-RateLimit-Remaining"))
+
+	rec = request(http.MethodPost, "cav_w_small")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get(echo.HeaderRetryAfter))
+
+	// overridden key has a larger bucket
+	assert.Equal(t, http.StatusCreated, request(http.MethodPost, "cav_w_big").Code)
+
+	// per IP limit applies across keys
+	rec = request(http.MethodPost, "cav_w_big")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get("X-RateLimit-Limit"))
+
+	// keys sent in query are limited like bearer tokens
+	req := httptest.NewRequest(http.MethodPost, "/clicks?key=cav_w_small", nil)
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("X-RateLimit-Limit"))
+
+	// routes without configuration are not limited
+	rec = request(http.MethodGet, "cav_w_small")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Empty(t, rec.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestCharge(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), Config{
+		Routes: map[string]RouteConfig{
+			"POST /clicks": {PerKey: &Limit{Rate: 1, Burst: 5}},
+		},
+	})
+
+	e := echo.New()
+	e.POST("/clicks", func(c echo.Context) error {
+		if err := Charge(c, 2); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusCreated)
+	}, limiter.Middleware())
+	e.POST("/views", func(c echo.Context) error {
+		if err := Charge(c, 2); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusCreated)
+	})
+
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set(echo.HeaderAuthorization, "Bearer cav_w_key")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request("/clicks")
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "2", rec.Header().Get("X-RateLimit-Remaining"), "the request and two charged ones are taken")
+
+	rec = request("/clicks")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+
+	// routes which aren't limited aren't charged
+	assert.Equal(t, http.StatusCreated, request("/views").Code)
+}
+
+func TestLimiterReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	limiter := NewLimiter(NewMemoryStore(), DefaultConfig)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"routes":{"POST /views":{"perIP":{"rate":5,"burst":10}}}}`), 0o644))
+	assert.NoError(t, limiter.Reload(path))
+	assert.Equal(t, Config{Routes: map[string]RouteConfig{"POST /views": {PerIP: &Limit{Rate: 5, Burst: 10}}}}, *limiter.config.Load())
+
+	// invalid configuration keeps the active one
+	assert.NoError(t, os.WriteFile(path, []byte(`{"routes":{"POST /views":{"perIP":{"rate":0,"burst":10}}}}`), 0o644))
+	assert.Error(t, limiter.Reload(path))
+	assert.Equal(t, 5.0, limiter.config.Load().Routes["POST /views"].PerIP.Rate)
+}
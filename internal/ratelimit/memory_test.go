@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 2}
+	now := time.Now()
+
+	result := store.Take("key", limit, 1, now)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, result)
+
+	result = store.Take("key", limit, 1, now)
+	assert.Equal(t, Result{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, result)
+
+	result = store.Take("key", limit, 1, now.Add(500*time.Millisecond))
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	// other keys have their own buckets
+	assert.True(t, store.Take("other", limit, 1, now).Allowed)
+
+	result = store.Take("key", limit, 1, now.Add(time.Second))
+	assert.True(t, result.Allowed)
+
+	// several tokens are taken all at once or not at all
+	result = store.Take("other", limit, 2, now)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.True(t, store.Take("other", limit, 1, now).Allowed)
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 1, Burst: 1}
+	now := time.Now()
+
+	store.Take("idle", limit, 1, now)
+	for i := 0; i < sweepInterval-1; i++ {
+		store.Take("busy", limit, 1, now.Add(time.Minute))
+	}
+
+	assert.Equal(t, 1, store.Len())
+}
@@ -0,0 +1,38 @@
+// ratelimit package provides token bucket rate limiting for HTTP routes.
+// Limits are configured per route, and applied per API key and per client IP.
+package ratelimit
+
+import (
+	"time"
+)
+
+// Limit defines a token bucket. Rate is the number of tokens added per second,
+// Burst is the bucket capacity.
+type Limit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Valid reports whether the Limit can be enforced.
+func (l Limit) Valid() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result holds the outcome of a single Take.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of whole tokens left in the bucket.
+	Remaining int
+	// Reset is the time until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is the time until the requested tokens are available. Zero if request was allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the state of token buckets.
+// Implementations must be safe for concurrent use.
+type Store interface {
+	// Take removes n tokens from the bucket identified by key, if there are that many available.
+	Take(key string, limit Limit, n int, now time.Time) Result
+}
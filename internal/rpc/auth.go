@@ -0,0 +1,118 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/rpc/pb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataAuthorization is the metadata key carrying the project API key as a bearer token.
+const MetadataAuthorization = "authorization"
+
+// scopes maps methods to the key scope they require. Methods which are not listed are rejected.
+var scopes = map[string]project.Scope{
+	pb.ClicksAndViews_CreateClick_FullMethodName:  project.ScopeWrite,
+	pb.ClicksAndViews_CreateView_FullMethodName:   project.ScopeWrite,
+	pb.ClicksAndViews_Ingest_FullMethodName:       project.ScopeWrite,
+	pb.ClicksAndViews_FilterClicks_FullMethodName: project.ScopeRead,
+	pb.ClicksAndViews_FilterViews_FullMethodName:  project.ScopeRead,
+}
+
+type projectIDKey struct{}
+
+// ProjectID returns ID of the project authenticated by Auth, or zero if there is none.
+func ProjectID(ctx context.Context) uint {
+	id, _ := ctx.Value(projectIDKey{}).(uint)
+	return id
+}
+
+// Auth authenticates calls with a project API key, the gRPC counterpart of project.Auth.
+// On success, ID of the key's project is stored in the call context and can be retrieved with ProjectID.
+type Auth struct {
+	repository project.Repository
+}
+
+// Unary returns interceptor which authenticates unary calls.
+func (a Auth) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns interceptor which authenticates streaming calls.
+func (a Auth) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func (a Auth) authenticate(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := scopes[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "method is not available")
+	}
+
+	plain := bearerToken(ctx)
+	if plain == "" {
+		return nil, status.Error(codes.Unauthenticated, "missing API key")
+	}
+
+	key, err := a.repository.FindKeyByHash(ctx, project.HashKey(plain))
+	if errors.Is(err, project.ErrNotFound) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if key.Scope != scope {
+		return nil, status.Error(codes.PermissionDenied, "key is not allowed to perform this operation")
+	}
+
+	return context.WithValue(ctx, projectIDKey{}, key.ProjectID), nil
+}
+
+// NewAuth is an Auth constructor.
+func NewAuth(repository project.Repository) Auth {
+	return Auth{
+		repository: repository,
+	}
+}
+
+// authenticatedStream replaces the context of a stream with the authenticated one.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream interface.
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func bearerToken(ctx context.Context) string {
+	values := metadata.ValueFromIncomingContext(ctx, MetadataAuthorization)
+	if len(values) == 0 {
+		return ""
+	}
+
+	scheme, token, ok := strings.Cut(values[0], " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
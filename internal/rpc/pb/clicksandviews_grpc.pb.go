@@ -0,0 +1,362 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: clicksandviews.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	ClicksAndViews_CreateClick_FullMethodName  = "/clicksandviews.v1.ClicksAndViews/CreateClick"
+	ClicksAndViews_CreateView_FullMethodName   = "/clicksandviews.v1.ClicksAndViews/CreateView"
+	ClicksAndViews_Ingest_FullMethodName       = "/clicksandviews.v1.ClicksAndViews/Ingest"
+	ClicksAndViews_FilterClicks_FullMethodName = "/clicksandviews.v1.ClicksAndViews/FilterClicks"
+	ClicksAndViews_FilterViews_FullMethodName  = "/clicksandviews.v1.ClicksAndViews/FilterViews"
+)
+
+// ClicksAndViewsClient is the client API for ClicksAndViews service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ClicksAndViewsClient interface {
+	// CreateClick records a single click, same as POST /clicks. Resubmitting a known event_id,
+	// or the same url from the same visitor within the dedup window, returns the original click.
+	CreateClick(ctx context.Context, in *CreateClickRequest, opts ...grpc.CallOption) (*Click, error)
+	// CreateView records a single view, same as POST /views. Resubmitting a known event_id,
+	// or the same url from the same visitor within the dedup window, returns the original view.
+	CreateView(ctx context.Context, in *CreateViewRequest, opts ...grpc.CallOption) (*View, error)
+	// Ingest records a stream of clicks and views in batches, same as POST /import.
+	// Events with a known event_id are counted as duplicates.
+	Ingest(ctx context.Context, opts ...grpc.CallOption) (ClicksAndViews_IngestClient, error)
+	// FilterClicks streams clicks matching the filter.
+	FilterClicks(ctx context.Context, in *FilterRequest, opts ...grpc.CallOption) (ClicksAndViews_FilterClicksClient, error)
+	// FilterViews streams views matching the filter.
+	FilterViews(ctx context.Context, in *FilterRequest, opts ...grpc.CallOption) (ClicksAndViews_FilterViewsClient, error)
+}
+
+type clicksAndViewsClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClicksAndViewsClient(cc grpc.ClientConnInterface) ClicksAndViewsClient {
+	return &clicksAndViewsClient{cc}
+}
+
+func (c *clicksAndViewsClient) CreateClick(ctx context.Context, in *CreateClickRequest, opts ...grpc.CallOption) (*Click, error) {
+	out := new(Click)
+	err := c.cc.Invoke(ctx, ClicksAndViews_CreateClick_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clicksAndViewsClient) CreateView(ctx context.Context, in *CreateViewRequest, opts ...grpc.CallOption) (*View, error) {
+	out := new(View)
+	err := c.cc.Invoke(ctx, ClicksAndViews_CreateView_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *clicksAndViewsClient) Ingest(ctx context.Context, opts ...grpc.CallOption) (ClicksAndViews_IngestClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClicksAndViews_ServiceDesc.Streams[0], ClicksAndViews_Ingest_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clicksAndViewsIngestClient{stream}
+	return x, nil
+}
+
+type ClicksAndViews_IngestClient interface {
+	Send(*IngestRequest) error
+	CloseAndRecv() (*IngestResponse, error)
+	grpc.ClientStream
+}
+
+type clicksAndViewsIngestClient struct {
+	grpc.ClientStream
+}
+
+func (x *clicksAndViewsIngestClient) Send(m *IngestRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *clicksAndViewsIngestClient) CloseAndRecv() (*IngestResponse, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(IngestResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clicksAndViewsClient) FilterClicks(ctx context.Context, in *FilterRequest, opts ...grpc.CallOption) (ClicksAndViews_FilterClicksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClicksAndViews_ServiceDesc.Streams[1], ClicksAndViews_FilterClicks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clicksAndViewsFilterClicksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClicksAndViews_FilterClicksClient interface {
+	Recv() (*Click, error)
+	grpc.ClientStream
+}
+
+type clicksAndViewsFilterClicksClient struct {
+	grpc.ClientStream
+}
+
+func (x *clicksAndViewsFilterClicksClient) Recv() (*Click, error) {
+	m := new(Click)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *clicksAndViewsClient) FilterViews(ctx context.Context, in *FilterRequest, opts ...grpc.CallOption) (ClicksAndViews_FilterViewsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ClicksAndViews_ServiceDesc.Streams[2], ClicksAndViews_FilterViews_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &clicksAndViewsFilterViewsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ClicksAndViews_FilterViewsClient interface {
+	Recv() (*View, error)
+	grpc.ClientStream
+}
+
+type clicksAndViewsFilterViewsClient struct {
+	grpc.ClientStream
+}
+
+func (x *clicksAndViewsFilterViewsClient) Recv() (*View, error) {
+	m := new(View)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ClicksAndViewsServer is the server API for ClicksAndViews service.
+// All implementations must embed UnimplementedClicksAndViewsServer
+// for forward compatibility
+type ClicksAndViewsServer interface {
+	// CreateClick records a single click, same as POST /clicks. Resubmitting a known event_id,
+	// or the same url from the same visitor within the dedup window, returns the original click.
+	CreateClick(context.Context, *CreateClickRequest) (*Click, error)
+	// CreateView records a single view, same as POST /views. Resubmitting a known event_id,
+	// or the same url from the same visitor within the dedup window, returns the original view.
+	CreateView(context.Context, *CreateViewRequest) (*View, error)
+	// Ingest records a stream of clicks and views in batches, same as POST /import.
+	// Events with a known event_id are counted as duplicates.
+	Ingest(ClicksAndViews_IngestServer) error
+	// FilterClicks streams clicks matching the filter.
+	FilterClicks(*FilterRequest, ClicksAndViews_FilterClicksServer) error
+	// FilterViews streams views matching the filter.
+	FilterViews(*FilterRequest, ClicksAndViews_FilterViewsServer) error
+	mustEmbedUnimplementedClicksAndViewsServer()
+}
+
+// UnimplementedClicksAndViewsServer must be embedded to have forward compatible implementations.
+type UnimplementedClicksAndViewsServer struct {
+}
+
+func (UnimplementedClicksAndViewsServer) CreateClick(context.Context, *CreateClickRequest) (*Click, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateClick not implemented")
+}
+func (UnimplementedClicksAndViewsServer) CreateView(context.Context, *CreateViewRequest) (*View, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateView not implemented")
+}
+func (UnimplementedClicksAndViewsServer) Ingest(ClicksAndViews_IngestServer) error {
+	return status.Errorf(codes.Unimplemented, "method Ingest not implemented")
+}
+func (UnimplementedClicksAndViewsServer) FilterClicks(*FilterRequest, ClicksAndViews_FilterClicksServer) error {
+	return status.Errorf(codes.Unimplemented, "method FilterClicks not implemented")
+}
+func (UnimplementedClicksAndViewsServer) FilterViews(*FilterRequest, ClicksAndViews_FilterViewsServer) error {
+	return status.Errorf(codes.Unimplemented, "method FilterViews not implemented")
+}
+func (UnimplementedClicksAndViewsServer) mustEmbedUnimplementedClicksAndViewsServer() {}
+
+// UnsafeClicksAndViewsServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ClicksAndViewsServer will
+// result in compilation errors.
+type UnsafeClicksAndViewsServer interface {
+	mustEmbedUnimplementedClicksAndViewsServer()
+}
+
+func RegisterClicksAndViewsServer(s grpc.ServiceRegistrar, srv ClicksAndViewsServer) {
+	s.RegisterService(&ClicksAndViews_ServiceDesc, srv)
+}
+
+func _ClicksAndViews_CreateClick_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateClickRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClicksAndViewsServer).CreateClick(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClicksAndViews_CreateClick_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClicksAndViewsServer).CreateClick(ctx, req.(*CreateClickRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClicksAndViews_CreateView_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateViewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ClicksAndViewsServer).CreateView(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ClicksAndViews_CreateView_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ClicksAndViewsServer).CreateView(ctx, req.(*CreateViewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ClicksAndViews_Ingest_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ClicksAndViewsServer).Ingest(&clicksAndViewsIngestServer{stream})
+}
+
+type ClicksAndViews_IngestServer interface {
+	SendAndClose(*IngestResponse) error
+	Recv() (*IngestRequest, error)
+	grpc.ServerStream
+}
+
+type clicksAndViewsIngestServer struct {
+	grpc.ServerStream
+}
+
+func (x *clicksAndViewsIngestServer) SendAndClose(m *IngestResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *clicksAndViewsIngestServer) Recv() (*IngestRequest, error) {
+	m := new(IngestRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _ClicksAndViews_FilterClicks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FilterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClicksAndViewsServer).FilterClicks(m, &clicksAndViewsFilterClicksServer{stream})
+}
+
+type ClicksAndViews_FilterClicksServer interface {
+	Send(*Click) error
+	grpc.ServerStream
+}
+
+type clicksAndViewsFilterClicksServer struct {
+	grpc.ServerStream
+}
+
+func (x *clicksAndViewsFilterClicksServer) Send(m *Click) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ClicksAndViews_FilterViews_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(FilterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ClicksAndViewsServer).FilterViews(m, &clicksAndViewsFilterViewsServer{stream})
+}
+
+type ClicksAndViews_FilterViewsServer interface {
+	Send(*View) error
+	grpc.ServerStream
+}
+
+type clicksAndViewsFilterViewsServer struct {
+	grpc.ServerStream
+}
+
+func (x *clicksAndViewsFilterViewsServer) Send(m *View) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// ClicksAndViews_ServiceDesc is the grpc.ServiceDesc for ClicksAndViews service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ClicksAndViews_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "clicksandviews.v1.ClicksAndViews",
+	HandlerType: (*ClicksAndViewsServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateClick",
+			Handler:    _ClicksAndViews_CreateClick_Handler,
+		},
+		{
+			MethodName: "CreateView",
+			Handler:    _ClicksAndViews_CreateView_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Ingest",
+			Handler:       _ClicksAndViews_Ingest_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "FilterClicks",
+			Handler:       _ClicksAndViews_FilterClicks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "FilterViews",
+			Handler:       _ClicksAndViews_FilterViews_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "clicksandviews.proto",
+}
@@ -0,0 +1,243 @@
+// rpc package implements gRPC API, a typed alternative to the REST API
+// for backend services. It's built on the same repositories as HTTP handlers.
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/importer"
+	"google.com/ivan-sabo/clicks-and-views/internal/rpc/pb"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements pb.ClicksAndViewsServer.
+type Server struct {
+	pb.UnimplementedClicksAndViewsServer
+
+	clickRepository click.Repository
+	viewRepository  view.Repository
+	importer        *importer.Importer
+	clickRecorder   click.Recorder
+	viewRecorder    view.Recorder
+}
+
+// CreateClick implements pb.ClicksAndViewsServer interface.
+// Clicks are recorded the same way as over HTTP: resubmitting a known EventId, or the same URL from
+// the same visitor within the dedup window, returns the original Click instead of creating a new one.
+func (s *Server) CreateClick(ctx context.Context, req *pb.CreateClickRequest) (*pb.Click, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	c, err := s.clickRecorder.Record(ctx, visitor(ctx), click.Click{
+		ProjectID:  ProjectID(ctx),
+		ExternalID: req.GetEventId(),
+		VisitorID:  req.GetVisitorId(),
+		URL:        req.GetUrl(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return newClick(c), nil
+}
+
+// CreateView implements pb.ClicksAndViewsServer interface.
+// Views are recorded the same way as over HTTP: resubmitting a known EventId, or the same URL from
+// the same visitor within the dedup window, returns the original View instead of creating a new one.
+func (s *Server) CreateView(ctx context.Context, req *pb.CreateViewRequest) (*pb.View, error) {
+	if req.GetUrl() == "" {
+		return nil, status.Error(codes.InvalidArgument, "url is required")
+	}
+
+	v, err := s.viewRecorder.Record(ctx, visitor(ctx), view.View{
+		ProjectID:  ProjectID(ctx),
+		ExternalID: req.GetEventId(),
+		VisitorID:  req.GetVisitorId(),
+		URL:        req.GetUrl(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return newView(v), nil
+}
+
+// Ingest implements pb.ClicksAndViewsServer interface.
+// Events are persisted in batches as they arrive, so batches persisted before an error are kept.
+func (s *Server) Ingest(srv pb.ClicksAndViews_IngestServer) error {
+	ctx := srv.Context()
+
+	progress, err := s.importer.Import(ctx, ProjectID(ctx), ingestReader{srv}, 0, nil)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	return srv.SendAndClose(&pb.IngestResponse{
+		Read:       progress.Read,
+		Imported:   progress.Imported,
+		Duplicates: progress.Duplicates,
+	})
+}
+
+// FilterClicks implements pb.ClicksAndViewsServer interface.
+func (s *Server) FilterClicks(req *pb.FilterRequest, srv pb.ClicksAndViews_FilterClicksServer) error {
+	ctx := srv.Context()
+
+	clickCollection, err := s.clickRepository.Filter(ctx, click.Filter{
+		ProjectID: ProjectID(ctx),
+		URL:       req.GetUrl(),
+		After:     asTime(req.GetAfter()),
+		Before:    asTime(req.GetBefore()),
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for _, c := range clickCollection {
+		if err := srv.Send(newClick(c)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FilterViews implements pb.ClicksAndViewsServer interface.
+func (s *Server) FilterViews(req *pb.FilterRequest, srv pb.ClicksAndViews_FilterViewsServer) error {
+	ctx := srv.Context()
+
+	viewCollection, err := s.viewRepository.Filter(ctx, view.Filter{
+		ProjectID: ProjectID(ctx),
+		URL:       req.GetUrl(),
+		After:     asTime(req.GetAfter()),
+		Before:    asTime(req.GetBefore()),
+	})
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for _, v := range viewCollection {
+		if err := srv.Send(newView(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewServer is a Server constructor. Created events are recorded with the same recorders
+// as in click.NewHandler and view.NewHandler.
+func NewServer(clickRepository click.Repository, viewRepository view.Repository, clickRecorder click.Recorder, viewRecorder view.Recorder) *Server {
+	return &Server{
+		clickRepository: clickRepository,
+		viewRepository:  viewRepository,
+		importer:        importer.NewImporter(clickRepository, viewRepository, importer.DefaultBatchSize),
+		clickRecorder:   clickRecorder,
+		viewRecorder:    viewRecorder,
+	}
+}
+
+// ingestReader adapts Ingest stream to importer.Reader.
+type ingestReader struct {
+	srv pb.ClicksAndViews_IngestServer
+}
+
+// Read implements importer.Reader interface.
+func (r ingestReader) Read() (importer.Record, error) {
+	req, err := r.srv.Recv()
+	if err != nil {
+		return importer.Record{}, err
+	}
+
+	var record importer.Record
+	switch event := req.GetEvent().(type) {
+	case *pb.IngestRequest_Click:
+		record = importer.Record{
+			Type:       importer.TypeClick,
+			ExternalID: event.Click.GetEventId(),
+			VisitorID:  event.Click.GetVisitorId(),
+			URL:        event.Click.GetUrl(),
+			CreatedAt:  asTime(event.Click.GetCreatedAt()),
+		}
+	case *pb.IngestRequest_View:
+		record = importer.Record{
+			Type:       importer.TypeView,
+			ExternalID: event.View.GetEventId(),
+			VisitorID:  event.View.GetVisitorId(),
+			URL:        event.View.GetUrl(),
+			CreatedAt:  asTime(event.View.GetCreatedAt()),
+		}
+	default:
+		return importer.Record{}, status.Error(codes.InvalidArgument, "either click or view is required")
+	}
+
+	if record.URL == "" {
+		return importer.Record{}, status.Error(codes.InvalidArgument, "url is required")
+	}
+	return record, nil
+}
+
+func newClick(c click.Click) *pb.Click {
+	return &pb.Click{
+		Id:        uint64(c.ID),
+		EventId:   c.ExternalID,
+		Url:       c.URL,
+		CreatedAt: timestamppb.New(c.CreatedAt),
+		VisitorId: c.VisitorID,
+	}
+}
+
+func newView(v view.View) *pb.View {
+	return &pb.View{
+		Id:        uint64(v.ID),
+		EventId:   v.ExternalID,
+		Url:       v.URL,
+		CreatedAt: timestamppb.New(v.CreatedAt),
+		VisitorId: v.VisitorID,
+	}
+}
+
+// visitor returns an anonymous identifier of the client making a call, the same as dedup.Visitor does for HTTP requests.
+func visitor(ctx context.Context) string {
+	var ip, userAgent string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("user-agent"); len(values) > 0 {
+			userAgent = values[0]
+		}
+	}
+	return dedup.AnonymousVisitor(ip, userAgent)
+}
+
+// asTime converts an optional timestamp, missing one is the zero time.
+func asTime(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
+
+// toStatus maps errors which are not gRPC statuses already.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Internal, err.Error())
+}
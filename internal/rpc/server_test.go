@@ -0,0 +1,250 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/rpc/pb"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuth(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	client, keys := setupServer(t, gormDB, nil)
+
+	tests := []struct {
+		testName     string
+		key          string
+		expectedCode codes.Code
+	}{
+		{testName: "missing key", key: "", expectedCode: codes.Unauthenticated},
+		{testName: "invalid key", key: "cav_w_invalid", expectedCode: codes.Unauthenticated},
+		{testName: "read key", key: keys[project.ScopeRead], expectedCode: codes.PermissionDenied},
+		{testName: "write key", key: keys[project.ScopeWrite], expectedCode: codes.OK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			_, err := client.CreateClick(withKey(test.key), &pb.CreateClickRequest{Url: "test.url1"})
+			assert.Equal(t, test.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestCreateAndFilter(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	hub := stream.NewHub[click.Click](stream.DefaultHistorySize, stream.DefaultBufferSize)
+	defer hub.Close()
+	subscription := hub.Subscribe(1, nil, 0)
+
+	client, keys := setupServer(t, gormDB, hub)
+	write, read := withKey(keys[project.ScopeWrite]), withKey(keys[project.ScopeRead])
+
+	created, err := client.CreateClick(write, &pb.CreateClickRequest{EventId: "e1", Url: "test.url1"})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), created.Id)
+
+	// resubmission returns the original click and isn't published again
+	resubmitted, err := client.CreateClick(write, &pb.CreateClickRequest{EventId: "e1", Url: "test.url1"})
+	assert.NoError(t, err)
+	assert.Equal(t, created.Id, resubmitted.Id)
+
+	_, err = client.CreateClick(write, &pb.CreateClickRequest{Url: "test.url2"})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "test.url1", (<-subscription.Events()).Data.URL)
+	assert.Equal(t, "test.url2", (<-subscription.Events()).Data.URL)
+
+	filterStream, err := client.FilterClicks(read, &pb.FilterRequest{Url: "test.url1"})
+	assert.NoError(t, err)
+	var urls []string
+	for {
+		c, err := filterStream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			break
+		}
+		urls = append(urls, c.Url)
+	}
+	assert.Equal(t, []string{"test.url1"}, urls)
+}
+
+func TestCreateDedup(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	client, keys := setupServer(t, gormDB, nil)
+	write := withKey(keys[project.ScopeWrite])
+
+	// the same URL from the same client within the dedup window is recorded once, same as over HTTP
+	first, err := client.CreateClick(write, &pb.CreateClickRequest{Url: "test.url1"})
+	assert.NoError(t, err)
+	second, err := client.CreateClick(write, &pb.CreateClickRequest{Url: "test.url1"})
+	assert.NoError(t, err)
+	assert.Equal(t, first.Id, second.Id)
+
+	firstView, err := client.CreateView(write, &pb.CreateViewRequest{Url: "test.url1"})
+	assert.NoError(t, err)
+	secondView, err := client.CreateView(write, &pb.CreateViewRequest{Url: "test.url1"})
+	assert.NoError(t, err)
+	assert.Equal(t, firstView.Id, secondView.Id)
+
+	// events without url are rejected, same as over HTTP
+	_, err = client.CreateClick(write, &pb.CreateClickRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.CreateView(write, &pb.CreateViewRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// visitors identified by VisitorId are told apart even though they call from the same client
+	visitors := map[string]uint64{}
+	for _, visitorID := range []string{"visitor1", "visitor2", "visitor1"} {
+		c, err := client.CreateClick(write, &pb.CreateClickRequest{Url: "test.url2", VisitorId: visitorID})
+		if assert.NoError(t, err) {
+			assert.Equal(t, visitorID, c.VisitorId)
+			if id, ok := visitors[visitorID]; ok {
+				assert.Equal(t, id, c.Id, "the same visitor is recorded once")
+			}
+			visitors[visitorID] = c.Id
+		}
+	}
+	assert.NotEqual(t, visitors["visitor1"], visitors["visitor2"])
+
+	clicks, err := click.NewSQLiteRepository(gormDB).Filter(context.Background(), click.Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, clicks, 3)
+}
+
+func TestIngest(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	client, keys := setupServer(t, gormDB, nil)
+	write := withKey(keys[project.ScopeWrite])
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	ingestStream, err := client.Ingest(write)
+	assert.NoError(t, err)
+	for _, req := range []*pb.IngestRequest{
+		{Event: &pb.IngestRequest_Click{Click: &pb.Click{EventId: "c1", Url: "test.url1", CreatedAt: timestamppb.New(createdAt), VisitorId: "visitor1"}}},
+		{Event: &pb.IngestRequest_Click{Click: &pb.Click{EventId: "c1", Url: "test.url1", CreatedAt: timestamppb.New(createdAt)}}},
+		{Event: &pb.IngestRequest_View{View: &pb.View{EventId: "v1", Url: "test.url1"}}},
+	} {
+		assert.NoError(t, ingestStream.Send(req))
+	}
+	response, err := ingestStream.CloseAndRecv()
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(3), response.Read)
+		assert.Equal(t, int64(2), response.Imported)
+		assert.Equal(t, int64(1), response.Duplicates)
+	}
+
+	clicks, err := click.NewSQLiteRepository(gormDB).Filter(context.Background(), click.Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, clicks, 1) {
+		assert.True(t, createdAt.Equal(clicks[0].CreatedAt))
+		assert.Equal(t, "visitor1", clicks[0].VisitorID)
+	}
+
+	// events without url are rejected
+	ingestStream, err = client.Ingest(write)
+	assert.NoError(t, err)
+	assert.NoError(t, ingestStream.Send(&pb.IngestRequest{Event: &pb.IngestRequest_View{View: &pb.View{}}}))
+	_, err = ingestStream.CloseAndRecv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// setupServer serves gRPC API of project 1 in memory and returns a client along with plain text keys of each scope.
+func setupServer(t *testing.T, gormDB *gorm.DB, clickHub *stream.Hub[click.Click]) (pb.ClicksAndViewsClient, map[project.Scope]string) {
+	t.Helper()
+
+	projectRepository := project.NewSQLiteRepository(gormDB)
+	keys := make(map[project.Scope]string)
+	for _, scope := range []project.Scope{project.ScopeRead, project.ScopeWrite} {
+		key, plain, err := project.NewKey(1, scope)
+		assert.NoError(t, err)
+		_, err = projectRepository.CreateKey(context.Background(), key)
+		assert.NoError(t, err)
+		keys[scope] = plain
+	}
+
+	auth := NewAuth(projectRepository)
+	server := grpc.NewServer(grpc.UnaryInterceptor(auth.Unary()), grpc.StreamInterceptor(auth.Stream()))
+	clickRepository := click.NewSQLiteRepository(gormDB)
+	viewRepository := view.NewSQLiteRepository(gormDB)
+	counters := live.NewCounters(live.DefaultMaxURLs)
+	pb.RegisterClicksAndViewsServer(server, NewServer(
+		clickRepository,
+		viewRepository,
+		click.NewRecorder(clickRepository, dedup.NewWindow[click.Click](time.Minute), clickHub, counters),
+		view.NewRecorder(viewRepository, dedup.NewWindow[view.View](time.Minute), nil, counters),
+	))
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewClicksAndViewsClient(conn), keys
+}
+
+func withKey(key string) context.Context {
+	if key == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), MetadataAuthorization, "Bearer "+key)
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&click.ClickDAO{}, &view.ViewDAO{}, &project.KeyDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,320 @@
+package shortlink
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+)
+
+// maxGenerateAttempts limits retries when a generated code collides with an existing one.
+const maxGenerateAttempts = 5
+
+// LinkDTO represents HTTP request/response model.
+// Code is generated when not provided. ExpiresAt accepts anything timeparam.Parse does, such as "now+30d",
+// and is responded to in RFC 3339.
+type LinkDTO struct {
+	ID          uint   `json:"id,omitempty"`
+	Code        string `json:"code,omitempty"`
+	Destination string `json:"destination"`
+	ShortURL    string `json:"shortUrl,omitempty"`
+	ExpiresAt   string `json:"expiresAt,omitempty"`
+	CreatedAt   string `json:"createdAt,omitempty"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+// Destination has to be on a host of the allowlist, the same as targets of /r, so links can't be used as open redirects.
+func (l LinkDTO) ToDomain(allowlist click.Allowlist) (Link, error) {
+	target, err := url.Parse(l.Destination)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Link{}, errors.New("destination must be an absolute http or https URL")
+	}
+	if !allowlist.Allows(target) {
+		return Link{}, errors.New("destination must be on an allowed host")
+	}
+	if l.Code != "" && !ValidCode(l.Code) {
+		return Link{}, errors.New("code must be 3 to 64 letters, digits, '-' or '_'")
+	}
+
+	var expiresAt time.Time
+	if l.ExpiresAt != "" {
+		if expiresAt, err = timeparam.Parse(l.ExpiresAt, time.Now()); err != nil {
+			return Link{}, fmt.Errorf("expiresAt: %w", err)
+		}
+	}
+
+	return Link{
+		Code:        l.Code,
+		Destination: l.Destination,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+// NewLinkDTO is a LinkDTO constructor.
+func NewLinkDTO(l Link) LinkDTO {
+	dto := LinkDTO{
+		ID:          l.ID,
+		Code:        l.Code,
+		Destination: l.Destination,
+		ShortURL:    l.ShortURL,
+		CreatedAt:   l.CreatedAt.Format(time.RFC3339),
+	}
+	if !l.ExpiresAt.IsZero() {
+		dto.ExpiresAt = l.ExpiresAt.Format(time.RFC3339)
+	}
+	return dto
+}
+
+// LinkDTOCollection represents LinkDTO collection.
+type LinkDTOCollection []LinkDTO
+
+// NewLinkDTOCollection maps domain models into DTO models.
+func NewLinkDTOCollection(linkCollection LinkCollection) LinkDTOCollection {
+	linkDTOCollection := make(LinkDTOCollection, 0, len(linkCollection))
+
+	for _, link := range linkCollection {
+		linkDTOCollection = append(linkDTOCollection, NewLinkDTO(link))
+	}
+
+	return linkDTOCollection
+}
+
+// BucketDTO represents HTTP response model of clicks in a single time bucket.
+type BucketDTO struct {
+	Period string `json:"period"`
+	Clicks int64  `json:"clicks"`
+}
+
+// StatsDTO represents HTTP response model of clicks on a link.
+type StatsDTO struct {
+	Clicks  int64       `json:"clicks"`
+	Buckets []BucketDTO `json:"buckets"`
+}
+
+// ClickRecorder records a Click the same way as other tracking endpoints, *click.Handler implements it.
+type ClickRecorder interface {
+	Record(echo.Context, click.Click) (click.Click, error)
+}
+
+// Handler defines all API methods for short links.
+type Handler struct {
+	repository      Repository
+	clickRepository click.Repository
+	recorder        ClickRecorder
+	baseURL         string
+	allowlist       click.Allowlist
+}
+
+// Create implements handler for Create Link HTTP request.
+// A random code is generated when none is provided.
+func (h *Handler) Create(c echo.Context) error {
+	var linkDTO LinkDTO
+	if err := c.Bind(&linkDTO); err != nil {
+		return err
+	}
+
+	link, err := linkDTO.ToDomain(h.allowlist)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	link.ProjectID = project.ID(c)
+
+	if link.Code != "" {
+		link, err = h.create(c, link)
+		if errors.Is(err, ErrCodeTaken) {
+			return echo.NewHTTPError(http.StatusConflict, err.Error())
+		}
+	} else {
+		for i := 0; i < maxGenerateAttempts; i++ {
+			if link.Code, err = GenerateCode(); err != nil {
+				return err
+			}
+			var created Link
+			if created, err = h.create(c, link); !errors.Is(err, ErrCodeTaken) {
+				link = created
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusCreated, NewLinkDTO(link))
+}
+
+func (h *Handler) create(c echo.Context, link Link) (Link, error) {
+	link.ShortURL = h.baseURL + "/s/" + link.Code
+	return h.repository.CreateLink(c.Request().Context(), link)
+}
+
+// List implements handler for List Links HTTP request.
+func (h *Handler) List(c echo.Context) error {
+	links, err := h.repository.ListLinks(c.Request().Context(), project.ID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewLinkDTOCollection(links))
+}
+
+// Get implements handler for Get Link HTTP request.
+func (h *Handler) Get(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	link, err := h.repository.GetLink(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewLinkDTO(link))
+}
+
+// Update implements handler for Update Link HTTP request.
+// It replaces destination and expiry of a link, its code can't be changed.
+func (h *Handler) Update(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var linkDTO LinkDTO
+	if err := c.Bind(&linkDTO); err != nil {
+		return err
+	}
+	linkDTO.Code = ""
+
+	link, err := linkDTO.ToDomain(h.allowlist)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	link.ID = id
+	link.ProjectID = project.ID(c)
+
+	link, err = h.repository.UpdateLink(c.Request().Context(), link)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewLinkDTO(link))
+}
+
+// Delete implements handler for Delete Link HTTP request.
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.repository.DeleteLink(c.Request().Context(), project.ID(c), id); err != nil {
+		return notFound(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Stats implements handler for Link Stats HTTP request.
+// It returns the number of clicks on a link, also split into time buckets given by "period"
+// query parameter, which is one of hour, day (default) or month. Buckets start in the time zone
+// given by "tz" query parameter, UTC by default.
+func (h *Handler) Stats(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	period := click.Period(c.QueryParam("period"))
+	switch period {
+	case "":
+		period = click.PeriodDay
+	case click.PeriodHour, click.PeriodDay, click.PeriodMonth:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "period must be one of hour, day or month")
+	}
+	loc, err := timeparam.Location(c.QueryParam("tz"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ctx := c.Request().Context()
+	link, err := h.repository.GetLink(ctx, project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	filter := click.Filter{ProjectID: link.ProjectID, URL: link.ShortURL}
+	total, err := h.clickRepository.Count(ctx, filter)
+	if err != nil {
+		return err
+	}
+	groups, err := h.clickRepository.Aggregate(ctx, filter, click.GroupBy{Period: period, Location: loc})
+	if err != nil {
+		return err
+	}
+
+	stats := StatsDTO{Clicks: total, Buckets: make([]BucketDTO, 0, len(groups))}
+	for _, g := range groups {
+		stats.Buckets = append(stats.Buckets, BucketDTO{Period: g.Period.Format(time.RFC3339), Clicks: g.Count})
+	}
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// Resolve implements handler for Resolve Link HTTP request. It requires no authentication,
+// records a Click on the short URL in the project owning the link and redirects to its destination.
+// Expired links respond with 410 Gone.
+func (h *Handler) Resolve(c echo.Context) error {
+	link, err := h.repository.FindByCode(c.Request().Context(), c.Param("code"))
+	if err != nil {
+		return notFound(err)
+	}
+	if link.Expired(time.Now()) {
+		return echo.NewHTTPError(http.StatusGone, "link has expired")
+	}
+
+	// a link should keep working even if the click can't be recorded
+	if _, err := h.recorder.Record(c, click.Click{ProjectID: link.ProjectID, URL: link.ShortURL}); err != nil {
+		c.Logger().Error(err)
+	}
+
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.Redirect(http.StatusFound, link.Destination)
+}
+
+// NewHandler is a Handler constructor.
+// baseURL is the public URL of the service, which short URLs are built from.
+func NewHandler(repository Repository, clickRepository click.Repository, recorder ClickRecorder, baseURL string, allowlist click.Allowlist) Handler {
+	return Handler{
+		repository:      repository,
+		clickRepository: clickRepository,
+		recorder:        recorder,
+		baseURL:         strings.TrimSuffix(baseURL, "/"),
+		allowlist:       allowlist,
+	}
+}
+
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
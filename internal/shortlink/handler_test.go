@@ -0,0 +1,250 @@
+package shortlink
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandlerCreate(t *testing.T) {
+	tests := []struct {
+		testName       string
+		body           string
+		expectedStatus int
+	}{
+		{
+			testName:       "generated code",
+			body:           `{"destination":"https://example.com/landing"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "custom code",
+			body:           `{"code":"spring-sale","destination":"https://example.com/sale","expiresAt":"2030-01-01T00:00:00Z"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "taken code",
+			body:           `{"code":"taken","destination":"https://example.com/sale"}`,
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			testName:       "invalid code",
+			body:           `{"code":"a/b","destination":"https://example.com/sale"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "relative destination",
+			body:           `{"destination":"/landing"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "destination on another host",
+			body:           `{"destination":"https://evil.example.org/login"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "invalid expiry",
+			body:           `{"destination":"https://example.com/sale","expiresAt":"tomorrow"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			gormDB := setupDatabase(t)
+			defer func() {
+				teardownDatabase(t)
+			}()
+
+			repository := NewSQLiteRepository(gormDB)
+			_, err := repository.CreateLink(context.Background(), Link{ProjectID: 2, Code: "taken", Destination: "https://example.com"})
+			assert.NoError(t, err)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/links", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			h := newHandler(gormDB)
+			err = h.Create(c)
+			if test.expectedStatus != http.StatusCreated {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			var created LinkDTO
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+			assert.True(t, ValidCode(created.Code))
+			assert.Equal(t, "https://sho.rt/s/"+created.Code, created.ShortURL)
+
+			links, _ := repository.ListLinks(context.Background(), 1)
+			assert.Len(t, links, 1)
+		})
+	}
+}
+
+func TestHandlerResolve(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	repository := NewSQLiteRepository(gormDB)
+	for _, link := range []Link{
+		{ProjectID: 1, Code: "active", Destination: "https://example.com/active", ShortURL: "https://sho.rt/s/active"},
+		{ProjectID: 1, Code: "expired", Destination: "https://example.com/expired", ShortURL: "https://sho.rt/s/expired", ExpiresAt: time.Now().Add(-time.Minute)},
+	} {
+		_, err := repository.CreateLink(context.Background(), link)
+		assert.NoError(t, err)
+	}
+
+	tests := []struct {
+		testName         string
+		code             string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			testName:         "active",
+			code:             "active",
+			expectedStatus:   http.StatusFound,
+			expectedLocation: "https://example.com/active",
+		},
+		{
+			testName:       "expired",
+			code:           "expired",
+			expectedStatus: http.StatusGone,
+		},
+		{
+			testName:       "unknown",
+			code:           "unknown",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	h := newHandler(gormDB)
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/s/"+test.code, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("code")
+			c.SetParamValues(test.code)
+
+			err := h.Resolve(c)
+			if test.expectedStatus != http.StatusFound {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusFound, rec.Code)
+			assert.Equal(t, test.expectedLocation, rec.Header().Get(echo.HeaderLocation))
+		})
+	}
+
+	clicks, err := click.NewSQLiteRepository(gormDB).Filter(context.Background(), click.Filter{ProjectID: 1})
+	assert.NoError(t, err)
+	if assert.Len(t, clicks, 1, "only resolved links are recorded") {
+		assert.Equal(t, "https://sho.rt/s/active", clicks[0].URL)
+	}
+}
+
+func TestHandlerStats(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	_, err := NewSQLiteRepository(gormDB).CreateLink(context.Background(), Link{
+		ProjectID: 1, Code: "sale", Destination: "https://example.com/sale", ShortURL: "https://sho.rt/s/sale",
+	})
+	assert.NoError(t, err)
+
+	day1, _ := time.Parse(time.RFC3339, "2024-01-02T03:04:05Z")
+	_, err = click.NewSQLiteRepository(gormDB).CreateBatch(context.Background(), click.ClickCollection{
+		{ProjectID: 1, URL: "https://sho.rt/s/sale", CreatedAt: day1},
+		{ProjectID: 1, URL: "https://sho.rt/s/sale", CreatedAt: day1.Add(time.Hour)},
+		{ProjectID: 1, URL: "https://sho.rt/s/sale", CreatedAt: day1.Add(24 * time.Hour)},
+		{ProjectID: 1, URL: "https://sho.rt/s/other", CreatedAt: day1},
+		{ProjectID: 2, URL: "https://sho.rt/s/sale", CreatedAt: day1},
+	})
+	assert.NoError(t, err)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/links/1/stats?period=day", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+
+	h := newHandler(gormDB)
+	assert.NoError(t, h.Stats(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"clicks":3,"buckets":[`+
+		`{"period":"2024-01-02T00:00:00Z","clicks":2},`+
+		`{"period":"2024-01-03T00:00:00Z","clicks":1}]}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/links/1/stats?period=day&tz=America/New_York", nil), rec)
+	c.Set(project.ContextKey, uint(1))
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	assert.NoError(t, h.Stats(c))
+	assert.JSONEq(t, `{"clicks":3,"buckets":[`+
+		`{"period":"2024-01-01T00:00:00-05:00","clicks":2},`+
+		`{"period":"2024-01-02T00:00:00-05:00","clicks":1}]}`, rec.Body.String(), "buckets start at local midnight")
+
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/links/1/stats?tz=Nowhere", nil), httptest.NewRecorder())
+	c.Set(project.ContextKey, uint(1))
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	assert.Equal(t, http.StatusBadRequest, h.Stats(c).(*echo.HTTPError).Code)
+
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/links/1/stats", nil), httptest.NewRecorder())
+	c.Set(project.ContextKey, uint(2))
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	assert.Equal(t, http.StatusNotFound, h.Stats(c).(*echo.HTTPError).Code, "links of other projects are not found")
+}
+
+func newHandler(gormDB *gorm.DB) Handler {
+	clickRepository := click.NewSQLiteRepository(gormDB)
+	clickHandler := click.NewHandler(clickRepository, nil, nil, nil, nil)
+	return NewHandler(NewSQLiteRepository(gormDB), clickRepository, &clickHandler, "https://sho.rt/", click.Allowlist{"example.com"})
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&LinkDAO{}, &click.ClickDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
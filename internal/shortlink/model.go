@@ -0,0 +1,78 @@
+// shortlink package provides short links which redirect to a destination URL.
+// Every resolution of a short link is recorded as a click, so links come with click stats.
+package shortlink
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"time"
+)
+
+// GeneratedCodeLength is the length of generated codes, 62^7 of them are available.
+const GeneratedCodeLength = 7
+
+const codeAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// codePattern restricts custom codes to characters which don't need escaping in URLs.
+var codePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,64}$`)
+
+// Link represents entity model of a short link.
+// ShortURL is the URL clicks on the link are recorded under, fixed when the link is created.
+// Zero ExpiresAt means the link never expires.
+type Link struct {
+	ID          uint
+	ProjectID   uint
+	Code        string
+	Destination string
+	ShortURL    string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+// Expired reports whether the link has expired at a given time.
+func (l Link) Expired(now time.Time) bool {
+	return !l.ExpiresAt.IsZero() && !now.Before(l.ExpiresAt)
+}
+
+// LinkCollection represents a collection of Link domain entities.
+type LinkCollection []Link
+
+var (
+	// ErrNotFound is returned when requested entity doesn't exist.
+	ErrNotFound = errors.New("link not found")
+	// ErrCodeTaken is returned by Repository.CreateLink when a link with the same code exists.
+	ErrCodeTaken = errors.New("code is already taken")
+)
+
+// ValidCode reports whether a custom code can be used.
+func ValidCode(code string) bool {
+	return codePattern.MatchString(code)
+}
+
+// GenerateCode returns a random code of GeneratedCodeLength.
+func GenerateCode() (string, error) {
+	code := make([]byte, GeneratedCodeLength)
+	max := big.NewInt(int64(len(codeAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// Repository defines a storage API for Link entity.
+// Codes are unique across all projects, since links are resolved without authentication.
+type Repository interface {
+	CreateLink(context.Context, Link) (Link, error)
+	ListLinks(ctx context.Context, projectID uint) (LinkCollection, error)
+	GetLink(ctx context.Context, projectID, id uint) (Link, error)
+	UpdateLink(context.Context, Link) (Link, error)
+	DeleteLink(ctx context.Context, projectID, id uint) error
+	FindByCode(ctx context.Context, code string) (Link, error)
+}
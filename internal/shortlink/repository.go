@@ -0,0 +1,161 @@
+package shortlink
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LinkDAO represents a single database entry.
+type LinkDAO struct {
+	ID          uint `gorm:"primarykey"`
+	CreatedAt   time.Time
+	ProjectID   uint   `gorm:"index"`
+	Code        string `gorm:"uniqueIndex"`
+	Destination string
+	ShortURL    string
+	ExpiresAt   time.Time
+}
+
+// TableName overrides the table name used by LinkDAO to 'short_links'
+func (LinkDAO) TableName() string {
+	return "short_links"
+}
+
+// NewLinkDAO maps Link entity model into database model.
+func NewLinkDAO(l Link) LinkDAO {
+	return LinkDAO{
+		ID:          l.ID,
+		CreatedAt:   l.CreatedAt,
+		ProjectID:   l.ProjectID,
+		Code:        l.Code,
+		Destination: l.Destination,
+		ShortURL:    l.ShortURL,
+		ExpiresAt:   l.ExpiresAt,
+	}
+}
+
+// ToDomain maps database model into domain model.
+func (l *LinkDAO) ToDomain() Link {
+	return Link{
+		ID:          l.ID,
+		ProjectID:   l.ProjectID,
+		Code:        l.Code,
+		Destination: l.Destination,
+		ShortURL:    l.ShortURL,
+		ExpiresAt:   l.ExpiresAt,
+		CreatedAt:   l.CreatedAt,
+	}
+}
+
+// LinkDAOCollection represents a collection of Link database model.
+type LinkDAOCollection []LinkDAO
+
+// ToDomain maps DAO models into domain models.
+func (ll LinkDAOCollection) ToDomain() LinkCollection {
+	r := make(LinkCollection, 0, len(ll))
+
+	for _, l := range ll {
+		r = append(r, l.ToDomain())
+	}
+
+	return r
+}
+
+// SQLiteRepository is a SQLite implementation of shortlink Repository.
+type SQLiteRepository struct {
+	db *gorm.DB
+}
+
+// CreateLink persists Link entity, or returns ErrCodeTaken if its code is already used.
+func (r *SQLiteRepository) CreateLink(ctx context.Context, l Link) (Link, error) {
+	dao := NewLinkDAO(l)
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&dao)
+	if result.Error != nil {
+		return Link{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Link{}, ErrCodeTaken
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// ListLinks returns all links of a project.
+func (r *SQLiteRepository) ListLinks(ctx context.Context, projectID uint) (LinkCollection, error) {
+	var links LinkDAOCollection
+	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id").Find(&links).Error; err != nil {
+		return LinkCollection{}, err
+	}
+
+	return links.ToDomain(), nil
+}
+
+// GetLink returns a Link of a project, or ErrNotFound.
+func (r *SQLiteRepository) GetLink(ctx context.Context, projectID, id uint) (Link, error) {
+	var dao LinkDAO
+	err := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Link{}, ErrNotFound
+	}
+	if err != nil {
+		return Link{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// UpdateLink replaces destination and expiry of a Link, or returns ErrNotFound.
+// Code and ShortURL of a link never change, so its click stats stay together.
+func (r *SQLiteRepository) UpdateLink(ctx context.Context, l Link) (Link, error) {
+	result := r.db.WithContext(ctx).Model(&LinkDAO{}).
+		Where("project_id = ? AND id = ?", l.ProjectID, l.ID).
+		Updates(map[string]any{"destination": l.Destination, "expires_at": l.ExpiresAt})
+	if result.Error != nil {
+		return Link{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return Link{}, ErrNotFound
+	}
+
+	return r.GetLink(ctx, l.ProjectID, l.ID)
+}
+
+// DeleteLink removes a Link of a project, or returns ErrNotFound.
+// Clicks recorded through the link are kept.
+func (r *SQLiteRepository) DeleteLink(ctx context.Context, projectID, id uint) error {
+	result := r.db.WithContext(ctx).Where("project_id = ?", projectID).Delete(&LinkDAO{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// FindByCode returns the Link with a given code regardless of its project, or ErrNotFound.
+func (r *SQLiteRepository) FindByCode(ctx context.Context, code string) (Link, error) {
+	var dao LinkDAO
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Link{}, ErrNotFound
+	}
+	if err != nil {
+		return Link{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// NewSQLiteRepository is a SQLiteRepository constructor.
+func NewSQLiteRepository(db *gorm.DB) *SQLiteRepository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
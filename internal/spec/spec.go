@@ -0,0 +1,135 @@
+// spec package enforces the OpenAPI specification of the HTTP API, so the specification
+// and the handlers can't drift apart unnoticed.
+package spec
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/api"
+)
+
+func init() {
+	// text/plain bodies of this API are JSON sent by navigator.sendBeacon
+	openapi3filter.RegisterBodyDecoder(echo.MIMETextPlain, func(body io.Reader, _ http.Header, _ *openapi3.SchemaRef, _ openapi3filter.EncodingFn) (any, error) {
+		var value any
+		if err := json.NewDecoder(body).Decode(&value); err != nil {
+			return nil, &openapi3filter.ParseError{Kind: openapi3filter.KindInvalidFormat, Cause: err}
+		}
+		return value, nil
+	})
+}
+
+// Load parses and validates the OpenAPI specification.
+func Load() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(api.OpenAPI)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Validator validates requests and responses against the OpenAPI specification.
+type Validator struct {
+	router  routers.Router
+	options *openapi3filter.Options
+}
+
+// Middleware returns middleware which rejects requests that don't match the specification with 400.
+// Requests to paths missing from the specification are passed on unchecked. Only JSON bodies are validated,
+// others, such as imported files, are streamed to handlers as they are. Authentication is left to other middleware,
+// which should run first, so that unauthenticated callers can't learn the specification from validation errors.
+func (v *Validator) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			route, pathParams, err := v.router.FindRoute(req)
+			if err != nil {
+				return next(c)
+			}
+
+			options := *v.options
+			options.ExcludeRequestBody = !jsonBody(req.Header.Get(echo.HeaderContentType))
+			err = openapi3filter.ValidateRequest(req.Context(), &openapi3filter.RequestValidationInput{
+				Request:    req,
+				PathParams: pathParams,
+				Route:      route,
+				Options:    &options,
+			})
+			var requestErr *openapi3filter.RequestError
+			if errors.As(err, &requestErr) {
+				return echo.NewHTTPError(http.StatusBadRequest, requestErr.Error()).SetInternal(err)
+			}
+			if err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// ValidateResponse checks that the response to req is documented in the specification,
+// and that its body matches the documented schema. Only bodies of JSON responses are validated.
+func (v *Validator) ValidateResponse(req *http.Request, status int, header http.Header, body []byte) error {
+	route, pathParams, err := v.router.FindRoute(req)
+	if err != nil {
+		return err
+	}
+
+	options := *v.options
+	options.IncludeResponseStatus = true
+	mediaType, _, _ := mime.ParseMediaType(header.Get(echo.HeaderContentType))
+	options.ExcludeResponseBody = mediaType != echo.MIMEApplicationJSON
+	return openapi3filter.ValidateResponse(req.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: &openapi3filter.RequestValidationInput{
+			Request:    req,
+			PathParams: pathParams,
+			Route:      route,
+			Options:    &options,
+		},
+		Status:  status,
+		Header:  header,
+		Body:    io.NopCloser(bytes.NewReader(body)),
+		Options: &options,
+	})
+}
+
+// NewValidator is a Validator constructor.
+func NewValidator(doc *openapi3.T) (*Validator, error) {
+	// requests are matched regardless of the host the service is reachable at
+	routed := *doc
+	routed.Servers = nil
+	router, err := gorillamux.NewRouter(&routed)
+	if err != nil {
+		return nil, err
+	}
+
+	options := &openapi3filter.Options{
+		AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+		SkipSettingDefaults: true,
+	}
+	options.WithCustomSchemaErrorFunc(func(err *openapi3.SchemaError) string {
+		return err.Reason
+	})
+
+	return &Validator{router: router, options: options}, nil
+}
+
+func jsonBody(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == echo.MIMEApplicationJSON || mediaType == echo.MIMETextPlain
+}
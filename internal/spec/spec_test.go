@@ -0,0 +1,93 @@
+package spec
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware(t *testing.T) {
+	doc, err := Load()
+	assert.NoError(t, err)
+	validator, err := NewValidator(doc)
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		method         string
+		target         string
+		contentType    string
+		body           string
+		expectedStatus int
+	}{
+		"valid JSON body": {
+			method:         http.MethodPost,
+			target:         "/clicks",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"url":"https://example.com"}`,
+			expectedStatus: http.StatusOK,
+		},
+		"valid beacon body": {
+			method:         http.MethodPost,
+			target:         "/clicks",
+			contentType:    "text/plain;charset=UTF-8",
+			body:           `[{"url":"https://example.com"}]`,
+			expectedStatus: http.StatusOK,
+		},
+		"invalid JSON body": {
+			method:         http.MethodPost,
+			target:         "/clicks",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `{"url":1}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"malformed beacon body": {
+			method:         http.MethodPost,
+			target:         "/views",
+			contentType:    echo.MIMETextPlain,
+			body:           `{"url":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		"invalid query parameter": {
+			method:         http.MethodGet,
+			target:         "/links/1/stats?period=week",
+			expectedStatus: http.StatusBadRequest,
+		},
+		"unvalidated body": {
+			method:         http.MethodPost,
+			target:         "/import?format=csv",
+			contentType:    "text/csv",
+			body:           "not,validated",
+			expectedStatus: http.StatusOK,
+		},
+		"undocumented path": {
+			method:         http.MethodGet,
+			target:         "/unknown",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+			if test.contentType != "" {
+				req.Header.Set(echo.HeaderContentType, test.contentType)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := validator.Middleware()(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})(c)
+			if err != nil {
+				e.HTTPErrorHandler(err, c)
+			}
+
+			assert.Equal(t, test.expectedStatus, rec.Code, rec.Body.String())
+		})
+	}
+}
@@ -0,0 +1,166 @@
+// stream package broadcasts newly created events to live subscribers.
+// Recent events are kept in memory, so subscribers which reconnect can resume
+// where they left off.
+package stream
+
+import (
+	"sync"
+)
+
+const (
+	// DefaultHistorySize is the number of recent events kept for resuming subscribers.
+	DefaultHistorySize = 1024
+	// DefaultBufferSize is the number of events a subscriber may fall behind before it is evicted.
+	DefaultBufferSize = 256
+)
+
+// Event is a published item along with its position in the stream.
+// IDs are assigned sequentially, starting at 1.
+type Event[T any] struct {
+	ID        uint64
+	ProjectID uint
+	Data      T
+}
+
+// Subscription receives published events which match its project and filter.
+type Subscription[T any] struct {
+	events    chan Event[T]
+	evicted   chan struct{}
+	projectID uint
+	match     func(T) bool
+}
+
+// Events returns a channel of matching events.
+func (s *Subscription[T]) Events() <-chan Event[T] {
+	return s.events
+}
+
+// Evicted returns a channel which is closed when the subscriber falls too far behind
+// and stops receiving events.
+func (s *Subscription[T]) Evicted() <-chan struct{} {
+	return s.evicted
+}
+
+func (s *Subscription[T]) accepts(e Event[T]) bool {
+	return e.ProjectID == s.projectID && (s.match == nil || s.match(e.Data))
+}
+
+// Hub is an in-process publish/subscribe broker.
+// Publishing never blocks, subscribers which don't keep up are evicted instead.
+type Hub[T any] struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event[T]
+	historySize int
+	bufferSize  int
+	subscribers map[*Subscription[T]]struct{}
+}
+
+// Publish delivers data to all subscribers of a project and returns the assigned event ID.
+func (h *Hub[T]) Publish(projectID uint, data T) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event[T]{ID: h.lastID, ProjectID: projectID, Data: data}
+
+	if h.historySize > 0 {
+		if len(h.history) == h.historySize {
+			copy(h.history, h.history[1:])
+			h.history = h.history[:len(h.history)-1]
+		}
+		h.history = append(h.history, event)
+	}
+
+	for s := range h.subscribers {
+		if !s.accepts(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			h.evict(s)
+		}
+	}
+
+	return event.ID
+}
+
+// Subscribe starts receiving events of a project for which match returns true, nil match
+// accepts all events. If lastEventID is not zero, retained events published after it are
+// delivered first. Only the most recent of them are delivered if they don't fit the buffer.
+func (h *Hub[T]) Subscribe(projectID uint, match func(T) bool, lastEventID uint64) *Subscription[T] {
+	s := &Subscription[T]{
+		events:    make(chan Event[T], h.bufferSize),
+		evicted:   make(chan struct{}),
+		projectID: projectID,
+		match:     match,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if lastEventID > 0 {
+		var missed []Event[T]
+		for _, event := range h.history {
+			if event.ID > lastEventID && s.accepts(event) {
+				missed = append(missed, event)
+			}
+		}
+		if len(missed) > h.bufferSize {
+			missed = missed[len(missed)-h.bufferSize:]
+		}
+		for _, event := range missed {
+			s.events <- event
+		}
+	}
+
+	h.subscribers[s] = struct{}{}
+
+	return s
+}
+
+// Unsubscribe stops delivering events to a subscription.
+func (h *Hub[T]) Unsubscribe(s *Subscription[T]) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.subscribers, s)
+}
+
+// Len returns the number of events waiting to be sent to all subscribers.
+func (h *Hub[T]) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for s := range h.subscribers {
+		n += len(s.events)
+	}
+	return n
+}
+
+// Close evicts all subscribers, so their streams end and clients reconnect elsewhere.
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscribers {
+		h.evict(s)
+	}
+}
+
+func (h *Hub[T]) evict(s *Subscription[T]) {
+	delete(h.subscribers, s)
+	close(s.evicted)
+}
+
+// NewHub is a Hub constructor.
+func NewHub[T any](historySize, bufferSize int) *Hub[T] {
+	return &Hub[T]{
+		history:     make([]Event[T], 0, historySize),
+		historySize: historySize,
+		bufferSize:  bufferSize,
+		subscribers: make(map[*Subscription[T]]struct{}),
+	}
+}
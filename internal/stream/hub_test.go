@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHubPublish(t *testing.T) {
+	hub := NewHub[string](10, 10)
+
+	all := hub.Subscribe(1, nil, 0)
+	filtered := hub.Subscribe(1, func(s string) bool { return s == "test.url2" }, 0)
+	otherProject := hub.Subscribe(2, nil, 0)
+
+	assert.Equal(t, uint64(1), hub.Publish(1, "test.url1"))
+	assert.Equal(t, uint64(2), hub.Publish(1, "test.url2"))
+
+	assert.Equal(t, []Event[string]{{ID: 1, ProjectID: 1, Data: "test.url1"}, {ID: 2, ProjectID: 1, Data: "test.url2"}}, receive(all))
+	assert.Equal(t, []Event[string]{{ID: 2, ProjectID: 1, Data: "test.url2"}}, receive(filtered))
+	assert.Empty(t, receive(otherProject))
+	assert.Equal(t, 0, hub.Len())
+
+	hub.Unsubscribe(all)
+	hub.Publish(1, "test.url3")
+	assert.Empty(t, receive(all))
+}
+
+func TestHubResume(t *testing.T) {
+	hub := NewHub[string](3, 2)
+	for _, url := range []string{"test.url1", "test.url2", "test.url3", "test.url4", "test.url5"} {
+		hub.Publish(1, url)
+	}
+
+	tests := map[string]struct {
+		lastEventID uint64
+		expected    []uint64
+	}{
+		"new subscriber": {
+			lastEventID: 0,
+			expected:    nil,
+		},
+		"missed one event": {
+			lastEventID: 4,
+			expected:    []uint64{5},
+		},
+		"missed more than fits the buffer": {
+			lastEventID: 2,
+			expected:    []uint64{4, 5},
+		},
+		"missed events no longer retained": {
+			lastEventID: 1,
+			expected:    []uint64{4, 5},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var ids []uint64
+			for _, event := range receive(hub.Subscribe(1, nil, test.lastEventID)) {
+				ids = append(ids, event.ID)
+			}
+			assert.Equal(t, test.expected, ids)
+		})
+	}
+}
+
+func TestHubEviction(t *testing.T) {
+	hub := NewHub[string](0, 1)
+	slow := hub.Subscribe(1, nil, 0)
+	fast := hub.Subscribe(1, nil, 0)
+
+	hub.Publish(1, "test.url1")
+	receive(fast)
+	hub.Publish(1, "test.url2")
+
+	assertClosed(t, slow.Evicted())
+	assert.Len(t, receive(fast), 1)
+
+	hub.Close()
+	assertClosed(t, fast.Evicted())
+}
+
+func receive(s *Subscription[string]) []Event[string] {
+	var events []Event[string]
+	for {
+		select {
+		case event := <-s.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func assertClosed(t *testing.T, ch <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-ch:
+	default:
+		t.Error("channel is not closed")
+	}
+}
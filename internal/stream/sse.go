@@ -0,0 +1,71 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// HeaderLastEventID is sent by reconnecting EventSource clients.
+	HeaderLastEventID = "Last-Event-ID"
+
+	// HeartbeatInterval is how often a comment is sent on an idle stream,
+	// so proxies don't close the connection.
+	HeartbeatInterval = 15 * time.Second
+)
+
+// LastEventID reads the ID of the last event received by a reconnecting client.
+// It's zero for new clients and unparsable IDs.
+func LastEventID(c echo.Context) uint64 {
+	id, err := strconv.ParseUint(c.Request().Header.Get(HeaderLastEventID), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// Serve writes events of a subscription to the client in Server-Sent Events format until
+// the client disconnects or the subscriber is evicted. Each event is sent under a given
+// name with data encoded as JSON by toDTO.
+func Serve[T any](c echo.Context, hub *Hub[T], s *Subscription[T], name string, toDTO func(T) any) error {
+	defer hub.Unsubscribe(s)
+
+	response := c.Response()
+	response.Header().Set(echo.HeaderContentType, "text/event-stream")
+	response.Header().Set(echo.HeaderCacheControl, "no-cache")
+	response.Header().Set(echo.HeaderConnection, "keep-alive")
+	response.Header().Set("X-Accel-Buffering", "no")
+	response.WriteHeader(http.StatusOK)
+	response.Flush()
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request().Context().Done():
+			return nil
+		case <-s.Evicted():
+			// the client reconnects with the last received ID and resumes from there
+			return nil
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(response, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		case event := <-s.Events():
+			data, err := json.Marshal(toDTO(event.Data))
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(response, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, name, data); err != nil {
+				return nil
+			}
+		}
+		response.Flush()
+	}
+}
@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServe(t *testing.T) {
+	hub := NewHub[string](10, 10)
+	hub.Publish(1, "test.url1")
+
+	e := echo.New()
+	e.GET("/stream", func(c echo.Context) error {
+		s := hub.Subscribe(1, nil, LastEventID(c))
+		return Serve(c, hub, s, "click", func(url string) any {
+			return map[string]string{"url": url}
+		})
+	})
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/stream", nil)
+	req.Header.Set(HeaderLastEventID, "0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get(echo.HeaderContentType))
+
+	hub.Publish(1, "test.url2")
+	assert.Equal(t, "id: 2\nevent: click\ndata: {\"url\":\"test.url2\"}\n", readEvent(t, bufio.NewReader(resp.Body)))
+
+	// resumed stream starts with the events published after the last received one
+	req.Header.Set(HeaderLastEventID, "1")
+	resumed, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	defer resumed.Body.Close()
+	assert.Equal(t, "id: 2\nevent: click\ndata: {\"url\":\"test.url2\"}\n", readEvent(t, bufio.NewReader(resumed.Body)))
+
+	// the stream ends once the subscriber is evicted
+	hub.Close()
+	_, err = bufio.NewReader(resp.Body).ReadString('\n')
+	assert.Error(t, err)
+}
+
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+
+	var event strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if !assert.NoError(t, err) || line == "\n" {
+			return event.String()
+		}
+		event.WriteString(line)
+	}
+}
@@ -0,0 +1,111 @@
+// timeparam package parses points in time and time zones sent as request parameters.
+package timeparam
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// relative matches expressions relative to the current time, such as "now", "now-7d" or "now+90m".
+var relative = regexp.MustCompile(`^now(?:([+-])(\d+)([smhdw]))?$`)
+
+// units maps units of relative expressions to their durations. A day is always 24 hours long.
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// Parse parses a point in time given as one of:
+//   - RFC 3339 timestamp with an offset, such as "2024-01-02T03:04:05+02:00"
+//   - "2006-01-02 15:04:05" or "2006-01-02" in UTC
+//   - Unix epoch seconds, such as "1704164645"
+//   - "now", optionally followed by an offset in seconds, minutes, hours, days or weeks, such as "now-7d"
+//
+// Relative expressions are resolved against now. The result is in UTC.
+func Parse(value string, now time.Time) (time.Time, error) {
+	if match := relative.FindStringSubmatch(value); match != nil {
+		if match[1] == "" {
+			return now.UTC(), nil
+		}
+		n, err := strconv.ParseInt(match[2], 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", value)
+		}
+		offset := time.Duration(n) * units[match[3]]
+		if match[1] == "-" {
+			offset = -offset
+		}
+		return now.Add(offset).UTC(), nil
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, expected RFC 3339, Unix epoch seconds or now-<n><s|m|h|d|w>", value)
+}
+
+// Time is a point in time bound from a request parameter, see Parse.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalParam implements echo.BindUnmarshaler.
+func (t *Time) UnmarshalParam(value string) error {
+	parsed, err := Parse(value, time.Now())
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// Location returns the time zone of a given IANA name, such as "Europe/Zagreb". Empty name is UTC.
+func Location(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Offset is the offset of a time zone from UTC, in effect since a point in time.
+type Offset struct {
+	Since   time.Time
+	Seconds int
+}
+
+// Offsets returns offsets of loc from UTC in effect between from and to, in chronological order.
+// The first one is in effect at from, so there's always at least one.
+func Offsets(loc *time.Location, from, to time.Time) []Offset {
+	_, seconds := from.In(loc).Zone()
+	offsets := []Offset{{Since: from, Seconds: seconds}}
+
+	for {
+		_, end := from.In(loc).ZoneBounds()
+		if end.IsZero() || !end.Before(to) {
+			return offsets
+		}
+		from = end
+
+		_, seconds := end.In(loc).Zone()
+		// zones are sometimes renamed without changing the offset
+		if seconds != offsets[len(offsets)-1].Seconds {
+			offsets = append(offsets, Offset{Since: end, Seconds: seconds})
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package timeparam
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		value    string
+		expected time.Time
+		err      bool
+	}{
+		"RFC 3339 with offset": {
+			value:    "2024-01-02T03:04:05+02:00",
+			expected: time.Date(2024, 1, 2, 1, 4, 5, 0, time.UTC),
+		},
+		"RFC 3339 with fraction": {
+			value:    "2024-01-02T03:04:05.5Z",
+			expected: time.Date(2024, 1, 2, 3, 4, 5, 500_000_000, time.UTC),
+		},
+		"date and time": {
+			value:    "2024-01-02 03:04:05",
+			expected: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		"date": {
+			value:    "2024-01-02",
+			expected: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		"epoch seconds": {
+			value:    "1704164645",
+			expected: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		},
+		"now": {
+			value:    "now",
+			expected: now,
+		},
+		"days ago": {
+			value:    "now-7d",
+			expected: now.AddDate(0, 0, -7),
+		},
+		"minutes ahead": {
+			value:    "now+90m",
+			expected: now.Add(90 * time.Minute),
+		},
+		"weeks ago": {
+			value:    "now-2w",
+			expected: now.AddDate(0, 0, -14),
+		},
+		"unknown unit": {
+			value: "now-7y",
+			err:   true,
+		},
+		"garbage": {
+			value: "yesterday",
+			err:   true,
+		},
+		"empty": {
+			value: "",
+			err:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Parse(test.value, now)
+			if test.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, actual)
+		})
+	}
+}
+
+func TestLocation(t *testing.T) {
+	loc, err := Location("")
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, loc)
+
+	loc, err = Location("Europe/Zagreb")
+	assert.NoError(t, err)
+	assert.Equal(t, "Europe/Zagreb", loc.String())
+
+	_, err = Location("Mars/Olympus_Mons")
+	assert.Error(t, err)
+}
+
+func TestOffsets(t *testing.T) {
+	zagreb, err := time.LoadLocation("Europe/Zagreb")
+	assert.NoError(t, err)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	assert.NoError(t, err)
+
+	tests := map[string]struct {
+		loc      *time.Location
+		from     time.Time
+		to       time.Time
+		expected []Offset
+	}{
+		"UTC": {
+			loc:      time.UTC,
+			from:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: []Offset{{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Seconds: 0}},
+		},
+		"no transitions": {
+			loc:      kolkata,
+			from:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: []Offset{{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Seconds: 19800}},
+		},
+		"daylight saving time": {
+			loc:  zagreb,
+			from: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			to:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			expected: []Offset{
+				{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Seconds: 3600},
+				{Since: time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), Seconds: 7200},
+				{Since: time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC), Seconds: 3600},
+			},
+		},
+		"before transition": {
+			loc:      zagreb,
+			from:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			to:       time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),
+			expected: []Offset{{Since: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Seconds: 3600}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual := Offsets(test.loc, test.from, test.to)
+			assert.Equal(t, len(test.expected), len(actual))
+			for i := range test.expected {
+				if i < len(actual) {
+					assert.True(t, test.expected[i].Since.Equal(actual[i].Since), "%v != %v", test.expected[i].Since, actual[i].Since)
+					assert.Equal(t, test.expected[i].Seconds, actual[i].Seconds)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package topk
+
+import (
+	"context"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// ClickRepository decorates click.Repository, counting created Clicks in a Tracker.
+type ClickRepository struct {
+	click.Repository
+	tracker *Tracker
+}
+
+// Create implements click.Repository interface.
+func (r *ClickRepository) Create(ctx context.Context, c click.Click) (click.Click, error) {
+	created, err := r.Repository.Create(ctx, c)
+	if err == nil {
+		r.tracker.Add(created.ProjectID, EventClick, created.URL, createdAt(created.CreatedAt))
+	}
+
+	return created, err
+}
+
+// CreateBatch implements click.Repository interface.
+// Events skipped as duplicates can't be told apart, so the whole batch is counted when any of it is inserted.
+func (r *ClickRepository) CreateBatch(ctx context.Context, clicks click.ClickCollection) (int64, error) {
+	inserted, err := r.Repository.CreateBatch(ctx, clicks)
+	if inserted > 0 {
+		for _, c := range clicks {
+			r.tracker.Add(c.ProjectID, EventClick, c.URL, createdAt(c.CreatedAt))
+		}
+	}
+
+	return inserted, err
+}
+
+// NewClickRepository is a ClickRepository constructor.
+func NewClickRepository(repository click.Repository, tracker *Tracker) *ClickRepository {
+	return &ClickRepository{
+		Repository: repository,
+		tracker:    tracker,
+	}
+}
+
+// ViewRepository decorates view.Repository, counting created Views in a Tracker.
+type ViewRepository struct {
+	view.Repository
+	tracker *Tracker
+}
+
+// Create implements view.Repository interface.
+func (r *ViewRepository) Create(ctx context.Context, v view.View) (view.View, error) {
+	created, err := r.Repository.Create(ctx, v)
+	if err == nil {
+		r.tracker.Add(created.ProjectID, EventView, created.URL, createdAt(created.CreatedAt))
+	}
+
+	return created, err
+}
+
+// CreateBatch implements view.Repository interface.
+// Events skipped as duplicates can't be told apart, so the whole batch is counted when any of it is inserted.
+func (r *ViewRepository) CreateBatch(ctx context.Context, views view.ViewCollection) (int64, error) {
+	inserted, err := r.Repository.CreateBatch(ctx, views)
+	if inserted > 0 {
+		for _, v := range views {
+			r.tracker.Add(v.ProjectID, EventView, v.URL, createdAt(v.CreatedAt))
+		}
+	}
+
+	return inserted, err
+}
+
+// NewViewRepository is a ViewRepository constructor.
+func NewViewRepository(repository view.Repository, tracker *Tracker) *ViewRepository {
+	return &ViewRepository{
+		Repository: repository,
+		tracker:    tracker,
+	}
+}
+
+// createdAt returns the time an event is counted at, which is now for events without one,
+// as the database sets it on insert.
+func createdAt(at time.Time) time.Time {
+	if at.IsZero() {
+		return time.Now()
+	}
+	return at
+}
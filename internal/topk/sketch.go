@@ -0,0 +1,164 @@
+// topk package keeps approximate counts of the most frequent URLs in memory,
+// so the most active ones can be found without scanning every stored event.
+package topk
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// Count is an estimated number of occurrences of a URL.
+// The estimate is never lower than the true count, and exceeds it by at most Error.
+type Count struct {
+	URL   string
+	Count uint64
+	Error uint64
+}
+
+// counter is a Count along with its position in the heap.
+type counter struct {
+	Count
+	index int
+}
+
+// counters is a min-heap of counters ordered by their Count.
+type counters []*counter
+
+func (c counters) Len() int           { return len(c) }
+func (c counters) Less(i, j int) bool { return c[i].Count.Count < c[j].Count.Count }
+
+func (c counters) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+	c[i].index = i
+	c[j].index = j
+}
+
+func (c *counters) Push(x any) {
+	item := x.(*counter)
+	item.index = len(*c)
+	*c = append(*c, item)
+}
+
+func (c *counters) Pop() any {
+	old := *c
+	item := old[len(old)-1]
+	*c = old[:len(old)-1]
+	return item
+}
+
+// Sketch is a Space-Saving heavy hitters sketch. It counts at most capacity URLs,
+// a new URL replacing the least frequent one and inheriting its count as the Error.
+// Any URL occurring more than total/capacity times is guaranteed to be counted.
+type Sketch struct {
+	capacity int
+	heap     counters
+	urls     map[string]*counter
+}
+
+// Add counts n occurrences of a URL.
+func (s *Sketch) Add(url string, n uint64) {
+	if c, ok := s.urls[url]; ok {
+		c.Count.Count += n
+		heap.Fix(&s.heap, c.index)
+		return
+	}
+
+	if len(s.heap) < s.capacity {
+		c := &counter{Count: Count{URL: url, Count: n}}
+		heap.Push(&s.heap, c)
+		s.urls[url] = c
+		return
+	}
+
+	c := s.heap[0]
+	delete(s.urls, c.URL)
+	c.URL, c.Error, c.Count.Count = url, c.Count.Count, c.Count.Count+n
+	heap.Fix(&s.heap, 0)
+	s.urls[url] = c
+}
+
+// Merge adds counts of another Sketch to this one, keeping the most frequent URLs of both.
+// A URL missing from a full Sketch may have occurred there as many times as its least frequent one,
+// so that count is added to both its Count and Error.
+func (s *Sketch) Merge(other *Sketch) {
+	sMin, otherMin := s.min(), other.min()
+
+	merged := make([]Count, 0, len(s.heap)+len(other.heap))
+	for _, c := range s.heap {
+		if o, ok := other.urls[c.URL]; ok {
+			merged = append(merged, Count{URL: c.URL, Count: c.Count.Count + o.Count.Count, Error: c.Error + o.Error})
+		} else {
+			merged = append(merged, Count{URL: c.URL, Count: c.Count.Count + otherMin, Error: c.Error + otherMin})
+		}
+	}
+	for _, o := range other.heap {
+		if _, ok := s.urls[o.URL]; !ok {
+			merged = append(merged, Count{URL: o.URL, Count: o.Count.Count + sMin, Error: o.Error + sMin})
+		}
+	}
+	sortCounts(merged)
+	if len(merged) > s.capacity {
+		merged = merged[:s.capacity]
+	}
+
+	s.heap = s.heap[:0]
+	s.urls = make(map[string]*counter, s.capacity)
+	for _, c := range merged {
+		item := &counter{Count: c, index: len(s.heap)}
+		s.heap = append(s.heap, item)
+		s.urls[c.URL] = item
+	}
+	heap.Init(&s.heap)
+}
+
+// Top returns at most n Counts, the most frequent first.
+func (s *Sketch) Top(n int) []Count {
+	top := make([]Count, 0, len(s.heap))
+	for _, c := range s.heap {
+		top = append(top, c.Count)
+	}
+	sortCounts(top)
+	if n >= 0 && n < len(top) {
+		top = top[:n]
+	}
+
+	return top
+}
+
+// Get returns the Count of a URL, which is zero when the URL isn't counted.
+func (s *Sketch) Get(url string) Count {
+	if c, ok := s.urls[url]; ok {
+		return c.Count
+	}
+	return Count{URL: url}
+}
+
+// min returns the smallest count a URL missing from the Sketch may have, which is zero until it's full.
+func (s *Sketch) min() uint64 {
+	if len(s.heap) < s.capacity {
+		return 0
+	}
+	return s.heap[0].Count.Count
+}
+
+// sortCounts orders Counts from the most frequent, and then by URL.
+func sortCounts(counts []Count) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].URL < counts[j].URL
+	})
+}
+
+// NewSketch is a Sketch constructor.
+func NewSketch(capacity int) *Sketch {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Sketch{
+		capacity: capacity,
+		heap:     make(counters, 0, capacity),
+		urls:     make(map[string]*counter, capacity),
+	}
+}
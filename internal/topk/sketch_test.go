@@ -0,0 +1,72 @@
+package topk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSketch(t *testing.T) {
+	tests := map[string]struct {
+		capacity int
+		adds     []string
+		n        int
+		expected []Count
+	}{
+		"exact below capacity": {
+			capacity: 3,
+			adds:     []string{"a", "b", "a", "c", "a", "b"},
+			n:        -1,
+			expected: []Count{{URL: "a", Count: 3}, {URL: "b", Count: 2}, {URL: "c", Count: 1}},
+		},
+		"limited": {
+			capacity: 3,
+			adds:     []string{"a", "b", "a", "c", "a", "b"},
+			n:        2,
+			expected: []Count{{URL: "a", Count: 3}, {URL: "b", Count: 2}},
+		},
+		"least frequent replaced": {
+			capacity: 2,
+			adds:     []string{"a", "a", "a", "b", "c", "a"},
+			n:        -1,
+			expected: []Count{{URL: "a", Count: 4}, {URL: "c", Count: 2, Error: 1}},
+		},
+		"heavy hitter kept": {
+			capacity: 2,
+			adds:     []string{"a", "b", "c", "d", "a", "e", "a", "f", "a"},
+			n:        1,
+			expected: []Count{{URL: "a", Count: 5, Error: 2}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sketch := NewSketch(test.capacity)
+			for _, url := range test.adds {
+				sketch.Add(url, 1)
+			}
+
+			assert.Equal(t, test.expected, sketch.Top(test.n))
+		})
+	}
+}
+
+func TestSketchMerge(t *testing.T) {
+	first := NewSketch(2)
+	first.Add("a", 5)
+	first.Add("b", 2)
+	second := NewSketch(2)
+	second.Add("b", 3)
+	second.Add("c", 1)
+
+	merged := NewSketch(2)
+	merged.Merge(first)
+	merged.Merge(second)
+
+	// a might have occurred once in the second sketch, as often as c did
+	assert.Equal(t, []Count{{URL: "a", Count: 6, Error: 1}, {URL: "b", Count: 5}}, merged.Top(-1))
+	assert.Equal(t, Count{URL: "c"}, merged.Get("c"))
+
+	merged.Add("d", 1)
+	assert.Equal(t, Count{URL: "d", Count: 6, Error: 5}, merged.Get("d"))
+}
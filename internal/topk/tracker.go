@@ -0,0 +1,112 @@
+package topk
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults of a Tracker. Two days of hourly Sketches, along with the current hour,
+// are enough to compare the last 24 hours with the ones before.
+const (
+	DefaultCapacity = 200
+	DefaultSlots    = 49
+)
+
+// SlotWidth is the period counted by a single Sketch of a Tracker.
+const SlotWidth = time.Hour
+
+// Event types counted by a Tracker.
+const (
+	EventClick = "click"
+	EventView  = "view"
+)
+
+// slot is a Sketch of a single period, identified by its start divided by SlotWidth.
+type slot struct {
+	index  int64
+	sketch *Sketch
+}
+
+type key struct {
+	projectID uint
+	eventType string
+}
+
+// Tracker maintains hourly Sketches of URLs per project and event type, keeping the most recent ones.
+type Tracker struct {
+	mu       sync.Mutex
+	capacity int
+	slots    int
+	rings    map[key][]slot
+}
+
+// Add counts an event which happened at a given time. Events older than the kept Sketches are ignored.
+func (t *Tracker) Add(projectID uint, eventType, url string, at time.Time) {
+	index := at.Unix() / int64(SlotWidth/time.Second)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	k := key{projectID: projectID, eventType: eventType}
+	ring, ok := t.rings[k]
+	if !ok {
+		ring = make([]slot, t.slots)
+		t.rings[k] = ring
+	}
+
+	s := &ring[index%int64(t.slots)]
+	if s.sketch != nil && s.index > index {
+		return
+	}
+	if s.sketch == nil || s.index < index {
+		s.index = index
+		s.sketch = NewSketch(t.capacity)
+	}
+	s.sketch.Add(url, 1)
+}
+
+// Sketch merges Sketches of events which happened between after and before into a new one.
+// Bounds are rounded to whole slots, after down and before up. Zero after stands for the start of
+// the oldest kept slot, and zero before for now.
+func (t *Tracker) Sketch(projectID uint, eventType string, after, before time.Time) *Sketch {
+	width := int64(SlotWidth / time.Second)
+	now := time.Now()
+	if after.IsZero() {
+		after = t.Since(now)
+	}
+	if before.IsZero() {
+		before = now
+	}
+	first, last := after.Unix()/width, (before.Unix()+width-1)/width-1
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	merged := NewSketch(t.capacity)
+	for _, s := range t.rings[key{projectID: projectID, eventType: eventType}] {
+		if s.sketch != nil && s.index >= first && s.index <= last {
+			merged.Merge(s.sketch)
+		}
+	}
+
+	return merged
+}
+
+// Since returns the start of the oldest slot which can be kept at a given time.
+// Counts of events which happened before it are incomplete.
+func (t *Tracker) Since(now time.Time) time.Time {
+	current := now.Unix() / int64(SlotWidth/time.Second)
+	return time.Unix((current-int64(t.slots)+1)*int64(SlotWidth/time.Second), 0).UTC()
+}
+
+// NewTracker is a Tracker constructor. Each Sketch counts up to capacity URLs, and slots of them are kept.
+func NewTracker(capacity, slots int) *Tracker {
+	if slots < 1 {
+		slots = 1
+	}
+	return &Tracker{
+		capacity: capacity,
+		slots:    slots,
+		rings:    make(map[key][]slot),
+	}
+}
@@ -0,0 +1,72 @@
+package topk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	start, _ := time.Parse(time.RFC3339, "2024-01-02T10:00:00Z")
+	tracker := NewTracker(10, 3)
+
+	tracker.Add(1, EventView, "/a", start)
+	tracker.Add(1, EventView, "/a", start.Add(30*time.Minute))
+	tracker.Add(1, EventView, "/b", start.Add(90*time.Minute))
+	tracker.Add(1, EventView, "/b", start.Add(150*time.Minute))
+	tracker.Add(1, EventView, "/b", start.Add(170*time.Minute))
+	tracker.Add(1, EventClick, "/a", start)
+	tracker.Add(2, EventView, "/c", start)
+
+	tests := map[string]struct {
+		projectID uint
+		eventType string
+		after     time.Time
+		before    time.Time
+		expected  []Count
+	}{
+		"all": {
+			projectID: 1,
+			eventType: EventView,
+			after:     start,
+			before:    start.Add(3 * time.Hour),
+			expected:  []Count{{URL: "/b", Count: 3}, {URL: "/a", Count: 2}},
+		},
+		"rounded to slots": {
+			projectID: 1,
+			eventType: EventView,
+			after:     start.Add(70 * time.Minute),
+			before:    start.Add(100 * time.Minute),
+			expected:  []Count{{URL: "/b", Count: 1}},
+		},
+		"clicks": {
+			projectID: 1,
+			eventType: EventClick,
+			after:     start,
+			before:    start.Add(3 * time.Hour),
+			expected:  []Count{{URL: "/a", Count: 1}},
+		},
+		"other project": {
+			projectID: 2,
+			eventType: EventView,
+			after:     start,
+			before:    start.Add(time.Hour),
+			expected:  []Count{{URL: "/c", Count: 1}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, tracker.Sketch(test.projectID, test.eventType, test.after, test.before).Top(-1))
+		})
+	}
+
+	// the slot of the first hour is reused for the fourth one, and late events are ignored
+	tracker.Add(1, EventView, "/c", start.Add(3*time.Hour))
+	tracker.Add(1, EventView, "/a", start)
+	assert.Equal(t, []Count{{URL: "/b", Count: 3}, {URL: "/c", Count: 1}},
+		tracker.Sketch(1, EventView, start, start.Add(4*time.Hour)).Top(-1))
+
+	assert.Equal(t, start.Add(time.Hour), tracker.Since(start.Add(3*time.Hour+time.Minute)))
+}
@@ -0,0 +1,186 @@
+// tracing package configures OpenTelemetry tracing of HTTP requests and database queries.
+// Spans are exported over OTLP or printed to standard output, and trace context is
+// propagated from incoming requests using W3C Trace Context headers.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	// ServiceName is reported as service.name resource attribute of all spans.
+	ServiceName = "clicks-and-views"
+
+	instrumentationName = "google.com/ivan-sabo/clicks-and-views/internal/tracing"
+
+	statementKey = "tracing:statement"
+)
+
+// Exporters supported by Setup.
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// ErrUnsupportedExporter is returned by Setup for an unknown exporter name.
+var ErrUnsupportedExporter = errors.New("unsupported trace exporter")
+
+// Setup installs a global tracer provider which sends spans to the named exporter,
+// along with W3C Trace Context propagator. OTLP exporter is configured with standard
+// OTEL_EXPORTER_OTLP_* environment variables. The returned function flushes pending
+// spans and must be called before the program exits.
+func Setup(exporter string, stdout io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		e, err := stdouttrace.New(stdouttrace.WithWriter(stdout))
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = e
+	case ExporterOTLP:
+		e, err := otlptracehttp.New(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		spanExporter = e
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedExporter, exporter)
+	}
+
+	provider := NewTracerProvider(sdktrace.WithBatcher(spanExporter))
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// NewTracerProvider creates a tracer provider which identifies the service in all spans.
+// Tests can use it with an in-memory span processor.
+func NewTracerProvider(options ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	options = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	}, options...)
+
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// Middleware returns middleware which starts a server span for every request.
+// The span continues a trace from incoming traceparent header, if there is one.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+
+			ctx, span := otel.Tracer(instrumentationName).Start(ctx, request.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(request.Method),
+					semconv.HTTPRoute(route),
+					semconv.URLPath(request.URL.Path),
+					semconv.ClientAddress(c.RealIP()),
+				),
+			)
+			defer span.End()
+			c.SetRequest(request.WithContext(ctx))
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				status = http.StatusInternalServerError
+				var httpErr *echo.HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Code
+				}
+			}
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+
+			return err
+		}
+	}
+}
+
+// Plugin is a gorm plugin which keeps executed SQL statements, since gorm clears them
+// as soon as a query is finished. It must be used for RecordQuery to include the statements.
+type Plugin struct{}
+
+// Name implements gorm.Plugin interface.
+func (Plugin) Name() string {
+	return "tracing"
+}
+
+// Initialize implements gorm.Plugin interface.
+func (Plugin) Initialize(db *gorm.DB) error {
+	keep := func(db *gorm.DB) {
+		db.Statement.Settings.Store(statementKey, db.Statement.SQL.String())
+	}
+
+	callbacks := db.Callback()
+	for _, err := range []error{
+		callbacks.Create().After("gorm:create").Register("tracing:create", keep),
+		callbacks.Query().After("gorm:query").Register("tracing:query", keep),
+		callbacks.Update().After("gorm:update").Register("tracing:update", keep),
+		callbacks.Delete().After("gorm:delete").Register("tracing:delete", keep),
+		callbacks.Row().After("gorm:row").Register("tracing:row", keep),
+		callbacks.Raw().After("gorm:raw").Register("tracing:raw", keep),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RecordQuery adds the statement and the number of affected rows of a finished gorm query
+// to a span. The statement is recorded with placeholders, so argument values are not exported.
+func RecordQuery(span trace.Span, result *gorm.DB) {
+	statement, _ := result.Statement.Settings.Load(statementKey)
+	sql, _ := statement.(string)
+
+	span.SetAttributes(
+		semconv.DBSystemSqlite,
+		semconv.DBStatement(sql),
+		attribute.Int64("db.rows_affected", result.RowsAffected),
+	)
+	if result.Error != nil {
+		RecordError(span, result.Error)
+	}
+}
+
+// RecordError marks a span as failed.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,86 @@
+// tracker package serves tracker.js, a snippet which records views and clicks from the browser,
+// and binds the beacons it sends.
+package tracker
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Script is the tracker.js snippet.
+//
+//go:embed tracker.js
+var Script []byte
+
+// MaxBatchSize limits the number of events in a single request.
+const MaxBatchSize = 100
+
+// Serve implements handler for tracker.js HTTP request.
+func Serve(c echo.Context) error {
+	c.Response().Header().Set(echo.HeaderCacheControl, "public, max-age=3600")
+	return c.Blob(http.StatusOK, echo.MIMEApplicationJavaScriptCharsetUTF8, Script)
+}
+
+// Bind binds a request body holding either a single JSON object or a JSON array of them,
+// and reports whether it was an array. Besides application/json, the body may be sent as text/plain,
+// which is what navigator.sendBeacon sends strings as, since it's the only type that doesn't
+// require a CORS preflight. Other content types are bound by c.Bind into a single object.
+func Bind[T any](c echo.Context) (items []T, batch bool, err error) {
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	if !strings.HasPrefix(contentType, echo.MIMEApplicationJSON) && !strings.HasPrefix(contentType, echo.MIMETextPlain) {
+		var item T
+		if err := c.Bind(&item); err != nil {
+			return nil, false, err
+		}
+		return []T{item}, false, nil
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil, false, err
+	}
+	body = bytes.TrimSpace(body)
+
+	if len(body) > 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &items); err != nil {
+			return nil, true, invalidBody(err)
+		}
+		if len(items) == 0 || len(items) > MaxBatchSize {
+			return nil, true, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("batch must hold between 1 and %d events", MaxBatchSize))
+		}
+		return items, true, nil
+	}
+
+	var item T
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &item); err != nil {
+			return nil, false, invalidBody(err)
+		}
+	}
+	return []T{item}, false, nil
+}
+
+// Validate validates all bound items, so a batch is rejected as a whole before any of its events
+// is created. Errors of a batch name the invalid item by its index.
+func Validate[T interface{ Validate() error }](items []T, batch bool) error {
+	for i, item := range items {
+		if err := item.Validate(); err != nil {
+			if batch {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("event %d: %s", i, err))
+			}
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+	}
+	return nil
+}
+
+func invalidBody(err error) error {
+	return echo.NewHTTPError(http.StatusBadRequest, "request body must be a JSON object or an array of them").SetInternal(err)
+}
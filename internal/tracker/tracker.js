@@ -0,0 +1,104 @@
+/*
+ * Clicks and Views tracker.
+ *
+ *   <script src="https://clicks.example.com/tracker.js" data-key="cav_w_..." async></script>
+ *
+ * Records a view of the page when it loads, and a click on every element annotated with
+ * data-track-click. The attribute may hold the URL to record, otherwise href of the element,
+ * or the page URL, is recorded. Events are batched and sent with navigator.sendBeacon,
+ * so they aren't lost when the page is closed. Single page applications can record
+ * further views with window.clicksAndViews.view().
+ *
+ * Events are attributed to a visitor given in data-visitor-id, or later with
+ * window.clicksAndViews.identify(), which links them together for funnel analysis.
+ */
+(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  if (!script || window.clicksAndViews) {
+    return;
+  }
+
+  var endpoint = new URL(script.src).origin;
+  var key = script.getAttribute("data-key") || "";
+  var visitorId = script.getAttribute("data-visitor-id") || "";
+  var maxBatchSize = 10;
+  var flushInterval = 5000;
+  var queues = { clicks: [], views: [] };
+  var timer = null;
+
+  function eventId() {
+    if (window.crypto && crypto.randomUUID) {
+      return crypto.randomUUID();
+    }
+    return Date.now().toString(36) + Math.random().toString(36).slice(2);
+  }
+
+  function send(path, events) {
+    var url = endpoint + "/" + path + "?key=" + encodeURIComponent(key);
+    var body = JSON.stringify(events);
+    // a string is sent as text/plain, which doesn't need a CORS preflight
+    if (navigator.sendBeacon && navigator.sendBeacon(url, body)) {
+      return;
+    }
+    fetch(url, { method: "POST", body: body, keepalive: true, mode: "no-cors", headers: { "Content-Type": "text/plain" } });
+  }
+
+  function flush() {
+    clearTimeout(timer);
+    timer = null;
+    for (var path in queues) {
+      if (queues[path].length > 0) {
+        send(path, queues[path].splice(0, queues[path].length));
+      }
+    }
+  }
+
+  function enqueue(path, event) {
+    event.eventId = eventId();
+    if (visitorId) {
+      event.visitorId = visitorId;
+    }
+    queues[path].push(event);
+    if (queues[path].length >= maxBatchSize) {
+      flush();
+    } else if (timer === null) {
+      timer = setTimeout(flush, flushInterval);
+    }
+  }
+
+  function view() {
+    enqueue("views", {
+      url: location.href,
+      referrer: document.referrer,
+      screenWidth: screen.width,
+      screenHeight: screen.height
+    });
+  }
+
+  function click(url) {
+    enqueue("clicks", { url: url || location.href });
+  }
+
+  document.addEventListener("click", function (e) {
+    var el = e.target.closest && e.target.closest("[data-track-click]");
+    if (el) {
+      click(el.getAttribute("data-track-click") || el.href);
+    }
+  }, true);
+
+  document.addEventListener("visibilitychange", function () {
+    if (document.visibilityState === "hidden") {
+      flush();
+    }
+  });
+  window.addEventListener("pagehide", flush);
+
+  function identify(id) {
+    visitorId = id || "";
+  }
+
+  window.clicksAndViews = { view: view, click: click, flush: flush, identify: identify };
+  view();
+})();
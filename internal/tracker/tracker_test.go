@@ -0,0 +1,100 @@
+package tracker
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+type event struct {
+	URL string `json:"url" form:"url"`
+}
+
+func TestBind(t *testing.T) {
+	tests := []struct {
+		testName       string
+		contentType    string
+		body           string
+		expectedItems  []event
+		expectedBatch  bool
+		expectedStatus int
+	}{
+		{
+			testName:      "json object",
+			contentType:   echo.MIMEApplicationJSON,
+			body:          `{"url":"test.url1"}`,
+			expectedItems: []event{{URL: "test.url1"}},
+		},
+		{
+			testName:      "beacon object",
+			contentType:   "text/plain;charset=UTF-8",
+			body:          `{"url":"test.url1"}`,
+			expectedItems: []event{{URL: "test.url1"}},
+		},
+		{
+			testName:      "beacon array",
+			contentType:   "text/plain;charset=UTF-8",
+			body:          ` [{"url":"test.url1"},{"url":"test.url2"}]`,
+			expectedItems: []event{{URL: "test.url1"}, {URL: "test.url2"}},
+			expectedBatch: true,
+		},
+		{
+			testName:      "form",
+			contentType:   echo.MIMEApplicationForm,
+			body:          `url=test.url1`,
+			expectedItems: []event{{URL: "test.url1"}},
+		},
+		{
+			testName:       "invalid json",
+			contentType:    echo.MIMETextPlain,
+			body:           `url=test.url1`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "empty array",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			testName:       "too large batch",
+			contentType:    echo.MIMEApplicationJSON,
+			body:           "[" + strings.Repeat(`{"url":"test.url1"},`, MaxBatchSize) + `{"url":"test.url1"}]`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/clicks", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, test.contentType)
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			items, batch, err := Bind[event](c)
+			if test.expectedStatus != 0 {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedItems, items)
+			assert.Equal(t, test.expectedBatch, batch)
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/tracker.js", nil), rec)
+
+	assert.NoError(t, Serve(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMEApplicationJavaScriptCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Body.String(), "navigator.sendBeacon")
+}
@@ -0,0 +1,181 @@
+// urlmatch package matches URLs of events against conditions on the whole URL, its host or its path,
+// both in SQL and in memory, so stored and streamed events are filtered the same way.
+package urlmatch
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MaxValues is the largest number of URLs a single condition of a Match may list.
+const MaxValues = 100
+
+// Match holds conditions on URLs. Empty conditions are ignored and all of the others have to hold.
+// Glob patterns match any sequence of characters with '*' and any single character with '?'.
+type Match struct {
+	// URLs matches any of the listed URLs exactly.
+	URLs []string
+	// NotURLs matches none of the listed URLs.
+	NotURLs []string
+	// Prefix matches URLs starting with it.
+	Prefix string
+	// Glob is a pattern the whole URL has to match, such as "https://example.com/blog/*".
+	Glob string
+	// Host matches the host of the URL, without the port, case insensitively.
+	Host string
+	// Domain matches the host of the URL and all of its subdomains.
+	Domain string
+	// Path is a pattern the path of the URL has to match, such as "/docs/*".
+	// URLs without a scheme and host, such as "/pricing", are paths themselves.
+	Path string
+}
+
+// IsZero reports whether the Match has no conditions, so it matches all URLs.
+func (m Match) IsZero() bool {
+	return len(m.URLs) == 0 && len(m.NotURLs) == 0 && m.Prefix == "" && m.Glob == "" &&
+		m.Host == "" && m.Domain == "" && m.Path == ""
+}
+
+// Validate checks that the Match can be applied.
+func (m Match) Validate() error {
+	if len(m.URLs) > MaxValues || len(m.NotURLs) > MaxValues {
+		return fmt.Errorf("at most %d URLs can be listed", MaxValues)
+	}
+	return nil
+}
+
+// Apply adds the conditions of a Match to a query of a table with url, host and path columns.
+// Values are always passed as arguments, and patterns are translated into GLOB ones with
+// other special characters escaped, so they are matched case sensitively as given.
+func Apply(tx *gorm.DB, m Match) *gorm.DB {
+	if len(m.URLs) > 0 {
+		tx = tx.Where("url IN ?", m.URLs)
+	}
+	if len(m.NotURLs) > 0 {
+		tx = tx.Where("url NOT IN ?", m.NotURLs)
+	}
+	if m.Prefix != "" {
+		tx = tx.Where("url GLOB ?", escape(m.Prefix)+"*")
+	}
+	if m.Glob != "" {
+		tx = tx.Where("url GLOB ?", glob(m.Glob))
+	}
+	if m.Host != "" {
+		tx = tx.Where("host = ?", strings.ToLower(m.Host))
+	}
+	if m.Domain != "" {
+		domain := strings.ToLower(m.Domain)
+		tx = tx.Where("(host = ? OR host GLOB ?)", domain, "*."+escape(domain))
+	}
+	if m.Path != "" {
+		tx = tx.Where("path GLOB ?", glob(m.Path))
+	}
+
+	return tx
+}
+
+// Matches reports whether a URL satisfies all conditions of the Match.
+func (m Match) Matches(rawURL string) bool {
+	if len(m.URLs) > 0 && !contains(m.URLs, rawURL) {
+		return false
+	}
+	if contains(m.NotURLs, rawURL) {
+		return false
+	}
+	if !strings.HasPrefix(rawURL, m.Prefix) {
+		return false
+	}
+	if m.Glob != "" && !matchGlob(m.Glob, rawURL) {
+		return false
+	}
+
+	host, path := Split(rawURL)
+	if m.Host != "" && host != strings.ToLower(m.Host) {
+		return false
+	}
+	if domain := strings.ToLower(m.Domain); domain != "" && host != domain && !strings.HasSuffix(host, "."+domain) {
+		return false
+	}
+	if m.Path != "" && !matchGlob(m.Path, path) {
+		return false
+	}
+
+	return true
+}
+
+// Split returns the lower case host, without the port, and the path of a URL, which are stored
+// along with it to be matched. Both are empty when the URL can't be parsed.
+func Split(rawURL string) (host, path string) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ""
+	}
+
+	host, path = strings.ToLower(u.Hostname()), u.Path
+	if host != "" && path == "" {
+		path = "/"
+	}
+
+	return host, path
+}
+
+// escape escapes characters special to GLOB, by enclosing them in brackets.
+func escape(s string) string {
+	return strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]").Replace(s)
+}
+
+// glob translates a pattern into a GLOB one, keeping only '*' and '?' special.
+func glob(pattern string) string {
+	return strings.ReplaceAll(pattern, "[", "[[]")
+}
+
+// matchGlob reports whether s matches a pattern the same way GLOB does its translation.
+func matchGlob(pattern, s string) bool {
+	p, t := []rune(pattern), []rune(s)
+	i, j := 0, 0
+	// star is the position of the last '*' in the pattern, and match the position in s it matches up to
+	star, match := -1, 0
+	for j < len(t) {
+		switch {
+		case i < len(p) && p[i] == '*':
+			star, match = i, j
+			i++
+		case i < len(p) && (p[i] == '?' || p[i] == t[j]):
+			i++
+			j++
+		case star >= 0:
+			match++
+			i, j = star+1, match
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '*' {
+		i++
+	}
+
+	return i == len(p)
+}
+
+// NonEmpty returns values without empty ones, such as of query parameters given without a value.
+func NonEmpty(values []string) []string {
+	var nonEmpty []string
+	for _, v := range values {
+		if v != "" {
+			nonEmpty = append(nonEmpty, v)
+		}
+	}
+	return nonEmpty
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
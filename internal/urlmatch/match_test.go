@@ -0,0 +1,114 @@
+package urlmatch
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type eventDAO struct {
+	ID   uint `gorm:"primarykey"`
+	URL  string
+	Host string
+	Path string
+}
+
+func TestApply(t *testing.T) {
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	defer func() {
+		os.Remove("gorm.db")
+	}()
+	assert.NoError(t, gormDB.AutoMigrate(&eventDAO{}))
+
+	urls := []string{
+		"https://example.com/blog/first",
+		"https://example.com/blog/[draft]",
+		"https://example.com/blog*",
+		"https://shop.example.com/cart",
+		"https://notexample.com/",
+		"/pricing",
+	}
+	for _, u := range urls {
+		dao := eventDAO{URL: u}
+		dao.Host, dao.Path = Split(u)
+		assert.NoError(t, gormDB.Create(&dao).Error)
+	}
+
+	tests := map[string]struct {
+		match    Match
+		expected []string
+	}{
+		"no conditions": {Match{}, urls},
+		"any of URLs":   {Match{URLs: []string{"/pricing", "/missing"}}, []string{"/pricing"}},
+		"none of URLs":  {Match{NotURLs: urls[1:]}, urls[:1]},
+		"prefix":        {Match{Prefix: "https://example.com/blog/"}, urls[:2]},
+		"prefix is literal": {
+			Match{Prefix: "https://example.com/blog*"},
+			[]string{"https://example.com/blog*"},
+		},
+		"glob":              {Match{Glob: "https://*.com/*"}, urls[:5]},
+		"glob single":       {Match{Glob: "/pric?ng"}, []string{"/pricing"}},
+		"glob brackets":     {Match{Glob: "*/[draft]"}, []string{"https://example.com/blog/[draft]"}},
+		"glob is anchored":  {Match{Glob: "/blog/*"}, nil},
+		"host":              {Match{Host: "Shop.Example.com"}, []string{"https://shop.example.com/cart"}},
+		"domain":            {Match{Domain: "example.com"}, urls[:4]},
+		"path":              {Match{Path: "/blog/*"}, urls[:2]},
+		"path without host": {Match{Path: "/pricing"}, []string{"/pricing"}},
+		"quotes are values": {Match{URLs: []string{"' OR 1=1 --"}}, nil},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var daos []eventDAO
+			assert.NoError(t, Apply(gormDB.Order("id"), test.match).Find(&daos).Error)
+
+			var matched []string
+			for _, dao := range daos {
+				matched = append(matched, dao.URL)
+			}
+			assert.Equal(t, test.expected, matched)
+
+			// matching in memory must agree with the database
+			matched = nil
+			for _, u := range urls {
+				if test.match.Matches(u) {
+					matched = append(matched, u)
+				}
+			}
+			assert.Equal(t, test.expected, matched)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := map[string]struct {
+		url  string
+		host string
+		path string
+	}{
+		"absolute":      {"https://Example.com:8080/a/b?c=d#e", "example.com", "/a/b"},
+		"without path":  {"https://example.com", "example.com", "/"},
+		"relative":      {"/pricing?plan=pro", "", "/pricing"},
+		"not a URL":     {"%zz", "", ""},
+		"empty":         {"", "", ""},
+		"without slash": {"test.url", "", "test.url"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			host, path := Split(test.url)
+			assert.Equal(t, test.host, host)
+			assert.Equal(t, test.path, path)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Match{URLs: make([]string, MaxValues)}.Validate())
+	assert.Error(t, Match{URLs: make([]string, MaxValues+1)}.Validate())
+	assert.Error(t, Match{NotURLs: make([]string, MaxValues+1)}.Validate())
+}
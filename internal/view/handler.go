@@ -1,35 +1,109 @@
 package view
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/ratelimit"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracker"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 )
 
 // ViewDTO represents HTTP request/response model.
+// EventID is an optional client generated identifier, used to recognise resubmissions.
+// VisitorID is an optional client generated identifier of the visitor, used to analyse funnels.
 type ViewDTO struct {
-	ID        uint   `json:"id,omitempty"`
-	URL       string `json:"url" validate:"required,url"`
-	CreatedAt string `json:"createdAt,omitempty"`
+	ID           uint   `json:"id,omitempty"`
+	EventID      string `json:"eventId,omitempty"`
+	VisitorID    string `json:"visitorId,omitempty"`
+	URL          string `json:"url" validate:"required,url"`
+	Referrer     string `json:"referrer,omitempty"`
+	ScreenWidth  int    `json:"screenWidth,omitempty"`
+	ScreenHeight int    `json:"screenHeight,omitempty"`
+	CreatedAt    string `json:"createdAt,omitempty"`
 }
 
 // ToDomain maps DTO model into domain model.
 func (c ViewDTO) ToDomain() View {
 	return View{
-		URL: c.URL,
+		ExternalID:   c.EventID,
+		VisitorID:    c.VisitorID,
+		URL:          c.URL,
+		Referrer:     c.Referrer,
+		ScreenWidth:  c.ScreenWidth,
+		ScreenHeight: c.ScreenHeight,
 	}
 }
 
 // NewViewDTO is a ClickDTO constructor.
 func NewViewDTO(c View) ViewDTO {
 	return ViewDTO{
-		ID:        c.ID,
-		URL:       c.URL,
-		CreatedAt: c.CreatedAt.Format(time.DateTime),
+		ID:           c.ID,
+		EventID:      c.ExternalID,
+		VisitorID:    c.VisitorID,
+		URL:          c.URL,
+		Referrer:     c.Referrer,
+		ScreenWidth:  c.ScreenWidth,
+		ScreenHeight: c.ScreenHeight,
+		CreatedAt:    c.CreatedAt.Format(time.RFC3339),
 	}
 }
 
+// Validate checks that the ViewDTO can be created.
+func (c ViewDTO) Validate() error {
+	if c.URL == "" {
+		return errors.New("url is required")
+	}
+	if c.ScreenWidth < 0 || c.ScreenHeight < 0 {
+		return errors.New("screenWidth and screenHeight must not be negative")
+	}
+	return nil
+}
+
+// ViewPatchDTO represents HTTP request model of correcting a View. Only the fields present are changed.
+type ViewPatchDTO struct {
+	URL          *string `json:"url"`
+	VisitorID    *string `json:"visitorId"`
+	Referrer     *string `json:"referrer"`
+	ScreenWidth  *int    `json:"screenWidth"`
+	ScreenHeight *int    `json:"screenHeight"`
+}
+
+// Apply changes a View with the fields present in the ViewPatchDTO.
+func (p ViewPatchDTO) Apply(view View) (View, error) {
+	if p.URL != nil {
+		if *p.URL == "" {
+			return View{}, errors.New("url must not be empty")
+		}
+		view.URL = *p.URL
+	}
+	if p.VisitorID != nil {
+		view.VisitorID = *p.VisitorID
+	}
+	if p.Referrer != nil {
+		view.Referrer = *p.Referrer
+	}
+	if p.ScreenWidth != nil {
+		view.ScreenWidth = *p.ScreenWidth
+	}
+	if p.ScreenHeight != nil {
+		view.ScreenHeight = *p.ScreenHeight
+	}
+	if view.ScreenWidth < 0 || view.ScreenHeight < 0 {
+		return View{}, errors.New("screenWidth and screenHeight must not be negative")
+	}
+	return view, nil
+}
+
 // ViewDTOCollection represents ViewDTO collection.
 type ViewDTOCollection []ViewDTO
 
@@ -45,39 +119,116 @@ func NewViewDTOCollection(viewCollection ViewCollection) ViewDTOCollection {
 }
 
 // FilterDTO represents HTTP request model.
+// URL may be repeated to match any of the URLs, and NotURL, given as "url!=", excludes URLs.
+// URLGlob and Path are patterns, where '*' matches any characters and '?' a single one.
+// Host matches the host of URLs exactly, and Domain also its subdomains.
+// Before and After accept RFC 3339 timestamps, Unix epoch seconds and expressions relative to now, such as "now-7d".
+// AfterID and Limit page through results, which are ordered by ID.
+// Q is an expression of the query language, such as `url ~ "/blog/" or createdAt > now-1d`.
 type FilterDTO struct {
-	URL    string    `query:"url"`
-	Before time.Time `query:"before"`
-	After  time.Time `query:"after"`
+	URL       []string       `query:"url"`
+	NotURL    []string       `query:"url!"`
+	URLPrefix string         `query:"urlPrefix"`
+	URLGlob   string         `query:"urlGlob"`
+	Host      string         `query:"host"`
+	Domain    string         `query:"domain"`
+	Path      string         `query:"path"`
+	Before    timeparam.Time `query:"before"`
+	After     timeparam.Time `query:"after"`
+	AfterID   uint           `query:"afterId"`
+	Limit     int            `query:"limit"`
+	Q         string         `query:"q"`
 }
 
-// ToDomain maps DTO model into domain model.
-func (f *FilterDTO) ToDomain() Filter {
-	return Filter{
-		URL:    f.URL,
-		Before: f.Before,
-		After:  f.After,
+// ToDomain maps DTO model into domain model. Relative times of Q are resolved against now.
+func (f *FilterDTO) ToDomain(now time.Time) (Filter, error) {
+	condition, err := query.ParseAndCompile(f.Q, queryFields, now)
+	if err != nil {
+		return Filter{}, err
 	}
+
+	return Filter{
+		URLMatch: urlmatch.Match{
+			URLs:    urlmatch.NonEmpty(f.URL),
+			NotURLs: urlmatch.NonEmpty(f.NotURL),
+			Prefix:  f.URLPrefix,
+			Glob:    f.URLGlob,
+			Host:    f.Host,
+			Domain:  f.Domain,
+			Path:    f.Path,
+		},
+		Before:  f.Before.Time,
+		After:   f.After.Time,
+		Query:   condition,
+		AfterID: f.AfterID,
+		Limit:   f.Limit,
+	}, nil
+}
+
+// DeletedDTO represents HTTP response model of deleting Views matching a filter.
+type DeletedDTO struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// PurgeDTO represents HTTP request model. DeletedBefore limits purging to Views deleted before it,
+// all deleted Views are purged when it is not given.
+type PurgeDTO struct {
+	DeletedBefore timeparam.Time `query:"deletedBefore"`
+}
+
+// PurgedDTO represents HTTP response model of purging deleted Views.
+type PurgedDTO struct {
+	Purged int64 `json:"purged"`
 }
 
 // Handler defines all API methods for View.
 type Handler struct {
 	viewRepository Repository
+	dedupWindow    *dedup.Window[View]
+	hub            *stream.Hub[View]
+	counters       *live.Counters
 }
 
 // Create implements handler for Create View HTTP request.
+// Resubmitting a known EventID, or submitting the same URL again from the same visitor
+// within the dedup window, returns the original View instead of creating a new one.
+// A JSON array of Views is validated as a whole, created one by one and responded to with an array.
 func (h *Handler) Create(c echo.Context) error {
-	var viewDTO ViewDTO
-	if err := c.Bind(&viewDTO); err != nil {
+	viewDTOs, batch, err := tracker.Bind[ViewDTO](c)
+	if err != nil {
 		return err
 	}
-
-	click, err := h.viewRepository.Create(c.Request().Context(), viewDTO.ToDomain())
-	if err != nil {
+	if err := tracker.Validate(viewDTOs, batch); err != nil {
+		return err
+	}
+	// every event of a batch counts against rate limits, not only the request
+	if err := ratelimit.Charge(c, len(viewDTOs)-1); err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusCreated, NewViewDTO(click))
+	response := make(ViewDTOCollection, 0, len(viewDTOs))
+	for _, viewDTO := range viewDTOs {
+		view := viewDTO.ToDomain()
+		view.ProjectID = project.ID(c)
+
+		view, err := h.create(c, view)
+		if err != nil {
+			return err
+		}
+		response = append(response, NewViewDTO(view))
+	}
+
+	if batch {
+		return c.JSON(http.StatusCreated, response)
+	}
+	return c.JSON(http.StatusCreated, response[0])
+}
+
+// create records a View submitted through any of the endpoints, so they all deduplicate,
+// stream and count Views the same way, including the gRPC API. A resubmitted View is returned in place of the new one.
+func (h *Handler) create(c echo.Context, view View) (View, error) {
+	recorder := NewRecorder(h.viewRepository, h.dedupWindow, h.hub, h.counters)
+	return recorder.Record(c.Request().Context(), dedup.Visitor(c.Request(), c.RealIP()), view)
 }
 
 // Filter implements handler for Filter View HTTP request.
@@ -87,7 +238,20 @@ func (h *Handler) Filter(c echo.Context) error {
 		return err
 	}
 
-	viewCollection, err := h.viewRepository.Filter(c.Request().Context(), filterDTO.ToDomain())
+	if filterDTO.Limit < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit must not be negative")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	filter.ProjectID = project.ID(c)
+
+	viewCollection, err := h.viewRepository.Filter(c.Request().Context(), filter)
 	if err != nil {
 		return err
 	}
@@ -95,9 +259,197 @@ func (h *Handler) Filter(c echo.Context) error {
 	return c.JSON(http.StatusOK, NewViewDTOCollection(viewCollection))
 }
 
+// Stream implements handler for Stream View HTTP request.
+// Newly created Views are pushed to the client as Server-Sent Events, optionally limited to matching URLs.
+// Clients reconnecting with Last-Event-ID header receive Views they have missed in the meantime.
+func (h *Handler) Stream(c echo.Context) error {
+	if h.hub == nil {
+		return echo.ErrNotFound
+	}
+
+	var filterDTO FilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	// streamed events are matched in memory, which the query language doesn't support
+	if filterDTO.Q != "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "q is not supported by streams")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var match func(View) bool
+	if !filter.URLMatch.IsZero() {
+		match = func(view View) bool {
+			return filter.URLMatch.Matches(view.URL)
+		}
+	}
+
+	subscription := h.hub.Subscribe(project.ID(c), match, stream.LastEventID(c))
+
+	return stream.Serve(c, h.hub, subscription, "view", func(view View) any {
+		return NewViewDTO(view)
+	})
+}
+
+// Get implements handler for Get View HTTP request.
+func (h *Handler) Get(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	view, err := h.viewRepository.Get(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewViewDTO(view))
+}
+
+// Update implements handler for Update View HTTP request, which corrects a recorded View.
+func (h *Handler) Update(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var patchDTO ViewPatchDTO
+	if err := c.Bind(&patchDTO); err != nil {
+		return err
+	}
+
+	view, err := h.viewRepository.Get(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+	view, err = patchDTO.Apply(view)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	view, err = h.viewRepository.Update(c.Request().Context(), view)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewViewDTO(view))
+}
+
+// Delete implements handler for Delete View HTTP request.
+// The View is only marked as deleted and kept until it is purged.
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.viewRepository.Delete(c.Request().Context(), project.ID(c), id); err != nil {
+		return notFound(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Restore implements handler for Restore View HTTP request, which undoes deleting a View until it is purged.
+func (h *Handler) Restore(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	view, err := h.viewRepository.Restore(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewViewDTO(view))
+}
+
+// DeleteMatching implements handler for Delete Views HTTP request.
+// It takes the same filters as Filter, at least one of them is required so all Views of a project
+// are not deleted by accident.
+func (h *Handler) DeleteMatching(c echo.Context) error {
+	var filterDTO FilterDTO
+	if err := c.Bind(&filterDTO); err != nil {
+		return err
+	}
+
+	if filterDTO.Limit != 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "limit is not supported when deleting")
+	}
+
+	filter, err := filterDTO.ToDomain(time.Now())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err := filter.URLMatch.Validate(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if filter.IsZero() {
+		return echo.NewHTTPError(http.StatusBadRequest, "at least one filter is required")
+	}
+	filter.ProjectID = project.ID(c)
+
+	deleted, err := h.viewRepository.DeleteMatching(c.Request().Context(), filter)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, DeletedDTO{Deleted: deleted})
+}
+
+// Purge implements handler for Purge Views HTTP request, which is meant for administrators.
+// It permanently removes deleted Views of the project given in the path.
+func (h *Handler) Purge(c echo.Context) error {
+	projectID, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	var purgeDTO PurgeDTO
+	if err := c.Bind(&purgeDTO); err != nil {
+		return err
+	}
+
+	purged, err := h.viewRepository.Purge(c.Request().Context(), projectID, purgeDTO.DeletedBefore.Time)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, PurgedDTO{Purged: purged})
+}
+
 // NewHandler is a Handler constructor.
-func NewHandler(viewRepository Repository) Handler {
+// Nil dedupWindow disables deduplication of repeated submissions by the same visitor,
+// nil hub disables streaming of created Views and nil counters disables counting them.
+func NewHandler(viewRepository Repository, dedupWindow *dedup.Window[View], hub *stream.Hub[View], counters *live.Counters) Handler {
 	return Handler{
 		viewRepository: viewRepository,
+		dedupWindow:    dedupWindow,
+		hub:            hub,
+		counters:       counters,
+	}
+}
+
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
 	}
+	return err
 }
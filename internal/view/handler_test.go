@@ -13,6 +13,12 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 )
 
 type ViewRepositoryMock struct {
@@ -24,42 +30,177 @@ func (m *ViewRepositoryMock) Create(ctx context.Context, view View) (View, error
 	return args.Get(0).(View), args.Error(1)
 }
 
+func (m *ViewRepositoryMock) CreateBatch(ctx context.Context, views ViewCollection) (int64, error) {
+	args := m.Called(ctx, views)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func (m *ViewRepositoryMock) Filter(ctx context.Context, filter Filter) (ViewCollection, error) {
 	args := m.Called(ctx, filter)
 	return args.Get(0).(ViewCollection), args.Error(1)
 }
 
+func (m *ViewRepositoryMock) Count(ctx context.Context, filter Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Aggregate(ctx context.Context, filter Filter, groupBy GroupBy) (GroupCollection, error) {
+	args := m.Called(ctx, filter, groupBy)
+	return args.Get(0).(GroupCollection), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Get(ctx context.Context, projectID, id uint) (View, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Update(ctx context.Context, v View) (View, error) {
+	args := m.Called(ctx, v)
+	return args.Get(0).(View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Delete(ctx context.Context, projectID, id uint) error {
+	args := m.Called(ctx, projectID, id)
+	return args.Error(0)
+}
+
+func (m *ViewRepositoryMock) Restore(ctx context.Context, projectID, id uint) (View, error) {
+	args := m.Called(ctx, projectID, id)
+	return args.Get(0).(View), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) DeleteMatching(ctx context.Context, filter Filter) (int64, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *ViewRepositoryMock) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	args := m.Called(ctx, projectID, deletedBefore)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 func TestHandlerCreate(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`{"url":"test.url1"}`))
 	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
 
 	timeNow := time.Now()
 
 	viewRepository := &ViewRepositoryMock{}
 	viewRepository.
-		On("Create", c.Request().Context(), View{URL: "test.url1"}).
+		On("Create", c.Request().Context(), View{ProjectID: 1, URL: "test.url1"}).
 		Return(
 			View{
 				ID:        1,
+				ProjectID: 1,
 				URL:       "test.url1",
 				CreatedAt: timeNow,
 			},
 			nil,
 		).Once()
 
-	h := &Handler{viewRepository: viewRepository}
+	hub := stream.NewHub[View](0, 1)
+	subscription := hub.Subscribe(1, nil, 0)
+	counters := live.NewCounters(1)
+
+	h := &Handler{viewRepository: viewRepository, hub: hub, counters: counters}
 
 	if assert.NoError(t, h.Create(c)) {
 		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, 1, len(subscription.Events()), "created View is published")
+		assert.Equal(t, uint64(1), counters.Get(1, "test.url1", timeNow).Views.LastMinute, "created View is counted")
 
-		expectedJSON := fmt.Sprintf(`{"id":1,"url":"test.url1","createdAt":"%s"}`+"\n", timeNow.Format(time.DateTime))
+		expectedJSON := fmt.Sprintf(`{"id":1,"url":"test.url1","createdAt":"%s"}`+"\n", timeNow.Format(time.RFC3339))
 		assert.Equal(t, expectedJSON, rec.Body.String())
 	}
 }
 
+func TestHandlerCreateInvalidBatch(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(`[{"url":"test.url1"},{"url":"test.url2","screenWidth":-1}]`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+
+	viewRepository := &ViewRepositoryMock{}
+	h := &Handler{viewRepository: viewRepository, hub: stream.NewHub[View](0, 1), counters: live.NewCounters(1)}
+
+	err := h.Create(c)
+	if assert.Error(t, err) {
+		httpErr, ok := err.(*echo.HTTPError)
+		if assert.True(t, ok) {
+			assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+			assert.Equal(t, "event 1: screenWidth and screenHeight must not be negative", httpErr.Message)
+		}
+		viewRepository.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	}
+}
+
+func TestHandlerCreateDuplicate(t *testing.T) {
+	timeNow := time.Now()
+	original := View{ID: 1, ProjectID: 1, ExternalID: "ext-1", URL: "test.url1", CreatedAt: timeNow}
+	expectedJSON := fmt.Sprintf(`{"id":1,"eventId":"ext-1","url":"test.url1","createdAt":"%s"}`+"\n", timeNow.Format(time.RFC3339))
+
+	tests := []struct {
+		testName    string
+		body        string
+		dedupWindow bool
+		setup       func(*ViewRepositoryMock)
+	}{
+		{
+			testName: "known event ID",
+			body:     `{"eventId":"ext-1","url":"test.url1"}`,
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Create", mock.Anything, View{ProjectID: 1, ExternalID: "ext-1", URL: "test.url1"}).
+					Return(original, ErrDuplicate).Once()
+			},
+		},
+		{
+			testName:    "same visitor and URL within dedup window",
+			body:        `{"url":"test.url1"}`,
+			dedupWindow: true,
+			setup:       func(m *ViewRepositoryMock) {},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/views", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			test.setup(viewRepository)
+
+			dedupWindow := dedup.NewWindow[View](time.Second)
+			dedupWindow.Put(fmt.Sprintf("1|%s|test.url1", dedup.Visitor(req, c.RealIP())), original)
+
+			hub := stream.NewHub[View](0, 1)
+			subscription := hub.Subscribe(1, nil, 0)
+
+			h := &Handler{viewRepository: viewRepository, hub: hub}
+			if test.dedupWindow {
+				h.dedupWindow = dedupWindow
+			}
+
+			if assert.NoError(t, h.Create(c)) {
+				assert.Equal(t, http.StatusCreated, rec.Code)
+				assert.Equal(t, expectedJSON, rec.Body.String())
+				assert.Equal(t, 0, len(subscription.Events()), "duplicate is not published")
+				viewRepository.AssertExpectations(t)
+			}
+		})
+	}
+}
+
 func TestHandlerFilter(t *testing.T) {
 	e := echo.New()
 	q := make(url.Values)
@@ -67,16 +208,18 @@ func TestHandlerFilter(t *testing.T) {
 	req := httptest.NewRequest(http.MethodGet, "/views?"+q.Encode(), nil)
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
 
 	timeNow := time.Now()
 
 	viewRepository := &ViewRepositoryMock{}
 	viewRepository.
-		On("Filter", c.Request().Context(), Filter{URL: "test.url1"}).
+		On("Filter", c.Request().Context(), Filter{ProjectID: 1, URLMatch: urlmatch.Match{URLs: []string{"test.url1"}}}).
 		Return(
 			ViewCollection{
 				{
 					ID:        1,
+					ProjectID: 1,
 					URL:       "test.url1",
 					CreatedAt: timeNow,
 				},
@@ -89,7 +232,369 @@ func TestHandlerFilter(t *testing.T) {
 	if assert.NoError(t, h.Filter(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 
-		expectedJSON := fmt.Sprintf(`[{"id":1,"url":"test.url1","createdAt":"%s"}]`+"\n", timeNow.Format(time.DateTime))
+		expectedJSON := fmt.Sprintf(`[{"id":1,"url":"test.url1","createdAt":"%s"}]`+"\n", timeNow.Format(time.RFC3339))
 		assert.Equal(t, expectedJSON, rec.Body.String())
 	}
 }
+
+func TestHandlerFilterURLs(t *testing.T) {
+	tooMany := make(url.Values)
+	for i := 0; i <= urlmatch.MaxValues; i++ {
+		tooMany.Add("url", fmt.Sprintf("/page/%d", i))
+	}
+
+	tests := map[string]struct {
+		query    string
+		expected urlmatch.Match
+		code     int
+	}{
+		"multiple URLs": {
+			query:    "url=/a&url=/b&url=",
+			expected: urlmatch.Match{URLs: []string{"/a", "/b"}},
+		},
+		"negation": {
+			query:    "url!=/a&url!=/b",
+			expected: urlmatch.Match{NotURLs: []string{"/a", "/b"}},
+		},
+		"parts": {
+			query:    "urlPrefix=https://example.com/&urlGlob=*/blog/*&host=example.com&domain=example.com&path=/blog/*",
+			expected: urlmatch.Match{Prefix: "https://example.com/", Glob: "*/blog/*", Host: "example.com", Domain: "example.com", Path: "/blog/*"},
+		},
+		"too many URLs": {
+			query: tooMany.Encode(),
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/views?"+test.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			if test.code == 0 {
+				viewRepository.On("Filter", mock.Anything, Filter{ProjectID: 1, URLMatch: test.expected}).Return(ViewCollection{}, nil).Once()
+			}
+			h := &Handler{viewRepository: viewRepository}
+
+			err := h.Filter(c)
+			if test.code != 0 {
+				var httpErr *echo.HTTPError
+				if assert.ErrorAs(t, err, &httpErr) {
+					assert.Equal(t, test.code, httpErr.Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			viewRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerFilterQuery(t *testing.T) {
+	tests := map[string]struct {
+		query    string
+		expected query.Condition
+		err      string
+	}{
+		"query": {
+			query:    `url ~ "/blog/" or id = 2`,
+			expected: query.Condition{SQL: "(instr(url, ?) > 0 OR id = ?)", Args: []any{"/blog/", int64(2)}},
+		},
+		"syntax error": {
+			query: `url ~ "/blog/" and`,
+			err:   "invalid query at column 19: expected field name, found end of query",
+		},
+		"unknown field": {
+			query: `device = "mobile"`,
+			err:   `invalid query at column 1: unknown field "device"`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			q := make(url.Values)
+			q.Set("q", test.query)
+			req := httptest.NewRequest(http.MethodGet, "/views?"+q.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			if test.err == "" {
+				viewRepository.On("Filter", mock.Anything, Filter{ProjectID: 1, Query: test.expected}).Return(ViewCollection{}, nil).Once()
+			}
+			h := &Handler{viewRepository: viewRepository}
+
+			err := h.Filter(c)
+			if test.err != "" {
+				var httpErr *echo.HTTPError
+				if assert.ErrorAs(t, err, &httpErr) {
+					assert.Equal(t, http.StatusBadRequest, httpErr.Code)
+					assert.Contains(t, httpErr.Message, test.err)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			viewRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerGet(t *testing.T) {
+	timeNow := time.Now()
+
+	tests := map[string]struct {
+		id    string
+		setup func(m *ViewRepositoryMock)
+		code  int
+		body  string
+	}{
+		"found": {
+			id: "1",
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Get", mock.Anything, uint(1), uint(1)).Return(View{ID: 1, ProjectID: 1, URL: "test.url1", CreatedAt: timeNow}, nil).Once()
+			},
+			code: http.StatusOK,
+			body: fmt.Sprintf(`{"id":1,"url":"test.url1","createdAt":"%s"}`, timeNow.Format(time.RFC3339)),
+		},
+		"not found": {
+			id: "2",
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Get", mock.Anything, uint(1), uint(2)).Return(View{}, ErrNotFound).Once()
+			},
+			code: http.StatusNotFound,
+		},
+		"invalid id": {
+			id:    "abc",
+			setup: func(m *ViewRepositoryMock) {},
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(test.id)
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			test.setup(viewRepository)
+			h := &Handler{viewRepository: viewRepository}
+
+			err := h.Get(c)
+			if test.code != http.StatusOK {
+				var httpErr *echo.HTTPError
+				if assert.ErrorAs(t, err, &httpErr) {
+					assert.Equal(t, test.code, httpErr.Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, test.body, rec.Body.String())
+			viewRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerDelete(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set(project.ContextKey, uint(1))
+
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.On("Delete", mock.Anything, uint(1), uint(1)).Return(nil).Once()
+	viewRepository.On("Delete", mock.Anything, uint(1), uint(1)).Return(ErrNotFound).Once()
+	h := &Handler{viewRepository: viewRepository}
+
+	if assert.NoError(t, h.Delete(c)) {
+		assert.Equal(t, http.StatusNoContent, rec.Code)
+	}
+
+	var httpErr *echo.HTTPError
+	if assert.ErrorAs(t, h.Delete(c), &httpErr) {
+		assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	}
+	viewRepository.AssertExpectations(t)
+}
+
+func TestHandlerUpdate(t *testing.T) {
+	original := View{ID: 1, ProjectID: 1, URL: "https://example.com/a", VisitorID: "v1", CreatedAt: time.Now()}
+	updated := original
+	updated.URL = "https://example.com/b"
+
+	tests := []struct {
+		testName     string
+		body         string
+		setup        func(*ViewRepositoryMock)
+		expectedCode int
+	}{
+		{
+			testName: "changed URL",
+			body:     `{"url":"https://example.com/b"}`,
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Get", mock.Anything, uint(1), uint(1)).Return(original, nil).Once()
+				m.On("Update", mock.Anything, updated).Return(updated, nil).Once()
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			testName: "empty URL",
+			body:     `{"url":""}`,
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Get", mock.Anything, uint(1), uint(1)).Return(original, nil).Once()
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			testName: "deleted",
+			body:     `{"url":"https://example.com/b"}`,
+			setup: func(m *ViewRepositoryMock) {
+				m.On("Get", mock.Anything, uint(1), uint(1)).Return(View{}, ErrNotFound).Once()
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("1")
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			test.setup(viewRepository)
+			h := &Handler{viewRepository: viewRepository}
+
+			err := h.Update(c)
+			if test.expectedCode == http.StatusOK {
+				if assert.NoError(t, err) {
+					assert.Equal(t, http.StatusOK, rec.Code)
+					assert.Contains(t, rec.Body.String(), `"url":"https://example.com/b"`)
+					assert.Contains(t, rec.Body.String(), `"visitorId":"v1"`, "fields missing from the body are kept")
+				}
+			} else {
+				var httpErr *echo.HTTPError
+				if assert.ErrorAs(t, err, &httpErr) {
+					assert.Equal(t, test.expectedCode, httpErr.Code)
+				}
+			}
+			viewRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerRestore(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("1")
+	c.Set(project.ContextKey, uint(1))
+
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.On("Restore", mock.Anything, uint(1), uint(1)).Return(View{ID: 1, ProjectID: 1, URL: "test.url1"}, nil).Once()
+	viewRepository.On("Restore", mock.Anything, uint(1), uint(1)).Return(View{}, ErrNotFound).Once()
+	h := &Handler{viewRepository: viewRepository}
+
+	if assert.NoError(t, h.Restore(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"id":1`)
+	}
+
+	var httpErr *echo.HTTPError
+	if assert.ErrorAs(t, h.Restore(c), &httpErr) {
+		assert.Equal(t, http.StatusNotFound, httpErr.Code)
+	}
+	viewRepository.AssertExpectations(t)
+}
+
+func TestHandlerDeleteMatching(t *testing.T) {
+	tests := map[string]struct {
+		query    string
+		expected Filter
+		code     int
+	}{
+		"filtered": {
+			query:    "host=staging.example.com&before=1704164645",
+			expected: Filter{ProjectID: 1, URLMatch: urlmatch.Match{Host: "staging.example.com"}, Before: time.Unix(1704164645, 0).UTC()},
+		},
+		"no filters": {
+			query: "url=",
+			code:  http.StatusBadRequest,
+		},
+		"limit": {
+			query: "host=staging.example.com&limit=10",
+			code:  http.StatusBadRequest,
+		},
+		"invalid query": {
+			query: "q=url",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/views?"+test.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			viewRepository := &ViewRepositoryMock{}
+			if test.code == 0 {
+				viewRepository.On("DeleteMatching", mock.Anything, test.expected).Return(int64(3), nil).Once()
+			}
+			h := &Handler{viewRepository: viewRepository}
+
+			err := h.DeleteMatching(c)
+			if test.code != 0 {
+				var httpErr *echo.HTTPError
+				if assert.ErrorAs(t, err, &httpErr) {
+					assert.Equal(t, test.code, httpErr.Code)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.JSONEq(t, `{"deleted":3}`, rec.Body.String())
+			viewRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandlerPurge(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodDelete, "/admin/projects/2/views?deletedBefore=1704164645", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("2")
+
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.On("Purge", mock.Anything, uint(2), time.Unix(1704164645, 0).UTC()).Return(int64(5), nil).Once()
+	h := &Handler{viewRepository: viewRepository}
+
+	if assert.NoError(t, h.Purge(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"purged":5}`, rec.Body.String())
+		viewRepository.AssertExpectations(t)
+	}
+}
@@ -4,28 +4,102 @@ package view
 
 import (
 	"context"
+	"errors"
 	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 )
 
 // View represents entity model of a single view.
+// Referrer and screen size are known only for views recorded by tracker.js.
+// VisitorID is an optional identifier of the visitor, which links events of the same visitor together.
 type View struct {
-	ID        uint
-	URL       string
-	CreatedAt time.Time
+	ID           uint
+	ProjectID    uint
+	ExternalID   string
+	VisitorID    string
+	URL          string
+	Referrer     string
+	ScreenWidth  int
+	ScreenHeight int
+	CreatedAt    time.Time
 }
 
 // ViewCollection represents a collection of View domain entities.
 type ViewCollection []View
 
 // Filter holds parameters available for filtering Views.
+// Results are always limited to a single project.
 type Filter struct {
-	URL    string
+	ProjectID uint
+	URL       string
+	// URLMatch holds further conditions on URLs, which have to hold along with URL.
+	URLMatch urlmatch.Match
+	// Query is a condition compiled from the query language, which has to hold along with the other filters.
+	Query  query.Condition
 	After  time.Time
 	Before time.Time
+	// AfterID skips Views up to and including a given ID, results are ordered by ID
+	// so it can be used along with Limit to paginate through them.
+	AfterID uint
+	// Limit is the maximum number of results, zero means no limit.
+	Limit int
 }
 
+// IsZero reports whether the Filter has no conditions besides the project, so it matches all of its Views.
+func (f Filter) IsZero() bool {
+	return f.URL == "" && f.URLMatch.IsZero() && f.Query.IsZero() && f.After.IsZero() && f.Before.IsZero() && f.AfterID == 0
+}
+
+// Period is the length of time buckets Views are grouped into.
+type Period string
+
+// Supported periods.
+const (
+	PeriodHour  Period = "hour"
+	PeriodDay   Period = "day"
+	PeriodMonth Period = "month"
+)
+
+// GroupBy holds grouping of aggregated Views. Zero value aggregates all Views into a single Group.
+// Time buckets start at the full hour, day or month in Location, nil Location is UTC.
+type GroupBy struct {
+	URL      bool
+	Period   Period
+	Location *time.Location
+}
+
+// Group holds the number of Views in a single group.
+// URL and Period are set only when Views are grouped by them, Period is the start of a time bucket in GroupBy.Location.
+type Group struct {
+	URL    string
+	Period time.Time
+	Count  int64
+}
+
+// GroupCollection represents a collection of Groups.
+type GroupCollection []Group
+
+// ErrDuplicate is returned by Repository.Create, along with the already stored entity,
+// when a View with the same ExternalID exists in the project.
+var ErrDuplicate = errors.New("duplicate view")
+
+// ErrNotFound is returned when a View doesn't exist in the project, or it was deleted.
+var ErrNotFound = errors.New("view not found")
+
 // Repository defines a storage API for View entity.
+// Deleted Views are kept until purged, but are not returned, counted or deleted again.
 type Repository interface {
 	Create(context.Context, View) (View, error)
+	CreateBatch(context.Context, ViewCollection) (int64, error)
 	Filter(context.Context, Filter) (ViewCollection, error)
+	Count(context.Context, Filter) (int64, error)
+	Aggregate(context.Context, Filter, GroupBy) (GroupCollection, error)
+	Get(ctx context.Context, projectID, id uint) (View, error)
+	Update(context.Context, View) (View, error)
+	Delete(ctx context.Context, projectID, id uint) error
+	Restore(ctx context.Context, projectID, id uint) (View, error)
+	DeleteMatching(context.Context, Filter) (int64, error)
+	Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error)
 }
@@ -0,0 +1,37 @@
+package view
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// pixel is a 1x1 transparent GIF.
+var pixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// Pixel implements handler for Pixel HTTP request.
+// It records a View of the URL given in "url" query parameter and responds with a transparent GIF,
+// which tracks views in emails and other places where JavaScript doesn't run.
+func (h *Handler) Pixel(c echo.Context) error {
+	url := c.QueryParam("url")
+	if url == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "url is required")
+	}
+
+	// an image should be displayed even if the view can't be recorded
+	if _, err := h.create(c, View{ProjectID: project.ID(c), VisitorID: c.QueryParam("visitorId"), URL: url}); err != nil {
+		c.Logger().Error(err)
+	}
+
+	// every display must reach the server to be counted
+	header := c.Response().Header()
+	header.Set(echo.HeaderCacheControl, "no-cache, no-store, must-revalidate")
+	header.Set("Pragma", "no-cache")
+	header.Set("Expires", "0")
+	return c.Blob(http.StatusOK, "image/gif", pixel)
+}
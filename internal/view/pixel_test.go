@@ -0,0 +1,47 @@
+package view
+
+import (
+	"bytes"
+	"image/gif"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+func TestHandlerPixel(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/v.gif?url=https%3A%2F%2Fexample.com%2Fnewsletter", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(project.ContextKey, uint(1))
+
+	viewRepository := &ViewRepositoryMock{}
+	viewRepository.On("Create", mock.Anything, View{ProjectID: 1, URL: "https://example.com/newsletter"}).
+		Return(View{ID: 1, ProjectID: 1, URL: "https://example.com/newsletter"}, nil).Once()
+
+	h := &Handler{viewRepository: viewRepository}
+	if assert.NoError(t, h.Pixel(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/gif", rec.Header().Get(echo.HeaderContentType))
+		assert.Equal(t, "no-cache, no-store, must-revalidate", rec.Header().Get(echo.HeaderCacheControl))
+
+		image, err := gif.Decode(bytes.NewReader(rec.Body.Bytes()))
+		if assert.NoError(t, err) {
+			assert.Equal(t, 1, image.Bounds().Dx())
+			assert.Equal(t, 1, image.Bounds().Dy())
+			_, _, _, alpha := image.At(0, 0).RGBA()
+			assert.Zero(t, alpha, "pixel is transparent")
+		}
+		viewRepository.AssertExpectations(t)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v.gif", nil)
+	c = e.NewContext(req, httptest.NewRecorder())
+	err := h.Pixel(c)
+	assert.Equal(t, http.StatusBadRequest, err.(*echo.HTTPError).Code)
+}
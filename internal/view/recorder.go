@@ -0,0 +1,76 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/dedup"
+	"google.com/ivan-sabo/clicks-and-views/internal/live"
+	"google.com/ivan-sabo/clicks-and-views/internal/stream"
+)
+
+// Recorder records Views submitted through any of the APIs, so they all deduplicate,
+// stream and count Views the same way.
+type Recorder struct {
+	repository  Repository
+	dedupWindow *dedup.Window[View]
+	hub         *stream.Hub[View]
+	counters    *live.Counters
+}
+
+// Record creates a View submitted by a visitor, an identifier of the client such as dedup.AnonymousVisitor,
+// which is used only if the View carries no VisitorID. Resubmitting a known ExternalID, or submitting
+// the same URL again from the same visitor within the dedup window, returns the original View
+// instead of creating a new one.
+func (r Recorder) Record(ctx context.Context, visitor string, view View) (View, error) {
+	var dedupKey string
+	if r.dedupWindow != nil {
+		if view.VisitorID != "" {
+			// visitors sharing an address and a browser are told apart, and a visitor is recognised across devices
+			visitor = "id:" + view.VisitorID
+		}
+		dedupKey = fmt.Sprintf("%d|%s|%s", view.ProjectID, visitor, view.URL)
+		// reserving the key makes concurrent identical submissions wait for the first one
+		original, ok, err := r.dedupWindow.Reserve(ctx, dedupKey)
+		if err != nil {
+			return View{}, err
+		}
+		if ok {
+			return original, nil
+		}
+	}
+
+	view, err := r.repository.Create(ctx, view)
+	if err != nil && !errors.Is(err, ErrDuplicate) {
+		if r.dedupWindow != nil {
+			r.dedupWindow.Release(dedupKey)
+		}
+		return View{}, err
+	}
+
+	if r.hub != nil && err == nil {
+		r.hub.Publish(view.ProjectID, view)
+	}
+	if r.counters != nil && err == nil {
+		r.counters.Add(view.ProjectID, view.URL, live.KindView, view.CreatedAt)
+	}
+
+	if r.dedupWindow != nil {
+		r.dedupWindow.Put(dedupKey, view)
+	}
+
+	return view, nil
+}
+
+// NewRecorder is a Recorder constructor.
+// Nil dedupWindow disables deduplication of repeated submissions by the same visitor,
+// nil hub disables streaming of created Views and nil counters disables counting them.
+func NewRecorder(repository Repository, dedupWindow *dedup.Window[View], hub *stream.Hub[View], counters *live.Counters) Recorder {
+	return Recorder{
+		repository:  repository,
+		dedupWindow: dedupWindow,
+		hub:         hub,
+		counters:    counters,
+	}
+}
@@ -2,16 +2,37 @@ package view
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel"
+	"google.com/ivan-sabo/clicks-and-views/internal/query"
+	"google.com/ivan-sabo/clicks-and-views/internal/timeparam"
+	"google.com/ivan-sabo/clicks-and-views/internal/tracing"
+	"google.com/ivan-sabo/clicks-and-views/internal/urlmatch"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var tracer = otel.Tracer("google.com/ivan-sabo/clicks-and-views/internal/view")
+
 // ViewDAO represents a single database entry.
 type ViewDAO struct {
-	ID        uint `gorm:"primarykey"`
-	CreatedAt time.Time
-	URL       string
+	ID           uint    `gorm:"primarykey"`
+	ProjectID    uint    `gorm:"index;uniqueIndex:idx_views_project_external_id"`
+	ExternalID   *string `gorm:"uniqueIndex:idx_views_project_external_id"`
+	VisitorID    string  `gorm:"index"`
+	CreatedAt    time.Time
+	URL          string
+	Host         string `gorm:"index"`
+	Path         string `gorm:"index"`
+	Referrer     string
+	ScreenWidth  int
+	ScreenHeight int
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
 }
 
 // TableName overrides the table name used by ViewDAO to 'views'
@@ -21,11 +42,20 @@ func (ViewDAO) TableName() string {
 
 // ToModel maps database model into domain model.
 func (c *ViewDAO) ToDomain() View {
-	return View{
-		ID:        c.ID,
-		CreatedAt: c.CreatedAt,
-		URL:       c.URL,
+	view := View{
+		ID:           c.ID,
+		ProjectID:    c.ProjectID,
+		VisitorID:    c.VisitorID,
+		CreatedAt:    c.CreatedAt,
+		URL:          c.URL,
+		Referrer:     c.Referrer,
+		ScreenWidth:  c.ScreenWidth,
+		ScreenHeight: c.ScreenHeight,
+	}
+	if c.ExternalID != nil {
+		view.ExternalID = *c.ExternalID
 	}
+	return view
 }
 
 // ViewDAOCollection represents a collection of View database model.
@@ -42,15 +72,27 @@ func (cc ViewDAOCollection) ToDomain() ViewCollection {
 }
 
 // NewViewDAO maps Click entity model into database model.
+// CreatedAt is stored in UTC, so it can be compared as text, and host and path are split out of URL to be matched.
 func NewViewDAO(c View) ViewDAO {
 	if c.CreatedAt.IsZero() {
 		c.CreatedAt = time.Now()
 	}
-	return ViewDAO{
-		ID:        c.ID,
-		CreatedAt: c.CreatedAt,
-		URL:       c.URL,
+	c.CreatedAt = c.CreatedAt.UTC()
+	dao := ViewDAO{
+		ID:           c.ID,
+		ProjectID:    c.ProjectID,
+		VisitorID:    c.VisitorID,
+		CreatedAt:    c.CreatedAt,
+		URL:          c.URL,
+		Referrer:     c.Referrer,
+		ScreenWidth:  c.ScreenWidth,
+		ScreenHeight: c.ScreenHeight,
 	}
+	dao.Host, dao.Path = urlmatch.Split(c.URL)
+	if c.ExternalID != "" {
+		dao.ExternalID = &c.ExternalID
+	}
+	return dao
 }
 
 // SQLiteRepository is a SQLite implementation of View repository.
@@ -58,38 +100,410 @@ type SQLiteRepository struct {
 	db *gorm.DB
 }
 
-// Create persists Click entity.
+// Create persists View entity.
+// If a View with the same ExternalID already exists in the project, it is returned
+// along with ErrDuplicate and nothing is persisted.
 func (r *SQLiteRepository) Create(ctx context.Context, view View) (View, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Create")
+	defer span.End()
+
 	dao := NewViewDAO(view)
 
-	result := r.db.WithContext(ctx).Create(&dao)
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&dao)
+	tracing.RecordQuery(span, result)
 	if result.Error != nil {
 		return View{}, result.Error
 	}
 
+	if result.RowsAffected == 0 {
+		// the original may have been deleted since, it is still the one the external ID belongs to
+		var existing ViewDAO
+		err := r.db.WithContext(ctx).Unscoped().
+			Where("project_id = ? AND external_id = ?", dao.ProjectID, dao.ExternalID).
+			First(&existing).Error
+		if err != nil {
+			tracing.RecordError(span, err)
+			return View{}, err
+		}
+		return existing.ToDomain(), ErrDuplicate
+	}
+
 	return dao.ToDomain(), nil
 }
 
+// CreateBatch persists multiple View entities in a single statement.
+// Entities whose ExternalID is already stored in the same project are skipped, so the same batch
+// can be safely submitted more than once. It returns the number of inserted rows.
+func (r *SQLiteRepository) CreateBatch(ctx context.Context, views ViewCollection) (int64, error) {
+	if len(views) == 0 {
+		return 0, nil
+	}
+
+	daos := make(ViewDAOCollection, 0, len(views))
+	for _, view := range views {
+		daos = append(daos, NewViewDAO(view))
+	}
+
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&daos)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Filter applies provided filters and returns resulting subset.
 func (r *SQLiteRepository) Filter(ctx context.Context, filter Filter) (ViewCollection, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Filter")
+	defer span.End()
+
 	var views ViewDAOCollection
 
-	tx := r.db.WithContext(ctx)
+	tx := r.filtered(ctx, filter).Order("id")
+	if filter.AfterID > 0 {
+		tx = tx.Where("id > ?", filter.AfterID)
+	}
+	if filter.Limit > 0 {
+		tx = tx.Limit(filter.Limit)
+	}
+
+	result := tx.Find(&views)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return ViewCollection{}, result.Error
+	}
+
+	return views.ToDomain(), nil
+}
+
+// Count returns the number of Views matching provided filters.
+func (r *SQLiteRepository) Count(ctx context.Context, filter Filter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Count")
+	defer span.End()
+
+	var count int64
+	result := r.filtered(ctx, filter).Model(&ViewDAO{}).Count(&count)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return count, nil
+}
+
+// periodFormats map periods to strftime formats truncating timestamps to the start of a period.
+var periodFormats = map[Period]string{
+	PeriodHour:  "%Y-%m-%d %H:00:00",
+	PeriodDay:   "%Y-%m-%d 00:00:00",
+	PeriodMonth: "%Y-%m-01 00:00:00",
+}
+
+// Aggregate counts Views matching provided filters in groups, ordered by Period and URL.
+func (r *SQLiteRepository) Aggregate(ctx context.Context, filter Filter, groupBy GroupBy) (GroupCollection, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Aggregate")
+	defer span.End()
+
+	columns := []string{"COUNT(*) AS count"}
+	var groups []string
+	if groupBy.URL {
+		columns = append(columns, "url")
+		groups = append(groups, "url")
+	}
+	loc := groupBy.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	var args []any
+	if groupBy.Period != "" {
+		format, ok := periodFormats[groupBy.Period]
+		if !ok {
+			return nil, fmt.Errorf("unsupported period %q", groupBy.Period)
+		}
+		modifier, modifierArgs, err := r.localTime(ctx, filter, loc)
+		if err != nil {
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		columns = append(columns, fmt.Sprintf("strftime('%s', created_at%s) AS period", format, modifier))
+		args = modifierArgs
+		groups = append([]string{"period"}, groups...)
+	}
+
+	tx := r.filtered(ctx, filter).Model(&ViewDAO{}).Select(strings.Join(columns, ", "), args...)
+	if len(groups) > 0 {
+		tx = tx.Group(strings.Join(groups, ", ")).Order(strings.Join(groups, ", "))
+	}
+
+	var rows []struct {
+		URL    string
+		Period string
+		Count  int64
+	}
+	result := tx.Scan(&rows)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	collection := make(GroupCollection, 0, len(rows))
+	for _, row := range rows {
+		group := Group{URL: row.URL, Count: row.Count}
+		if row.Period != "" {
+			period, err := time.ParseInLocation(time.DateTime, row.Period, loc)
+			if err != nil {
+				return nil, err
+			}
+			group.Period = period
+		}
+		collection = append(collection, group)
+	}
+
+	return collection, nil
+}
+
+// localTime returns a strftime modifier shifting created_at from UTC into loc, along with its arguments.
+// Offsets of loc are looked up between the bounds of the filter, or between the earliest and the latest
+// View matching it when not bounded, so daylight saving time is taken into account.
+func (r *SQLiteRepository) localTime(ctx context.Context, filter Filter, loc *time.Location) (string, []any, error) {
+	if loc == time.UTC {
+		return "", nil, nil
+	}
+
+	from, to := filter.After, filter.Before
+	if from.IsZero() || to.IsZero() {
+		var bounds struct {
+			First sql.NullInt64
+			Last  sql.NullInt64
+		}
+		err := r.filtered(ctx, filter).Model(&ViewDAO{}).
+			Select("MIN(CAST(strftime('%s', created_at) AS INTEGER)) AS first, MAX(CAST(strftime('%s', created_at) AS INTEGER)) AS last").
+			Scan(&bounds).Error
+		if err != nil {
+			return "", nil, err
+		}
+		if from.IsZero() {
+			from = time.Unix(bounds.First.Int64, 0)
+		}
+		if to.IsZero() {
+			to = time.Unix(bounds.Last.Int64+1, 0)
+		}
+	}
+
+	offsets := timeparam.Offsets(loc, from, to)
+	if len(offsets) == 1 {
+		return ", ?", []any{fmt.Sprintf("%+d seconds", offsets[0].Seconds)}, nil
+	}
+
+	var b strings.Builder
+	var args []any
+	b.WriteString(", CASE")
+	for i := len(offsets) - 1; i > 0; i-- {
+		b.WriteString(" WHEN created_at >= ? THEN ?")
+		args = append(args, offsets[i].Since.UTC(), fmt.Sprintf("%+d seconds", offsets[i].Seconds))
+	}
+	b.WriteString(" ELSE ? END")
+	args = append(args, fmt.Sprintf("%+d seconds", offsets[0].Seconds))
+
+	return b.String(), args, nil
+}
+
+// filtered starts a query limited by provided filters.
+func (r *SQLiteRepository) filtered(ctx context.Context, filter Filter) *gorm.DB {
+	tx := r.db.WithContext(ctx).Where("project_id = ?", filter.ProjectID)
 
 	if filter.URL != "" {
 		tx = tx.Where("url = ?", filter.URL)
 	}
+	tx = urlmatch.Apply(tx, filter.URLMatch)
+	tx = query.Apply(tx, filter.Query)
 	if !filter.After.IsZero() {
-		tx = tx.Where("created_at > ?", filter.After)
+		tx = tx.Where("created_at > ?", filter.After.UTC())
 	}
 	if !filter.Before.IsZero() {
-		tx = tx.Where("created_at < ?", filter.Before)
+		tx = tx.Where("created_at < ?", filter.Before.UTC())
 	}
 
-	if tx.Find(&views).Error != nil {
-		return ViewCollection{}, r.db.Error
+	return tx
+}
+
+// Get returns a View of a project, or ErrNotFound.
+func (r *SQLiteRepository) Get(ctx context.Context, projectID, id uint) (View, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Get")
+	defer span.End()
+
+	var dao ViewDAO
+	result := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&dao)
+	tracing.RecordQuery(span, result)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return View{}, ErrNotFound
+	}
+	if result.Error != nil {
+		return View{}, result.Error
 	}
 
-	return views.ToDomain(), nil
+	return dao.ToDomain(), nil
+}
+
+// Delete marks a View of a project as deleted, or returns ErrNotFound.
+func (r *SQLiteRepository) Delete(ctx context.Context, projectID, id uint) error {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Delete")
+	defer span.End()
+
+	result := softDelete(r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id))
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Update corrects the URL, the visitor, the referrer and the screen size of a View of a project which isn't deleted,
+// or returns ErrNotFound. Its ID, event ID and creation time are kept.
+func (r *SQLiteRepository) Update(ctx context.Context, v View) (View, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Update")
+	defer span.End()
+
+	host, path := urlmatch.Split(v.URL)
+	result := r.db.WithContext(ctx).Model(&ViewDAO{}).Where("project_id = ? AND id = ?", v.ProjectID, v.ID).
+		Updates(map[string]any{"url": v.URL, "host": host, "path": path, "visitor_id": v.VisitorID,
+			"referrer": v.Referrer, "screen_width": v.ScreenWidth, "screen_height": v.ScreenHeight})
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return View{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return View{}, ErrNotFound
+	}
+
+	return r.Get(ctx, v.ProjectID, v.ID)
+}
+
+// Restore clears the deletion mark of a View of a project which isn't purged yet, or returns ErrNotFound.
+// Restoring a View which isn't deleted returns it unchanged.
+func (r *SQLiteRepository) Restore(ctx context.Context, projectID, id uint) (View, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Restore")
+	defer span.End()
+
+	result := r.db.WithContext(ctx).Unscoped().Model(&ViewDAO{}).
+		Where("project_id = ? AND id = ? AND deleted_at IS NOT NULL", projectID, id).Update("deleted_at", nil)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return View{}, result.Error
+	}
+
+	return r.Get(ctx, projectID, id)
+}
+
+// DeleteMatching marks Views matching provided filters as deleted and returns their number.
+// Limit of the filter is ignored.
+func (r *SQLiteRepository) DeleteMatching(ctx context.Context, filter Filter) (int64, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.DeleteMatching")
+	defer span.End()
+
+	tx := r.filtered(ctx, filter)
+	if filter.AfterID > 0 {
+		tx = tx.Where("id > ?", filter.AfterID)
+	}
+
+	result := softDelete(tx)
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// Purge permanently removes Views of a project which were deleted before a given time,
+// or all deleted ones when it is zero. It returns the number of removed Views.
+func (r *SQLiteRepository) Purge(ctx context.Context, projectID uint, deletedBefore time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "view.SQLiteRepository.Purge")
+	defer span.End()
+
+	tx := r.db.WithContext(ctx).Unscoped().Where("project_id = ? AND deleted_at IS NOT NULL", projectID)
+	if !deletedBefore.IsZero() {
+		tx = tx.Where("deleted_at < ?", deletedBefore.UTC())
+	}
+
+	result := tx.Delete(&ViewDAO{})
+	tracing.RecordQuery(span, result)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	return result.RowsAffected, nil
+}
+
+// softDelete marks Views selected by a query as deleted, unless they already are.
+// Deletion time is stored in UTC, so it can be compared as text like CreatedAt.
+func softDelete(tx *gorm.DB) *gorm.DB {
+	return tx.Model(&ViewDAO{}).Update("deleted_at", time.Now().UTC())
+}
+
+// BackfillURLParts stores hosts and paths of Views stored before they were split out of URLs,
+// in batches of a given size. It returns the number of updated Views.
+func (r *SQLiteRepository) BackfillURLParts(ctx context.Context, batchSize int) (int64, error) {
+	var updated int64
+	var batch ViewDAOCollection
+	result := r.db.WithContext(ctx).Unscoped().Select("id", "url").Where("host = '' AND path = ''").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, dao := range batch {
+					host, path := urlmatch.Split(dao.URL)
+					if host == "" && path == "" {
+						continue
+					}
+					err := tx.Unscoped().Model(&ViewDAO{}).Where("id = ?", dao.ID).Updates(map[string]any{"host": host, "path": path}).Error
+					if err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			})
+		})
+
+	return updated, result.Error
+}
+
+// BackfillUTC converts creation times of Views stored with another offset than UTC, before they
+// were normalized on creation, in batches of a given size. It returns the number of updated Views.
+func (r *SQLiteRepository) BackfillUTC(ctx context.Context, batchSize int) (int64, error) {
+	var updated int64
+	var batch ViewDAOCollection
+	result := r.db.WithContext(ctx).Unscoped().Select("id", "created_at").Where("created_at NOT LIKE ?", "%+00:00").
+		FindInBatches(&batch, batchSize, func(_ *gorm.DB, _ int) error {
+			return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, dao := range batch {
+					err := tx.Unscoped().Model(&ViewDAO{}).Where("id = ?", dao.ID).Update("created_at", dao.CreatedAt.UTC()).Error
+					if err != nil {
+						return err
+					}
+					updated++
+				}
+				return nil
+			})
+		})
+
+	return updated, result.Error
+}
+
+// queryFields are the fields of Views available in the query language, mapped to their columns.
+var queryFields = query.Fields{
+	"id":           {SQL: "id", Type: query.Int},
+	"eventId":      {SQL: "COALESCE(external_id, '')", Type: query.String},
+	"visitorId":    {SQL: "visitor_id", Type: query.String},
+	"url":          {SQL: "url", Type: query.String},
+	"host":         {SQL: "host", Type: query.String},
+	"path":         {SQL: "path", Type: query.String},
+	"createdAt":    {SQL: "created_at", Type: query.Time},
+	"referrer":     {SQL: "referrer", Type: query.String},
+	"screenWidth":  {SQL: "screen_width", Type: query.Int},
+	"screenHeight": {SQL: "screen_height", Type: query.Int},
 }
 
 // NewSQLiteRepository is a SQLiteRepository constructor.
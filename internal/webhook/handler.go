@@ -0,0 +1,331 @@
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+)
+
+// ConditionDTO represents HTTP request/response model.
+// Window is a duration such as "1h" or "15m".
+type ConditionDTO struct {
+	URL       string `json:"url,omitempty"`
+	Threshold int64  `json:"threshold"`
+	Window    string `json:"window"`
+}
+
+// WebhookDTO represents HTTP request/response model.
+// Secret is populated only in the response to webhook creation.
+type WebhookDTO struct {
+	ID              uint         `json:"id,omitempty"`
+	URL             string       `json:"url"`
+	Secret          string       `json:"secret,omitempty"`
+	EventType       EventType    `json:"eventType"`
+	Condition       ConditionDTO `json:"condition"`
+	LastTriggeredAt string       `json:"lastTriggeredAt,omitempty"`
+	CreatedAt       string       `json:"createdAt,omitempty"`
+}
+
+// ToDomain maps DTO model into domain model, validating it on the way.
+func (w WebhookDTO) ToDomain() (Webhook, error) {
+	target, err := url.Parse(w.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Webhook{}, errors.New("url must be an absolute http or https URL")
+	}
+	if w.EventType != EventClick && w.EventType != EventView {
+		return Webhook{}, errors.New("eventType must be either click or view")
+	}
+	if w.Condition.Threshold < 0 {
+		return Webhook{}, errors.New("condition.threshold must not be negative")
+	}
+	window, err := time.ParseDuration(w.Condition.Window)
+	if err != nil || window < time.Second {
+		return Webhook{}, errors.New("condition.window must be a duration of at least 1s")
+	}
+
+	return Webhook{
+		URL:       w.URL,
+		Secret:    w.Secret,
+		EventType: w.EventType,
+		Condition: Condition{
+			URL:       w.Condition.URL,
+			Threshold: w.Condition.Threshold,
+			Window:    window,
+		},
+	}, nil
+}
+
+// NewWebhookDTO is a WebhookDTO constructor.
+func NewWebhookDTO(w Webhook) WebhookDTO {
+	dto := WebhookDTO{
+		ID:        w.ID,
+		URL:       w.URL,
+		EventType: w.EventType,
+		Condition: ConditionDTO{
+			URL:       w.Condition.URL,
+			Threshold: w.Condition.Threshold,
+			Window:    w.Condition.Window.String(),
+		},
+		CreatedAt: w.CreatedAt.Format(time.RFC3339),
+	}
+	if !w.LastTriggeredAt.IsZero() {
+		dto.LastTriggeredAt = w.LastTriggeredAt.Format(time.RFC3339)
+	}
+	return dto
+}
+
+// WebhookDTOCollection represents WebhookDTO collection.
+type WebhookDTOCollection []WebhookDTO
+
+// NewWebhookDTOCollection maps domain models into DTO models.
+func NewWebhookDTOCollection(webhookCollection WebhookCollection) WebhookDTOCollection {
+	webhookDTOCollection := make(WebhookDTOCollection, 0, len(webhookCollection))
+
+	for _, webhook := range webhookCollection {
+		webhookDTOCollection = append(webhookDTOCollection, NewWebhookDTO(webhook))
+	}
+
+	return webhookDTOCollection
+}
+
+// AttemptDTO represents HTTP response model.
+type AttemptDTO struct {
+	Number     int    `json:"number"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	CreatedAt  string `json:"createdAt"`
+}
+
+// NewAttemptDTO is an AttemptDTO constructor.
+func NewAttemptDTO(a Attempt) AttemptDTO {
+	return AttemptDTO{
+		Number:     a.Number,
+		StatusCode: a.StatusCode,
+		Error:      a.Error,
+		DurationMs: a.Duration.Milliseconds(),
+		CreatedAt:  a.CreatedAt.Format(time.RFC3339),
+	}
+}
+
+// DeliveryDTO represents HTTP response model.
+// NextAttemptAt is populated only for pending deliveries.
+type DeliveryDTO struct {
+	ID            uint            `json:"id"`
+	WebhookID     uint            `json:"webhookId"`
+	Status        DeliveryStatus  `json:"status"`
+	Payload       json.RawMessage `json:"payload"`
+	NextAttemptAt string          `json:"nextAttemptAt,omitempty"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     string          `json:"createdAt"`
+	Attempts      []AttemptDTO    `json:"attempts"`
+}
+
+// NewDeliveryDTO is a DeliveryDTO constructor.
+func NewDeliveryDTO(d Delivery, attempts AttemptCollection) DeliveryDTO {
+	dto := DeliveryDTO{
+		ID:        d.ID,
+		WebhookID: d.WebhookID,
+		Status:    d.Status,
+		Payload:   d.Payload,
+		LastError: d.LastError,
+		CreatedAt: d.CreatedAt.Format(time.RFC3339),
+		Attempts:  make([]AttemptDTO, 0, len(attempts)),
+	}
+	if d.Status == StatusPending {
+		dto.NextAttemptAt = d.NextAttemptAt.Format(time.RFC3339)
+	}
+	for _, a := range attempts {
+		dto.Attempts = append(dto.Attempts, NewAttemptDTO(a))
+	}
+	return dto
+}
+
+// Handler defines all API methods for webhooks.
+type Handler struct {
+	repository Repository
+	resolve    Resolver
+}
+
+// Create implements handler for Create Webhook HTTP request.
+// A random secret is generated if none is provided. The secret is returned only once, in this response.
+// URLs resolving to loopback, link-local, private and other non-public addresses are rejected.
+func (h *Handler) Create(c echo.Context) error {
+	var webhookDTO WebhookDTO
+	if err := c.Bind(&webhookDTO); err != nil {
+		return err
+	}
+
+	webhook, err := webhookDTO.ToDomain()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	if err := checkTarget(c.Request().Context(), h.resolve, webhook.URL); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+	}
+	webhook.ProjectID = project.ID(c)
+	if webhook.Secret == "" {
+		if webhook.Secret, err = newSecret(); err != nil {
+			return err
+		}
+	}
+
+	webhook, err = h.repository.CreateWebhook(c.Request().Context(), webhook)
+	if err != nil {
+		return err
+	}
+
+	response := NewWebhookDTO(webhook)
+	response.Secret = webhook.Secret
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// List implements handler for List Webhooks HTTP request.
+func (h *Handler) List(c echo.Context) error {
+	webhooks, err := h.repository.ListWebhooks(c.Request().Context(), project.ID(c))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, NewWebhookDTOCollection(webhooks))
+}
+
+// Get implements handler for Get Webhook HTTP request.
+func (h *Handler) Get(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	webhook, err := h.repository.GetWebhook(c.Request().Context(), project.ID(c), id)
+	if err != nil {
+		return notFound(err)
+	}
+
+	return c.JSON(http.StatusOK, NewWebhookDTO(webhook))
+}
+
+// Delete implements handler for Delete Webhook HTTP request.
+func (h *Handler) Delete(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	if err := h.repository.DeleteWebhook(c.Request().Context(), project.ID(c), id); err != nil {
+		return notFound(err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ListDeliveries implements handler for List Deliveries HTTP request, which serves as the delivery log.
+// Dead-letter list is requested with status=dead.
+func (h *Handler) ListDeliveries(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+
+	status := DeliveryStatus(c.QueryParam("status"))
+	switch status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "status must be one of pending, delivered or dead")
+	}
+
+	ctx := c.Request().Context()
+	if _, err := h.repository.GetWebhook(ctx, project.ID(c), id); err != nil {
+		return notFound(err)
+	}
+
+	deliveries, err := h.repository.ListDeliveries(ctx, project.ID(c), id, status)
+	if err != nil {
+		return err
+	}
+
+	response := make([]DeliveryDTO, 0, len(deliveries))
+	for _, d := range deliveries {
+		attempts, err := h.repository.ListAttempts(ctx, d.ID)
+		if err != nil {
+			return err
+		}
+		response = append(response, NewDeliveryDTO(d, attempts))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// Redeliver implements handler for Redeliver HTTP request.
+// A dead delivery is queued again with a full set of attempts.
+func (h *Handler) Redeliver(c echo.Context) error {
+	id, err := pathID(c, "id")
+	if err != nil {
+		return err
+	}
+	deliveryID, err := pathID(c, "deliveryId")
+	if err != nil {
+		return err
+	}
+
+	ctx := c.Request().Context()
+	delivery, err := h.repository.GetDelivery(ctx, project.ID(c), deliveryID)
+	if err != nil {
+		return notFound(err)
+	}
+	if delivery.WebhookID != id {
+		return notFound(ErrNotFound)
+	}
+	if delivery.Status != StatusDead {
+		return echo.NewHTTPError(http.StatusConflict, "only dead deliveries can be redelivered")
+	}
+
+	delivery.Status = StatusPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := h.repository.UpdateDelivery(ctx, delivery); err != nil {
+		return err
+	}
+
+	return c.NoContent(http.StatusAccepted)
+}
+
+// NewHandler is a Handler constructor.
+func NewHandler(repository Repository) Handler {
+	return Handler{
+		repository: repository,
+		resolve:    lookupIP,
+	}
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func pathID(c echo.Context, name string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 0)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, name+" must be a positive integer")
+	}
+	return uint(id), nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, ErrNotFound) {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+	return err
+}
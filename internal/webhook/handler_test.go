@@ -0,0 +1,181 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/project"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHandlerCreate(t *testing.T) {
+	tests := []struct {
+		testName       string
+		body           string
+		expectedStatus int
+	}{
+		{
+			testName:       "valid",
+			body:           `{"url":"https://example.com/hook","eventType":"click","condition":{"url":"test.url1","threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			testName:       "relative url",
+			body:           `{"url":"/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "loopback url",
+			body:           `{"url":"http://127.0.0.1:8080/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "url resolving to private address",
+			body:           `{"url":"https://internal.example/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "link-local url",
+			body:           `{"url":"http://169.254.169.254/latest/meta-data","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "unresolvable url",
+			body:           `{"url":"https://unknown.example/hook","eventType":"click","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "unknown event type",
+			body:           `{"url":"https://example.com/hook","eventType":"hover","condition":{"threshold":1000,"window":"1h"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			testName:       "invalid window",
+			body:           `{"url":"https://example.com/hook","eventType":"view","condition":{"threshold":1000,"window":"soon"}}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			gormDB := setupDatabase(t)
+			defer func() {
+				teardownDatabase(t)
+			}()
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(test.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(project.ContextKey, uint(1))
+
+			h := NewHandler(NewSQLiteRepository(gormDB))
+			h.resolve = func(_ context.Context, host string) ([]net.IP, error) {
+				switch host {
+				case "example.com":
+					return []net.IP{net.ParseIP("93.184.215.14")}, nil
+				case "internal.example":
+					return []net.IP{net.ParseIP("93.184.215.14"), net.ParseIP("10.0.0.1")}, nil
+				default:
+					return nil, errors.New("no such host")
+				}
+			}
+			err := h.Create(c)
+			if test.expectedStatus != http.StatusCreated {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+
+			var created WebhookDTO
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+			assert.NotEmpty(t, created.Secret, "secret is generated")
+			assert.Equal(t, "1h0m0s", created.Condition.Window)
+
+			webhooks, _ := NewSQLiteRepository(gormDB).ListWebhooks(context.Background(), 1)
+			assert.Len(t, webhooks, 1)
+		})
+	}
+}
+
+func TestHandlerRedeliver(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	repository := NewSQLiteRepository(gormDB)
+	webhook, _ := repository.CreateWebhook(context.Background(), Webhook{ProjectID: 1, URL: "https://example.com/hook", EventType: EventClick})
+	delivered, _ := repository.Trigger(context.Background(), webhook, Delivery{ProjectID: 1, WebhookID: webhook.ID, Status: StatusDelivered})
+	dead, _ := repository.Trigger(context.Background(), webhook, Delivery{ProjectID: 1, WebhookID: webhook.ID, Status: StatusDead, Attempts: 3})
+
+	tests := []struct {
+		testName       string
+		projectID      uint
+		deliveryID     uint
+		expectedStatus int
+	}{
+		{testName: "delivered", projectID: 1, deliveryID: delivered.ID, expectedStatus: http.StatusConflict},
+		{testName: "other project", projectID: 2, deliveryID: dead.ID, expectedStatus: http.StatusNotFound},
+		{testName: "dead", projectID: 1, deliveryID: dead.ID, expectedStatus: http.StatusAccepted},
+	}
+
+	for _, test := range tests {
+		t.Run(test.testName, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+			c.SetParamNames("id", "deliveryId")
+			c.SetParamValues("1", strconv.FormatUint(uint64(test.deliveryID), 10))
+			c.Set(project.ContextKey, test.projectID)
+
+			h := NewHandler(repository)
+			err := h.Redeliver(c)
+			if test.expectedStatus != http.StatusAccepted {
+				assert.Equal(t, test.expectedStatus, err.(*echo.HTTPError).Code)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusAccepted, rec.Code)
+		})
+	}
+
+	due, err := repository.DueDeliveries(context.Background(), time.Now(), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, due, 1) {
+		assert.Equal(t, dead.ID, due[0].ID)
+		assert.Equal(t, 0, due[0].Attempts)
+	}
+}
+
+func setupDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	gormDB, err := gorm.Open(sqlite.Open("gorm.db"), &gorm.Config{})
+	assert.NoError(t, err)
+	gormDB.AutoMigrate(&WebhookDAO{}, &DeliveryDAO{}, &AttemptDAO{}, &click.ClickDAO{})
+
+	return gormDB
+}
+
+func teardownDatabase(t *testing.T) {
+	t.Helper()
+
+	os.Remove("gorm.db")
+}
@@ -0,0 +1,109 @@
+// webhook package notifies external services when the number of clicks or views
+// crosses a threshold. Notifications are signed JSON payloads, delivered with
+// retries and kept in a delivery log.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// EventType is the kind of events a Webhook counts.
+type EventType string
+
+const (
+	EventClick EventType = "click"
+	EventView  EventType = "view"
+)
+
+// Condition triggers a Webhook when more than Threshold events happen within Window.
+// Empty URL counts events of all URLs in the project.
+type Condition struct {
+	URL       string
+	Threshold int64
+	Window    time.Duration
+}
+
+// Webhook represents entity model of a registered webhook.
+// Once triggered, a Webhook is not triggered again until its Window passes.
+type Webhook struct {
+	ID              uint
+	ProjectID       uint
+	URL             string
+	Secret          string
+	EventType       EventType
+	Condition       Condition
+	LastTriggeredAt time.Time
+	CreatedAt       time.Time
+}
+
+// WebhookCollection represents a collection of Webhook domain entities.
+type WebhookCollection []Webhook
+
+// DeliveryStatus is the state of a Delivery.
+type DeliveryStatus string
+
+const (
+	// StatusPending deliveries are waiting for their next attempt.
+	StatusPending DeliveryStatus = "pending"
+	// StatusDelivered deliveries were accepted by the receiver.
+	StatusDelivered DeliveryStatus = "delivered"
+	// StatusDead deliveries have run out of attempts and form the dead-letter list.
+	StatusDead DeliveryStatus = "dead"
+)
+
+// Delivery represents a single notification sent to a Webhook, along with the state of its attempts.
+type Delivery struct {
+	ID            uint
+	ProjectID     uint
+	WebhookID     uint
+	Payload       []byte
+	Status        DeliveryStatus
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	CreatedAt     time.Time
+}
+
+// DeliveryCollection represents a collection of Delivery domain entities.
+type DeliveryCollection []Delivery
+
+// Attempt represents a single attempt to send a Delivery.
+// StatusCode is zero when no response was received.
+type Attempt struct {
+	ID         uint
+	DeliveryID uint
+	Number     int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+	CreatedAt  time.Time
+}
+
+// AttemptCollection represents a collection of Attempt domain entities.
+type AttemptCollection []Attempt
+
+// ErrNotFound is returned when a Webhook or a Delivery doesn't exist in the project.
+var ErrNotFound = errors.New("not found")
+
+// Repository defines a storage API for webhooks and their deliveries.
+type Repository interface {
+	CreateWebhook(context.Context, Webhook) (Webhook, error)
+	ListWebhooks(ctx context.Context, projectID uint) (WebhookCollection, error)
+	GetWebhook(ctx context.Context, projectID, id uint) (Webhook, error)
+	DeleteWebhook(ctx context.Context, projectID, id uint) error
+	// MatchingWebhooks returns webhooks of a project counting a given event type on a given URL.
+	MatchingWebhooks(ctx context.Context, projectID uint, eventType EventType, url string) (WebhookCollection, error)
+	// Trigger records that a Webhook was triggered and queues its Delivery.
+	Trigger(context.Context, Webhook, Delivery) (Delivery, error)
+
+	GetDelivery(ctx context.Context, projectID, id uint) (Delivery, error)
+	ListDeliveries(ctx context.Context, projectID, webhookID uint, status DeliveryStatus) (DeliveryCollection, error)
+	// DueDeliveries returns pending deliveries whose next attempt is due at a given time.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) (DeliveryCollection, error)
+	UpdateDelivery(context.Context, Delivery) error
+	// SaveAttempt stores an Attempt along with the resulting state of its Delivery.
+	SaveAttempt(context.Context, Delivery, Attempt) error
+	ListAttempts(ctx context.Context, deliveryID uint) (AttemptCollection, error)
+}
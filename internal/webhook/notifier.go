@@ -0,0 +1,398 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// PayloadEvent is the name of the event sent when a threshold is exceeded.
+const PayloadEvent = "threshold.exceeded"
+
+// Config holds delivery parameters of a Notifier.
+type Config struct {
+	// QueueSize is the number of created events waiting for evaluation,
+	// events created while the queue is full are not evaluated.
+	QueueSize int
+	// MaxAttempts is the number of attempts after which a delivery becomes dead.
+	MaxAttempts int
+	// InitialBackoff is the delay after the first failed attempt, doubled after each next one up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// PollInterval is how often due deliveries are looked up.
+	PollInterval time.Duration
+	// Timeout bounds a single delivery attempt.
+	Timeout time.Duration
+	// Workers is the number of webhooks delivered to at the same time.
+	Workers int
+}
+
+// DefaultConfig gives up on a delivery after retrying for about an hour and a half.
+var DefaultConfig = Config{
+	QueueSize:      10_000,
+	MaxAttempts:    10,
+	InitialBackoff: 10 * time.Second,
+	MaxBackoff:     time.Hour,
+	PollInterval:   time.Second,
+	Timeout:        10 * time.Second,
+	Workers:        10,
+}
+
+// Backoff returns the delay before the attempt following a given number of failed ones.
+func (c Config) Backoff(failed int) time.Duration {
+	d := c.InitialBackoff
+	for i := 1; i < failed && d < c.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.MaxBackoff {
+		d = c.MaxBackoff
+	}
+	return d
+}
+
+// PayloadDTO represents the body of a delivery.
+type PayloadDTO struct {
+	Event       string    `json:"event"`
+	WebhookID   uint      `json:"webhookId"`
+	EventType   EventType `json:"eventType"`
+	URL         string    `json:"url,omitempty"`
+	Threshold   int64     `json:"threshold"`
+	Window      string    `json:"window"`
+	Count       int64     `json:"count"`
+	TriggeredAt string    `json:"triggeredAt"`
+}
+
+// Sign computes the signature of a delivery, HMAC-SHA256 of the timestamp and the body
+// joined with a dot, keyed with the webhook secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+type event struct {
+	eventType EventType
+	projectID uint
+	url       string
+}
+
+// Notifier evaluates webhook conditions as events are created and delivers notifications.
+// Evaluation happens in the background, so it doesn't slow down ingestion.
+// Notifications are delivered only to public addresses.
+type Notifier struct {
+	repository      Repository
+	clickRepository click.Repository
+	viewRepository  view.Repository
+	client          *http.Client
+	config          Config
+	events          chan event
+	now             func() time.Time
+}
+
+// Observe queues a created event for evaluation. It never blocks, the event is dropped
+// if the queue is full.
+func (n *Notifier) Observe(eventType EventType, projectID uint, url string) {
+	select {
+	case n.events <- event{eventType: eventType, projectID: projectID, url: url}:
+	default:
+		log.Printf("webhook queue is full, %s on %s is not evaluated", eventType, url)
+	}
+}
+
+// Len returns the number of events waiting for evaluation.
+func (n *Notifier) Len() int {
+	return len(n.events)
+}
+
+// Cap returns the capacity of the evaluation queue.
+func (n *Notifier) Cap() int {
+	return cap(n.events)
+}
+
+// Run evaluates queued events and delivers due notifications until ctx is done.
+func (n *Notifier) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-n.events:
+				if err := n.evaluate(ctx, e); err != nil {
+					log.Printf("evaluating webhooks: %v", err)
+				}
+			}
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(n.config.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := n.DeliverDue(ctx); err != nil {
+					log.Printf("delivering webhooks: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+}
+
+// evaluate triggers webhooks whose condition is met after an event.
+func (n *Notifier) evaluate(ctx context.Context, e event) error {
+	webhooks, err := n.repository.MatchingWebhooks(ctx, e.projectID, e.eventType, e.url)
+	if err != nil {
+		return err
+	}
+
+	now := n.now()
+	for _, w := range webhooks {
+		if now.Sub(w.LastTriggeredAt) < w.Condition.Window {
+			continue
+		}
+
+		count, err := n.count(ctx, w, now.Add(-w.Condition.Window))
+		if err != nil {
+			return err
+		}
+		if count <= w.Condition.Threshold {
+			continue
+		}
+
+		payload, err := json.Marshal(PayloadDTO{
+			Event:       PayloadEvent,
+			WebhookID:   w.ID,
+			EventType:   w.EventType,
+			URL:         w.Condition.URL,
+			Threshold:   w.Condition.Threshold,
+			Window:      w.Condition.Window.String(),
+			Count:       count,
+			TriggeredAt: now.Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+
+		w.LastTriggeredAt = now
+		_, err = n.repository.Trigger(ctx, w, Delivery{
+			ProjectID:     w.ProjectID,
+			WebhookID:     w.ID,
+			Payload:       payload,
+			Status:        StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *Notifier) count(ctx context.Context, w Webhook, after time.Time) (int64, error) {
+	switch w.EventType {
+	case EventClick:
+		return n.clickRepository.Count(ctx, click.Filter{ProjectID: w.ProjectID, URL: w.Condition.URL, After: after})
+	case EventView:
+		return n.viewRepository.Count(ctx, view.Filter{ProjectID: w.ProjectID, URL: w.Condition.URL, After: after})
+	default:
+		return 0, fmt.Errorf("webhook %d: unknown event type %q", w.ID, w.EventType)
+	}
+}
+
+// DeliverDue attempts all deliveries which are due. Deliveries of different webhooks are attempted
+// by up to Config.Workers workers at the same time, and deliveries of the same webhook one after another,
+// so a slow receiver holds up only its own deliveries.
+func (n *Notifier) DeliverDue(ctx context.Context) error {
+	deliveries, err := n.repository.DueDeliveries(ctx, n.now(), 100)
+	if err != nil {
+		return err
+	}
+
+	var webhookIDs []uint
+	byWebhook := make(map[uint]DeliveryCollection)
+	for _, d := range deliveries {
+		if _, ok := byWebhook[d.WebhookID]; !ok {
+			webhookIDs = append(webhookIDs, d.WebhookID)
+		}
+		byWebhook[d.WebhookID] = append(byWebhook[d.WebhookID], d)
+	}
+
+	workers := n.config.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(webhookIDs) {
+		workers = len(webhookIDs)
+	}
+
+	queue := make(chan DeliveryCollection)
+	errs := make(chan error, len(webhookIDs))
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for deliveries := range queue {
+				if err := n.deliverAll(ctx, deliveries); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	for _, id := range webhookIDs {
+		queue <- byWebhook[id]
+	}
+	close(queue)
+	wg.Wait()
+	close(errs)
+
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+// deliverAll attempts deliveries of a single webhook in order. Once an attempt fails, the remaining
+// deliveries wait for its next attempt, instead of each of them waiting for the same receiver to time out.
+func (n *Notifier) deliverAll(ctx context.Context, deliveries DeliveryCollection) error {
+	for i, d := range deliveries {
+		d, err := n.deliver(ctx, d)
+		if err != nil {
+			return err
+		}
+		if d.Status != StatusPending {
+			continue
+		}
+
+		for _, next := range deliveries[i+1:] {
+			next.NextAttemptAt = d.NextAttemptAt
+			if err := n.repository.UpdateDelivery(ctx, next); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// deliver makes a single attempt to send a delivery, records its outcome and returns the updated delivery.
+func (n *Notifier) deliver(ctx context.Context, d Delivery) (Delivery, error) {
+	d.Attempts++
+	attempt := Attempt{
+		DeliveryID: d.ID,
+		Number:     d.Attempts,
+		CreatedAt:  n.now(),
+	}
+
+	retryable := true
+	w, err := n.repository.GetWebhook(ctx, d.ProjectID, d.WebhookID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		attempt.Error = "webhook was deleted"
+		retryable = false
+	case err != nil:
+		return Delivery{}, err
+	default:
+		attempt.StatusCode, err = n.send(ctx, w, d)
+		if err != nil {
+			attempt.Error = err.Error()
+			// the target won't become public by retrying
+			retryable = !errors.Is(err, ErrForbiddenTarget)
+		}
+	}
+	attempt.Duration = n.now().Sub(attempt.CreatedAt)
+
+	switch {
+	case attempt.Error == "":
+		d.Status = StatusDelivered
+		d.LastError = ""
+	case !retryable || d.Attempts >= n.config.MaxAttempts:
+		d.Status = StatusDead
+		d.LastError = attempt.Error
+	default:
+		d.NextAttemptAt = n.now().Add(n.config.Backoff(d.Attempts))
+		d.LastError = attempt.Error
+	}
+
+	return d, n.repository.SaveAttempt(ctx, d, attempt)
+}
+
+// send posts a signed payload and returns the response status. Responses other than 2xx are errors.
+func (n *Notifier) send(ctx context.Context, w Webhook, d Delivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, n.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := n.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clicks-and-views-webhooks")
+	req.Header.Set(HeaderDeliveryID, strconv.FormatUint(uint64(d.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, timestamp, d.Payload))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// NewNotifier is a Notifier constructor. Click and view repositories are used
+// to count events when evaluating conditions.
+func NewNotifier(repository Repository, clickRepository click.Repository, viewRepository view.Repository, config Config) *Notifier {
+	return &Notifier{
+		repository:      repository,
+		clickRepository: clickRepository,
+		viewRepository:  viewRepository,
+		client:          newHTTPClient(),
+		config:          config,
+		events:          make(chan event, config.QueueSize),
+		now:             time.Now,
+	}
+}
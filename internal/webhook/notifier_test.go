@@ -0,0 +1,276 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// receiver records deliveries and responds with queued status codes, 200 once they run out.
+type receiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, _ := io.ReadAll(req.Body)
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func TestNotifier(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	rcv := &receiver{statuses: []int{http.StatusInternalServerError}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	now, _ := time.Parse(time.DateTime, "2024-01-02 03:04:05")
+	repository := NewSQLiteRepository(gormDB)
+	clickRepository := click.NewSQLiteRepository(gormDB)
+
+	config := DefaultConfig
+	config.MaxAttempts = 3
+	notifier := NewNotifier(repository, clickRepository, view.NewSQLiteRepository(gormDB), config)
+	notifier.now = func() time.Time { return now }
+	notifier.client = server.Client()
+
+	webhook, err := repository.CreateWebhook(context.Background(), Webhook{
+		ProjectID: 1,
+		URL:       server.URL,
+		Secret:    "secret",
+		EventType: EventClick,
+		Condition: Condition{URL: "test.url1", Threshold: 2, Window: time.Hour},
+	})
+	assert.NoError(t, err)
+
+	addClick := func(url string) {
+		c, err := clickRepository.Create(context.Background(), click.Click{ProjectID: 1, URL: url, CreatedAt: now.Add(-time.Minute)})
+		assert.NoError(t, err)
+		assert.NoError(t, notifier.evaluate(context.Background(), event{eventType: EventClick, projectID: c.ProjectID, url: c.URL}))
+	}
+
+	// the threshold is not exceeded yet
+	addClick("test.url1")
+	addClick("test.url1")
+	addClick("test.url2")
+	deliveries, _ := repository.ListDeliveries(context.Background(), 1, webhook.ID, "")
+	assert.Empty(t, deliveries)
+
+	// and now it is, but only the first time within the window
+	addClick("test.url1")
+	addClick("test.url1")
+	deliveries, _ = repository.ListDeliveries(context.Background(), 1, webhook.ID, "")
+	assert.Len(t, deliveries, 1)
+
+	// the first attempt fails and the delivery is retried after the backoff
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.Len(t, rcv.requests, 1)
+
+	now = now.Add(config.InitialBackoff)
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.Len(t, rcv.requests, 2)
+
+	delivery, err := repository.GetDelivery(context.Background(), 1, deliveries[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDelivered, delivery.Status)
+
+	attempts, err := repository.ListAttempts(context.Background(), delivery.ID)
+	assert.NoError(t, err)
+	assert.Len(t, attempts, 2)
+	assert.Equal(t, http.StatusInternalServerError, attempts[0].StatusCode)
+	assert.Equal(t, "unexpected response status 500", attempts[0].Error)
+	assert.Equal(t, http.StatusOK, attempts[1].StatusCode)
+
+	// the receiver can verify the payload with the secret
+	req, body := rcv.requests[1], rcv.bodies[1]
+	timestamp, _ := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+	assert.Equal(t, Sign("secret", timestamp, body), req.Header.Get(HeaderSignature))
+	assert.Equal(t, strconv.FormatUint(uint64(delivery.ID), 10), req.Header.Get(HeaderDeliveryID))
+
+	var payload PayloadDTO
+	assert.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, PayloadDTO{
+		Event:       PayloadEvent,
+		WebhookID:   webhook.ID,
+		EventType:   EventClick,
+		URL:         "test.url1",
+		Threshold:   2,
+		Window:      "1h0m0s",
+		Count:       3,
+		TriggeredAt: "2024-01-02T03:04:05Z",
+	}, payload)
+}
+
+func TestNotifierDeadLetter(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	rcv := &receiver{statuses: []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	now := time.Now()
+	repository := NewSQLiteRepository(gormDB)
+
+	config := DefaultConfig
+	config.MaxAttempts = 3
+	notifier := NewNotifier(repository, click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB), config)
+	notifier.now = func() time.Time { return now }
+	notifier.client = server.Client()
+
+	webhook, _ := repository.CreateWebhook(context.Background(), Webhook{ProjectID: 1, URL: server.URL, EventType: EventView})
+	delivery, err := repository.Trigger(context.Background(), webhook, Delivery{
+		ProjectID:     1,
+		WebhookID:     webhook.ID,
+		Payload:       []byte(`{}`),
+		Status:        StatusPending,
+		NextAttemptAt: now,
+	})
+	assert.NoError(t, err)
+
+	for i := 1; i <= config.MaxAttempts; i++ {
+		assert.NoError(t, notifier.DeliverDue(context.Background()))
+		now = now.Add(config.Backoff(i))
+	}
+	assert.Len(t, rcv.requests, config.MaxAttempts)
+
+	dead, err := repository.ListDeliveries(context.Background(), 1, webhook.ID, StatusDead)
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 1) {
+		assert.Equal(t, delivery.ID, dead[0].ID)
+		assert.Equal(t, "unexpected response status 502", dead[0].LastError)
+	}
+
+	// dead deliveries are not attempted anymore
+	now = now.Add(24 * time.Hour)
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.Len(t, rcv.requests, config.MaxAttempts)
+}
+
+func TestNotifierSlowReceiver(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	defer close(release)
+	rcv := &receiver{}
+	fast := httptest.NewServer(rcv)
+	defer fast.Close()
+
+	now := time.Now()
+	repository := NewSQLiteRepository(gormDB)
+
+	config := DefaultConfig
+	config.Timeout = 100 * time.Millisecond
+	notifier := NewNotifier(repository, click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB), config)
+	notifier.now = func() time.Time { return now }
+	notifier.client = &http.Client{}
+
+	trigger := func(w Webhook) {
+		_, err := repository.Trigger(context.Background(), w, Delivery{
+			ProjectID:     1,
+			WebhookID:     w.ID,
+			Payload:       []byte(`{}`),
+			Status:        StatusPending,
+			NextAttemptAt: now,
+		})
+		assert.NoError(t, err)
+	}
+	slowWebhook, _ := repository.CreateWebhook(context.Background(), Webhook{ProjectID: 1, URL: slow.URL, EventType: EventView})
+	fastWebhook, _ := repository.CreateWebhook(context.Background(), Webhook{ProjectID: 1, URL: fast.URL, EventType: EventView})
+	for i := 0; i < 3; i++ {
+		trigger(slowWebhook)
+	}
+	trigger(fastWebhook)
+
+	started := time.Now()
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.Less(t, time.Since(started), 3*config.Timeout, "deliveries of the slow receiver wait for its next attempt")
+	assert.Len(t, rcv.requests, 1, "the fast receiver isn't held up by the slow one")
+
+	pending, err := repository.ListDeliveries(context.Background(), 1, slowWebhook.ID, StatusPending)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 3) {
+		for _, d := range pending {
+			assert.WithinDuration(t, now.Add(config.InitialBackoff), d.NextAttemptAt, time.Second)
+		}
+	}
+}
+
+func TestNotifierForbiddenTarget(t *testing.T) {
+	gormDB := setupDatabase(t)
+	defer func() {
+		teardownDatabase(t)
+	}()
+
+	rcv := &receiver{}
+	server := httptest.NewServer(rcv)
+	defer server.Close()
+
+	repository := NewSQLiteRepository(gormDB)
+	notifier := NewNotifier(repository, click.NewSQLiteRepository(gormDB), view.NewSQLiteRepository(gormDB), DefaultConfig)
+
+	// the target could have resolved to a public address when the webhook was created
+	webhook, _ := repository.CreateWebhook(context.Background(), Webhook{ProjectID: 1, URL: server.URL, EventType: EventView})
+	_, err := repository.Trigger(context.Background(), webhook, Delivery{
+		ProjectID:     1,
+		WebhookID:     webhook.ID,
+		Payload:       []byte(`{}`),
+		Status:        StatusPending,
+		NextAttemptAt: time.Now(),
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, notifier.DeliverDue(context.Background()))
+	assert.Empty(t, rcv.requests)
+
+	dead, err := repository.ListDeliveries(context.Background(), 1, webhook.ID, StatusDead)
+	assert.NoError(t, err)
+	if assert.Len(t, dead, 1) {
+		assert.Contains(t, dead[0].LastError, ErrForbiddenTarget.Error())
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	config := Config{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	var backoffs []time.Duration
+	for failed := 1; failed <= 5; failed++ {
+		backoffs = append(backoffs, config.Backoff(failed))
+	}
+	assert.Equal(t, []time.Duration{10 * time.Second, 20 * time.Second, 40 * time.Second, time.Minute, time.Minute}, backoffs)
+}
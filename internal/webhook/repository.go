@@ -0,0 +1,354 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookDAO represents a single database entry.
+type WebhookDAO struct {
+	ID                 uint `gorm:"primarykey"`
+	CreatedAt          time.Time
+	ProjectID          uint `gorm:"index"`
+	URL                string
+	Secret             string
+	EventType          string
+	ConditionURL       string
+	ConditionThreshold int64
+	ConditionWindow    time.Duration
+	LastTriggeredAt    time.Time
+}
+
+// TableName overrides the table name used by WebhookDAO to 'webhooks'
+func (WebhookDAO) TableName() string {
+	return "webhooks"
+}
+
+// NewWebhookDAO maps Webhook entity model into database model.
+func NewWebhookDAO(w Webhook) WebhookDAO {
+	return WebhookDAO{
+		ID:                 w.ID,
+		CreatedAt:          w.CreatedAt,
+		ProjectID:          w.ProjectID,
+		URL:                w.URL,
+		Secret:             w.Secret,
+		EventType:          string(w.EventType),
+		ConditionURL:       w.Condition.URL,
+		ConditionThreshold: w.Condition.Threshold,
+		ConditionWindow:    w.Condition.Window,
+		LastTriggeredAt:    w.LastTriggeredAt,
+	}
+}
+
+// ToDomain maps database model into domain model.
+func (w *WebhookDAO) ToDomain() Webhook {
+	return Webhook{
+		ID:        w.ID,
+		ProjectID: w.ProjectID,
+		URL:       w.URL,
+		Secret:    w.Secret,
+		EventType: EventType(w.EventType),
+		Condition: Condition{
+			URL:       w.ConditionURL,
+			Threshold: w.ConditionThreshold,
+			Window:    w.ConditionWindow,
+		},
+		LastTriggeredAt: w.LastTriggeredAt,
+		CreatedAt:       w.CreatedAt,
+	}
+}
+
+// WebhookDAOCollection represents a collection of Webhook database model.
+type WebhookDAOCollection []WebhookDAO
+
+// ToDomain maps DAO models into domain models.
+func (ww WebhookDAOCollection) ToDomain() WebhookCollection {
+	r := make(WebhookCollection, 0, len(ww))
+
+	for _, w := range ww {
+		r = append(r, w.ToDomain())
+	}
+
+	return r
+}
+
+// DeliveryDAO represents a single database entry.
+type DeliveryDAO struct {
+	ID            uint `gorm:"primarykey"`
+	CreatedAt     time.Time
+	ProjectID     uint `gorm:"index"`
+	WebhookID     uint `gorm:"index"`
+	Payload       []byte
+	Status        string    `gorm:"index:idx_webhook_deliveries_due"`
+	NextAttemptAt time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	Attempts      int
+	LastError     string
+}
+
+// TableName overrides the table name used by DeliveryDAO to 'webhook_deliveries'
+func (DeliveryDAO) TableName() string {
+	return "webhook_deliveries"
+}
+
+// NewDeliveryDAO maps Delivery entity model into database model.
+func NewDeliveryDAO(d Delivery) DeliveryDAO {
+	return DeliveryDAO{
+		ID:            d.ID,
+		CreatedAt:     d.CreatedAt,
+		ProjectID:     d.ProjectID,
+		WebhookID:     d.WebhookID,
+		Payload:       d.Payload,
+		Status:        string(d.Status),
+		NextAttemptAt: d.NextAttemptAt,
+		Attempts:      d.Attempts,
+		LastError:     d.LastError,
+	}
+}
+
+// ToDomain maps database model into domain model.
+func (d *DeliveryDAO) ToDomain() Delivery {
+	return Delivery{
+		ID:            d.ID,
+		ProjectID:     d.ProjectID,
+		WebhookID:     d.WebhookID,
+		Payload:       d.Payload,
+		Status:        DeliveryStatus(d.Status),
+		Attempts:      d.Attempts,
+		NextAttemptAt: d.NextAttemptAt,
+		LastError:     d.LastError,
+		CreatedAt:     d.CreatedAt,
+	}
+}
+
+// DeliveryDAOCollection represents a collection of Delivery database model.
+type DeliveryDAOCollection []DeliveryDAO
+
+// ToDomain maps DAO models into domain models.
+func (dd DeliveryDAOCollection) ToDomain() DeliveryCollection {
+	r := make(DeliveryCollection, 0, len(dd))
+
+	for _, d := range dd {
+		r = append(r, d.ToDomain())
+	}
+
+	return r
+}
+
+// AttemptDAO represents a single database entry.
+type AttemptDAO struct {
+	ID         uint `gorm:"primarykey"`
+	CreatedAt  time.Time
+	DeliveryID uint `gorm:"index"`
+	Number     int
+	StatusCode int
+	Error      string
+	Duration   time.Duration
+}
+
+// TableName overrides the table name used by AttemptDAO to 'webhook_attempts'
+func (AttemptDAO) TableName() string {
+	return "webhook_attempts"
+}
+
+// ToDomain maps database model into domain model.
+func (a *AttemptDAO) ToDomain() Attempt {
+	return Attempt{
+		ID:         a.ID,
+		DeliveryID: a.DeliveryID,
+		Number:     a.Number,
+		StatusCode: a.StatusCode,
+		Error:      a.Error,
+		Duration:   a.Duration,
+		CreatedAt:  a.CreatedAt,
+	}
+}
+
+// AttemptDAOCollection represents a collection of Attempt database model.
+type AttemptDAOCollection []AttemptDAO
+
+// ToDomain maps DAO models into domain models.
+func (aa AttemptDAOCollection) ToDomain() AttemptCollection {
+	r := make(AttemptCollection, 0, len(aa))
+
+	for _, a := range aa {
+		r = append(r, a.ToDomain())
+	}
+
+	return r
+}
+
+// SQLiteRepository is a SQLite implementation of webhook Repository.
+type SQLiteRepository struct {
+	db *gorm.DB
+}
+
+// CreateWebhook persists Webhook entity.
+func (r *SQLiteRepository) CreateWebhook(ctx context.Context, w Webhook) (Webhook, error) {
+	dao := NewWebhookDAO(w)
+	if err := r.db.WithContext(ctx).Create(&dao).Error; err != nil {
+		return Webhook{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// ListWebhooks returns all webhooks of a project.
+func (r *SQLiteRepository) ListWebhooks(ctx context.Context, projectID uint) (WebhookCollection, error) {
+	var webhooks WebhookDAOCollection
+	if err := r.db.WithContext(ctx).Where("project_id = ?", projectID).Order("id").Find(&webhooks).Error; err != nil {
+		return WebhookCollection{}, err
+	}
+
+	return webhooks.ToDomain(), nil
+}
+
+// GetWebhook returns a Webhook of a project, or ErrNotFound.
+func (r *SQLiteRepository) GetWebhook(ctx context.Context, projectID, id uint) (Webhook, error) {
+	var dao WebhookDAO
+	err := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Webhook{}, ErrNotFound
+	}
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// DeleteWebhook removes a Webhook of a project, or returns ErrNotFound.
+// Its deliveries are kept in the log, pending ones are not attempted anymore.
+func (r *SQLiteRepository) DeleteWebhook(ctx context.Context, projectID, id uint) error {
+	result := r.db.WithContext(ctx).Where("project_id = ?", projectID).Delete(&WebhookDAO{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// MatchingWebhooks returns webhooks of a project counting a given event type on a given URL,
+// including webhooks which count all URLs.
+func (r *SQLiteRepository) MatchingWebhooks(ctx context.Context, projectID uint, eventType EventType, url string) (WebhookCollection, error) {
+	var webhooks WebhookDAOCollection
+	err := r.db.WithContext(ctx).
+		Where("project_id = ? AND event_type = ? AND condition_url IN ?", projectID, string(eventType), []string{"", url}).
+		Find(&webhooks).Error
+	if err != nil {
+		return WebhookCollection{}, err
+	}
+
+	return webhooks.ToDomain(), nil
+}
+
+// Trigger updates LastTriggeredAt of a Webhook and persists its Delivery in a single transaction.
+func (r *SQLiteRepository) Trigger(ctx context.Context, w Webhook, d Delivery) (Delivery, error) {
+	dao := NewDeliveryDAO(d)
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WebhookDAO{}).Where("id = ?", w.ID).Update("last_triggered_at", w.LastTriggeredAt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&dao).Error
+	})
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// GetDelivery returns a Delivery of a project, or ErrNotFound.
+func (r *SQLiteRepository) GetDelivery(ctx context.Context, projectID, id uint) (Delivery, error) {
+	var dao DeliveryDAO
+	err := r.db.WithContext(ctx).Where("project_id = ? AND id = ?", projectID, id).First(&dao).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	return dao.ToDomain(), nil
+}
+
+// ListDeliveries returns deliveries of a Webhook, newest first. Empty status returns all of them.
+func (r *SQLiteRepository) ListDeliveries(ctx context.Context, projectID, webhookID uint, status DeliveryStatus) (DeliveryCollection, error) {
+	var deliveries DeliveryDAOCollection
+
+	tx := r.db.WithContext(ctx).Where("project_id = ? AND webhook_id = ?", projectID, webhookID)
+	if status != "" {
+		tx = tx.Where("status = ?", string(status))
+	}
+
+	if err := tx.Order("id DESC").Find(&deliveries).Error; err != nil {
+		return DeliveryCollection{}, err
+	}
+
+	return deliveries.ToDomain(), nil
+}
+
+// DueDeliveries returns pending deliveries whose next attempt is due at a given time, oldest first.
+func (r *SQLiteRepository) DueDeliveries(ctx context.Context, now time.Time, limit int) (DeliveryCollection, error) {
+	var deliveries DeliveryDAOCollection
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", string(StatusPending), now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return DeliveryCollection{}, err
+	}
+
+	return deliveries.ToDomain(), nil
+}
+
+// UpdateDelivery stores the state of a Delivery.
+func (r *SQLiteRepository) UpdateDelivery(ctx context.Context, d Delivery) error {
+	dao := NewDeliveryDAO(d)
+	return r.db.WithContext(ctx).Save(&dao).Error
+}
+
+// SaveAttempt persists an Attempt and the state of its Delivery in a single transaction.
+func (r *SQLiteRepository) SaveAttempt(ctx context.Context, d Delivery, a Attempt) error {
+	delivery := NewDeliveryDAO(d)
+	attempt := AttemptDAO{
+		DeliveryID: d.ID,
+		Number:     a.Number,
+		StatusCode: a.StatusCode,
+		Error:      a.Error,
+		Duration:   a.Duration,
+		CreatedAt:  a.CreatedAt,
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&delivery).Error; err != nil {
+			return err
+		}
+		return tx.Create(&attempt).Error
+	})
+}
+
+// ListAttempts returns all attempts of a Delivery in the order they were made.
+func (r *SQLiteRepository) ListAttempts(ctx context.Context, deliveryID uint) (AttemptCollection, error) {
+	var attempts AttemptDAOCollection
+	if err := r.db.WithContext(ctx).Where("delivery_id = ?", deliveryID).Order("number").Find(&attempts).Error; err != nil {
+		return AttemptCollection{}, err
+	}
+
+	return attempts.ToDomain(), nil
+}
+
+// NewSQLiteRepository is a SQLiteRepository constructor.
+func NewSQLiteRepository(db *gorm.DB) *SQLiteRepository {
+	return &SQLiteRepository{
+		db: db,
+	}
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+
+	"google.com/ivan-sabo/clicks-and-views/internal/click"
+	"google.com/ivan-sabo/clicks-and-views/internal/view"
+)
+
+// ClickRepository decorates click.Repository, passing created Clicks to a Notifier.
+type ClickRepository struct {
+	click.Repository
+	notifier *Notifier
+}
+
+// Create implements click.Repository interface.
+func (r *ClickRepository) Create(ctx context.Context, c click.Click) (click.Click, error) {
+	created, err := r.Repository.Create(ctx, c)
+	if err == nil {
+		r.notifier.Observe(EventClick, created.ProjectID, created.URL)
+	}
+
+	return created, err
+}
+
+// CreateBatch implements click.Repository interface.
+// Conditions are evaluated once for every URL in the batch.
+func (r *ClickRepository) CreateBatch(ctx context.Context, clicks click.ClickCollection) (int64, error) {
+	inserted, err := r.Repository.CreateBatch(ctx, clicks)
+	if inserted > 0 {
+		urls := make([]string, 0, len(clicks))
+		for _, c := range clicks {
+			urls = append(urls, c.URL)
+		}
+		observeBatch(r.notifier, EventClick, clicks[0].ProjectID, urls)
+	}
+
+	return inserted, err
+}
+
+// NewClickRepository is a ClickRepository constructor.
+func NewClickRepository(repository click.Repository, notifier *Notifier) *ClickRepository {
+	return &ClickRepository{
+		Repository: repository,
+		notifier:   notifier,
+	}
+}
+
+// ViewRepository decorates view.Repository, passing created Views to a Notifier.
+type ViewRepository struct {
+	view.Repository
+	notifier *Notifier
+}
+
+// Create implements view.Repository interface.
+func (r *ViewRepository) Create(ctx context.Context, v view.View) (view.View, error) {
+	created, err := r.Repository.Create(ctx, v)
+	if err == nil {
+		r.notifier.Observe(EventView, created.ProjectID, created.URL)
+	}
+
+	return created, err
+}
+
+// CreateBatch implements view.Repository interface.
+// Conditions are evaluated once for every URL in the batch.
+func (r *ViewRepository) CreateBatch(ctx context.Context, views view.ViewCollection) (int64, error) {
+	inserted, err := r.Repository.CreateBatch(ctx, views)
+	if inserted > 0 {
+		urls := make([]string, 0, len(views))
+		for _, v := range views {
+			urls = append(urls, v.URL)
+		}
+		observeBatch(r.notifier, EventView, views[0].ProjectID, urls)
+	}
+
+	return inserted, err
+}
+
+// NewViewRepository is a ViewRepository constructor.
+func NewViewRepository(repository view.Repository, notifier *Notifier) *ViewRepository {
+	return &ViewRepository{
+		Repository: repository,
+		notifier:   notifier,
+	}
+}
+
+// observeBatch passes each distinct URL of a batch to the notifier.
+// All events of a batch belong to the same project.
+func observeBatch(notifier *Notifier, eventType EventType, projectID uint, urls []string) {
+	seen := make(map[string]struct{}, len(urls))
+	for _, url := range urls {
+		if _, ok := seen[url]; ok {
+			continue
+		}
+		seen[url] = struct{}{}
+		notifier.Observe(eventType, projectID, url)
+	}
+}
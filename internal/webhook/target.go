@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenTarget is returned for webhook targets which resolve to loopback, link-local,
+// private or other non-public addresses, so webhooks can't be used to probe internal networks.
+var ErrForbiddenTarget = errors.New("url must point to a public address")
+
+// nonPublic lists ranges which are not covered by net.IP methods, but aren't reachable publicly either.
+var nonPublic = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+}
+
+// Resolver looks up IP addresses of a host.
+type Resolver func(ctx context.Context, host string) ([]net.IP, error)
+
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// publicIP reports whether ip is allowed as a webhook target.
+func publicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range nonPublic {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTarget resolves the host of a webhook URL and checks that all of its addresses are public.
+func checkTarget(ctx context.Context, resolve Resolver, target string) error {
+	u, err := url.Parse(target)
+	if err != nil {
+		return err
+	}
+
+	host := u.Hostname()
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = resolve(ctx, host); err != nil || len(ips) == 0 {
+			return fmt.Errorf("url host %q can't be resolved", host)
+		}
+	}
+
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return ErrForbiddenTarget
+		}
+	}
+	return nil
+}
+
+// dialControl rejects connections to non-public addresses. It runs after the host is resolved,
+// so a target which resolves to another address than at creation time, e.g. with DNS rebinding, is still rejected.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("connecting to %s: %w", address, ErrForbiddenTarget)
+	}
+	return nil
+}
+
+// newHTTPClient returns a client which connects only to public addresses. Proxies are not used,
+// since they would connect to the target on the client's behalf.
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: dialControl,
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
+
+func mustParseCIDR(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}